@@ -48,5 +48,21 @@ func initFlags() {
 	flag.StringVar(&opts.Ingress.ReloadAddr, "bfe-reload-address", opts.Ingress.ReloadAddr, "Address of bfe config reloading.")
 	flag.StringVar(&opts.Ingress.IngressClass, "ingress-class", opts.Ingress.IngressClass, "Class name of bfe ingress controller.")
 	flag.StringVar(&opts.Ingress.DefaultBackend, "default-backend", opts.Ingress.DefaultBackend, "set default backend name, default backend is used if no any ingress rule matched, format namespace/name.")
+	flag.StringVar(&opts.Ingress.ConfigMap, "configmap", opts.Ingress.ConfigMap, "Global configmap name, format namespace/name. Its data are used as default values of annotations without prefix bfe.ingress.kubernetes.io/.")
+
+	flag.BoolVar(&opts.Ingress.SSLRedirect, "ssl-redirect", opts.Ingress.SSLRedirect, "Redirect HTTP requests of hosts in spec.tls to HTTPS by default. Can be overwritten by annotation redirect.ssl-redirect.")
+	flag.BoolVar(&opts.Ingress.SSLRedirectHSTS, "ssl-redirect-hsts", opts.Ingress.SSLRedirectHSTS, "Add Strict-Transport-Security header for hosts redirected to HTTPS by default. Can be overwritten by annotation redirect.ssl-redirect-hsts.")
+	flag.BoolVar(&opts.Ingress.ProxyProtocol, "proxy-protocol", opts.Ingress.ProxyProtocol, "Read client address from PROXY protocol header sent by the layer4 load balancer in front of bfe.")
+	flag.StringVar(&opts.Ingress.AuthURL, "auth-url", opts.Ingress.AuthURL, "URL of the external authorization service. If set, requests of ingresses annotated with the same auth-url are checked by it.")
+	flag.DurationVar(&opts.Ingress.AuthTimeout, "auth-timeout", opts.Ingress.AuthTimeout, "Timeout of requests to the external authorization service.")
+	flag.StringVar(&opts.Ingress.RouterConditionDenyNamespaces, "router-condition-deny-namespaces", opts.Ingress.RouterConditionDenyNamespaces, "Namespaces in which annotation router.condition is rejected, delimited by ','. '*' means all namespaces.")
+	flag.StringVar(&opts.Ingress.ProductMode, "product-mode", opts.Ingress.ProductMode, "How rules are grouped into bfe products: single, host or namespace. single puts all rules into the default product.")
+
+	flag.StringVar(&opts.Ingress.AcmeDirectoryURL, "acme-directory-url", opts.Ingress.AcmeDirectoryURL, "Directory URL of the ACME server. If set, ingresses annotated with tls.acme get certificates through ACME HTTP-01.")
+	flag.StringVar(&opts.Ingress.AcmeEmail, "acme-email", opts.Ingress.AcmeEmail, "Contact email of the ACME account.")
+	flag.StringVar(&opts.Ingress.AcmeCAFile, "acme-ca-file", opts.Ingress.AcmeCAFile, "CA bundle used to verify the ACME server, e.g. the root of a local Pebble server.")
+	flag.DurationVar(&opts.Ingress.AcmeRenewBefore, "acme-renew-before", opts.Ingress.AcmeRenewBefore, "Renew ACME certificates when they expire within this duration.")
+	flag.IntVar(&opts.Ingress.AcmeSolverPort, "acme-solver-port", opts.Ingress.AcmeSolverPort, "Local port serving ACME HTTP-01 challenges to bfe.")
+	flag.StringVar(&opts.Ingress.AcmeSecret, "acme-secret", opts.Ingress.AcmeSecret, "Secret storing the ACME account and pending challenges shared by replicas, format namespace/name. A Lease of the same name elects the replica issuing certificates.")
 
 }
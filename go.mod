@@ -3,11 +3,15 @@ module github.com/bfenetworks/ingress-bfe
 go 1.16
 
 require (
+	github.com/baidu/go-lib v0.0.0-20200819072111-21df249f5e6a
 	github.com/bfenetworks/bfe v1.5.0
 	github.com/jwangsadinata/go-multimap v0.0.0-20190620162914-c29f3d7f33b6
+	golang.org/x/crypto v0.0.0-20210220033148-5ea612d1eb83
+	gopkg.in/square/go-jose.v2 v2.4.1
 	k8s.io/api v0.21.2
 	k8s.io/apimachinery v0.21.2
 	k8s.io/client-go v0.21.2
 	k8s.io/klog/v2 v2.9.0 //indirect
+	k8s.io/utils v0.0.0-20210527160623-6fdb442a123b
 	sigs.k8s.io/controller-runtime v0.9.2
 )
@@ -14,6 +14,9 @@
 package annotations
 
 import (
+	"fmt"
+	"strconv"
+
 	netv1beta1 "k8s.io/api/networking/v1beta1"
 )
 
@@ -23,3 +26,59 @@ const (
 	IngressClassKey       = netv1beta1.AnnotationIngressClass
 	IsDefaultIngressClass = netv1beta1.AnnotationIsDefaultIngressClass
 )
+
+// getBool parse a bool annotation, defaultValue is used if the annotation is not set
+func getBool(annotations map[string]string, key string, defaultValue bool) (bool, error) {
+	value, ok := annotations[key]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("annotation %s is illegal, should be true or false", key)
+	}
+	return b, nil
+}
+
+// configMapAnnotations are the annotations whose values are names of ConfigMaps in the namespace of the ingress
+var configMapAnnotations = []string{
+	BlockWhitelistConfigMapAnnotation,
+	BlockBlacklistConfigMapAnnotation,
+	AuthJWTConfigMapAnnotation,
+	ErrorsPagesConfigMapAnnotation,
+	StaticConfigMapAnnotation,
+}
+
+// GetConfigMapReferences returns names of ConfigMaps referenced by annotations
+func GetConfigMapReferences(annotations map[string]string) []string {
+	var names []string
+	for _, key := range configMapAnnotations {
+		if name := annotations[key]; len(name) > 0 && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// secretAnnotations are the annotations whose values are names of Secrets in the namespace of the ingress,
+// Secrets of TLS are defined in spec.tls
+var secretAnnotations = []string{
+	AuthBasicSecretAnnotation,
+	AuthJWTSecretAnnotation,
+}
+
+// GetSecretReferences returns names of Secrets referenced by annotations
+func GetSecretReferences(annotations map[string]string) []string {
+	var names []string
+	for _, key := range secretAnnotations {
+		if name := annotations[key]; len(name) > 0 && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// TrustClientIPKey is the key of the global ConfigMap, requests from these IPs are trusted,
+// and BFE reads the real client IP from header X-Real-Ip of them
+const TrustClientIPKey = "trust-clientip.cidrs"
@@ -0,0 +1,112 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"strings"
+)
+
+const authBasicAnnotationPrefix = BfeAnnotationPrefix + "auth-basic."
+
+// the annotations related to basic authentication
+const (
+	// AuthBasicSecretAnnotation is the Secret in the namespace of the ingress, which contains users in htpasswd format
+	AuthBasicSecretAnnotation = authBasicAnnotationPrefix + "secret"
+	AuthBasicRealmAnnotation  = authBasicAnnotationPrefix + "realm"
+)
+
+// AuthBasicSecretKey is the key of users in data of the Secret
+const AuthBasicSecretKey = "auth"
+
+// prefixes of password hashes supported by mod_auth_basic
+var htpasswdHashPrefixes = []string{"$apr1$", "$1$", "{SHA}", "$2a$", "$2b$", "$2x$", "$2y$"}
+
+// AuthBasic defines the basic authentication of an Ingress.
+// Refer to https://www.bfe-networks.net/en_us/modules/mod_auth_basic/mod_auth_basic/.
+type AuthBasic struct {
+	Secret string
+	// Realm is empty if not set, and "Restricted" is used by BFE
+	Realm string
+}
+
+// GetAuthBasic parse annotations "auth-basic.*", nil is returned if "auth-basic.secret" is not set
+func GetAuthBasic(annotations map[string]string) (*AuthBasic, error) {
+	secret, ok := annotations[AuthBasicSecretAnnotation]
+	if !ok {
+		if _, ok := annotations[AuthBasicRealmAnnotation]; ok {
+			return nil, fmt.Errorf("annotation %s is required when %s is set", AuthBasicSecretAnnotation, AuthBasicRealmAnnotation)
+		}
+		return nil, nil
+	}
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("annotation %s is illegal, secret name is required", AuthBasicSecretAnnotation)
+	}
+	realm := annotations[AuthBasicRealmAnnotation]
+	if !isValidHeaderValue(realm) || strings.Contains(realm, "\"") {
+		return nil, fmt.Errorf("annotation %s is illegal, realm can't contain '\"' or control characters", AuthBasicRealmAnnotation)
+	}
+
+	return &AuthBasic{
+		Secret: secret,
+		Realm:  realm,
+	}, nil
+}
+
+// ParseHtpasswd checks users in htpasswd format, one "user:hash" per line, and returns them in lines.
+// Lines beginning with '#' are comments.
+func ParseHtpasswd(data []byte) ([]string, error) {
+	var users []string
+	names := make(map[string]bool)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ":")
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			return nil, fmt.Errorf("line %d is illegal, should be in format of user:hash", i+1)
+		}
+		name, hash := parts[0], parts[1]
+		// lines containing '#' are ignored by mod_auth_basic
+		if strings.Contains(line, "#") {
+			return nil, fmt.Errorf("line %d is illegal, user or hash can't contain '#'", i+1)
+		}
+		if !isSupportedHtpasswdHash(hash) {
+			return nil, fmt.Errorf("line %d is illegal, hash of user %s should be one of MD5 ($apr1$), SHA1 ({SHA}) and bcrypt ($2y$)", i+1, name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("line %d is illegal, duplicated user %s", i+1, name)
+		}
+		names[name] = true
+		users = append(users, line)
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("at least one user is required")
+	}
+	return users, nil
+}
+
+func isSupportedHtpasswdHash(hash string) bool {
+	for _, prefix := range htpasswdHashPrefixes {
+		if strings.HasPrefix(hash, prefix) && len(hash) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetAuthBasic(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    *AuthBasic
+		wantErr bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "secret",
+			annots: map[string]string{
+				AuthBasicSecretAnnotation: "users",
+			},
+			want:    &AuthBasic{Secret: "users"},
+			wantErr: false,
+		},
+		{
+			name: "secret and realm",
+			annots: map[string]string{
+				AuthBasicSecretAnnotation: "users",
+				AuthBasicRealmAnnotation:  "Internal Dashboard",
+			},
+			want:    &AuthBasic{Secret: "users", Realm: "Internal Dashboard"},
+			wantErr: false,
+		},
+		{
+			name: "realm without secret",
+			annots: map[string]string{
+				AuthBasicRealmAnnotation: "Internal Dashboard",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "empty secret",
+			annots: map[string]string{
+				AuthBasicSecretAnnotation: "",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal realm",
+			annots: map[string]string{
+				AuthBasicSecretAnnotation: "users",
+				AuthBasicRealmAnnotation:  "\"Internal\"",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetAuthBasic(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAuthBasic() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAuthBasic() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHtpasswd(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "md5, sha1 and bcrypt",
+			data: "# users of dashboard\n" +
+				"alice:$apr1$mI7SilJz$CWwYJyYKbhVDNl26sdUSh/\n" +
+				"\n" +
+				"bob:{SHA}fEqNCco3Yq9h5ZUglD3CZJT4lBs=\n" +
+				"carol:$2y$05$gS1RAMoQW5rPkqJt5W9QoO0P1Sd5tZzmPEI2qrFKOQmHc/cx0CFqu\n",
+			want: []string{
+				"alice:$apr1$mI7SilJz$CWwYJyYKbhVDNl26sdUSh/",
+				"bob:{SHA}fEqNCco3Yq9h5ZUglD3CZJT4lBs=",
+				"carol:$2y$05$gS1RAMoQW5rPkqJt5W9QoO0P1Sd5tZzmPEI2qrFKOQmHc/cx0CFqu",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "plain password",
+			data:    "alice:123456",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "without hash",
+			data:    "alice",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "duplicated user",
+			data:    "alice:{SHA}fEqNCco3Yq9h5ZUglD3CZJT4lBs=\nalice:{SHA}fEqNCco3Yq9h5ZUglD3CZJT4lBs=",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "no user",
+			data:    "# no user",
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHtpasswd([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseHtpasswd() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHtpasswd() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const authJWTAnnotationPrefix = BfeAnnotationPrefix + "auth-jwt."
+
+// the annotations related to JWT authentication
+const (
+	// AuthJWTSecretAnnotation is the Secret in the namespace of the ingress, which contains the JWK set
+	AuthJWTSecretAnnotation = authJWTAnnotationPrefix + "secret"
+	// AuthJWTConfigMapAnnotation is the ConfigMap in the namespace of the ingress, which contains the JWK set
+	AuthJWTConfigMapAnnotation = authJWTAnnotationPrefix + "configmap"
+	AuthJWTRealmAnnotation     = authJWTAnnotationPrefix + "realm"
+)
+
+// AuthJWTKeySetKey is the key of the JWK set in data of the Secret or ConfigMap
+const AuthJWTKeySetKey = "jwks"
+
+// AuthJWT defines the JWT authentication of an Ingress, only one of Secret and ConfigMap is set.
+// Refer to https://www.bfe-networks.net/en_us/modules/mod_auth_jwt/mod_auth_jwt/.
+type AuthJWT struct {
+	Secret    string
+	ConfigMap string
+	// Realm is empty if not set, and "Restricted" is used by BFE
+	Realm string
+}
+
+// GetAuthJWT parse annotations "auth-jwt.*", nil is returned if neither "auth-jwt.secret" nor "auth-jwt.configmap" is set
+func GetAuthJWT(annotations map[string]string) (*AuthJWT, error) {
+	secret, secretOk := annotations[AuthJWTSecretAnnotation]
+	configMap, configMapOk := annotations[AuthJWTConfigMapAnnotation]
+	if !secretOk && !configMapOk {
+		if _, ok := annotations[AuthJWTRealmAnnotation]; ok {
+			return nil, fmt.Errorf("annotation %s or %s is required when %s is set", AuthJWTSecretAnnotation, AuthJWTConfigMapAnnotation, AuthJWTRealmAnnotation)
+		}
+		return nil, nil
+	}
+
+	if secretOk && configMapOk {
+		return nil, fmt.Errorf("annotation %s and %s can't be set at the same time", AuthJWTSecretAnnotation, AuthJWTConfigMapAnnotation)
+	}
+	if secretOk && len(secret) == 0 {
+		return nil, fmt.Errorf("annotation %s is illegal, secret name is required", AuthJWTSecretAnnotation)
+	}
+	if configMapOk && len(configMap) == 0 {
+		return nil, fmt.Errorf("annotation %s is illegal, configmap name is required", AuthJWTConfigMapAnnotation)
+	}
+	realm := annotations[AuthJWTRealmAnnotation]
+	if !isValidHeaderValue(realm) || strings.Contains(realm, "\"") {
+		return nil, fmt.Errorf("annotation %s is illegal, realm can't contain '\"' or control characters", AuthJWTRealmAnnotation)
+	}
+
+	return &AuthJWT{
+		Secret:    secret,
+		ConfigMap: configMap,
+		Realm:     realm,
+	}, nil
+}
+
+// ParseJWKS checks the JWK set, and returns keys in format of the key file of mod_auth_jwt, which is a JSON array of keys.
+// Both a JWK set defined in RFC 7517, e.g. {"keys": [...]}, and a JSON array of keys are accepted.
+// Asymmetric private keys are converted to public keys.
+func ParseJWKS(data []byte) ([]byte, error) {
+	var rawKeys []json.RawMessage
+	data = []byte(strings.TrimSpace(string(data)))
+	if strings.HasPrefix(string(data), "[") {
+		if err := json.Unmarshal(data, &rawKeys); err != nil {
+			return nil, fmt.Errorf("invalid JWK set: %s", err)
+		}
+	} else {
+		keySet := struct {
+			Keys []json.RawMessage `json:"keys"`
+		}{}
+		if err := json.Unmarshal(data, &keySet); err != nil {
+			return nil, fmt.Errorf("invalid JWK set: %s", err)
+		}
+		rawKeys = keySet.Keys
+	}
+
+	if len(rawKeys) == 0 {
+		return nil, fmt.Errorf("at least one key is required")
+	}
+
+	keys := make([]jose.JSONWebKey, 0, len(rawKeys))
+	for i, rawKey := range rawKeys {
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(rawKey); err != nil {
+			return nil, fmt.Errorf("key %d is illegal, %s", i, err)
+		}
+		// symmetric keys are kept as they are, and signatures are verified by public keys of asymmetric keys
+		if symmetricKey, ok := key.Key.([]byte); ok {
+			if len(symmetricKey) == 0 {
+				return nil, fmt.Errorf("key %d is illegal, empty symmetric key", i)
+			}
+		} else {
+			if !key.IsPublic() {
+				key = key.Public()
+			}
+			if !key.Valid() {
+				return nil, fmt.Errorf("key %d is illegal, invalid key material", i)
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	return json.Marshal(keys)
+}
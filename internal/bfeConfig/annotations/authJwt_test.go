@@ -0,0 +1,184 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func TestGetAuthJWT(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    *AuthJWT
+		wantErr bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "secret and realm",
+			annots: map[string]string{
+				AuthJWTSecretAnnotation: "jwks",
+				AuthJWTRealmAnnotation:  "api",
+			},
+			want:    &AuthJWT{Secret: "jwks", Realm: "api"},
+			wantErr: false,
+		},
+		{
+			name: "configmap",
+			annots: map[string]string{
+				AuthJWTConfigMapAnnotation: "jwks",
+			},
+			want:    &AuthJWT{ConfigMap: "jwks"},
+			wantErr: false,
+		},
+		{
+			name: "both secret and configmap",
+			annots: map[string]string{
+				AuthJWTSecretAnnotation:    "jwks",
+				AuthJWTConfigMapAnnotation: "jwks",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "realm only",
+			annots: map[string]string{
+				AuthJWTRealmAnnotation: "api",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "empty configmap",
+			annots: map[string]string{
+				AuthJWTConfigMapAnnotation: "",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal realm",
+			annots: map[string]string{
+				AuthJWTSecretAnnotation: "jwks",
+				AuthJWTRealmAnnotation:  "a\nb",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetAuthJWT(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAuthJWT() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAuthJWT() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateJWK, err := json.Marshal(jose.JSONWebKey{Key: privateKey, KeyID: "ec"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		data       string
+		wantKeyIDs []string
+		wantErr    bool
+	}{
+		{
+			name:       "jwk set",
+			data:       `{"keys": [{"kty": "oct", "k": "YmZland0Mg", "kid": "0001"}, {"kty": "oct", "k": "YmZland0", "kid": "0002"}]}`,
+			wantKeyIDs: []string{"0001", "0002"},
+			wantErr:    false,
+		},
+		{
+			name:       "array of keys",
+			data:       `[{"kty": "oct", "k": "YmZland0Mg", "kid": "0001"}]`,
+			wantKeyIDs: []string{"0001"},
+			wantErr:    false,
+		},
+		{
+			name:       "private key",
+			data:       `{"keys": [` + string(privateJWK) + `]}`,
+			wantKeyIDs: []string{"ec"},
+			wantErr:    false,
+		},
+		{
+			name:    "no key",
+			data:    `{"keys": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "illegal key type",
+			data:    `[{"kty": "invalid", "k": "YmZland0", "kid": "0001"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			data:    `keys`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJWKS([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseJWKS() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var keys []jose.JSONWebKey
+			if err := json.Unmarshal(got, &keys); err != nil {
+				t.Fatalf("ParseJWKS() got illegal key file: %v", err)
+			}
+			var keyIDs []string
+			for _, key := range keys {
+				if _, ok := key.Key.([]byte); !ok && !key.IsPublic() {
+					t.Errorf("ParseJWKS() got private key %s", key.KeyID)
+				}
+				keyIDs = append(keyIDs, key.KeyID)
+			}
+			if !reflect.DeepEqual(keyIDs, tt.wantKeyIDs) {
+				t.Errorf("ParseJWKS() got key ids = %v, want %v", keyIDs, tt.wantKeyIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// AuthURLAnnotation is the URL of the external authorization service, which checks requests matched by the ingress
+const AuthURLAnnotation = BfeAnnotationPrefix + "auth-url"
+
+// GetAuthURL parse annotation "auth-url", empty string is returned if not set
+func GetAuthURL(annotations map[string]string) (string, error) {
+	value, ok := annotations[AuthURLAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	authURL := strings.TrimSpace(value)
+	if err := CheckAuthURL(authURL); err != nil {
+		return "", fmt.Errorf("annotation %s is illegal, %s", AuthURLAnnotation, err)
+	}
+	return authURL, nil
+}
+
+// CheckAuthURL checks the URL of the external authorization service, which should be an absolute http or https URL
+func CheckAuthURL(authURL string) error {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("url %s should begin with http:// or https://", authURL)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import "testing"
+
+func TestGetAuthURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			want:    "",
+			wantErr: false,
+		},
+		{
+			name: "http",
+			annots: map[string]string{
+				AuthURLAnnotation: "http://auth.default.svc:8080/check",
+			},
+			want:    "http://auth.default.svc:8080/check",
+			wantErr: false,
+		},
+		{
+			name: "https with spaces",
+			annots: map[string]string{
+				AuthURLAnnotation: " https://auth.example.com/ ",
+			},
+			want:    "https://auth.example.com/",
+			wantErr: false,
+		},
+		{
+			name: "relative url",
+			annots: map[string]string{
+				AuthURLAnnotation: "/check",
+			},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name: "unsupported scheme",
+			annots: map[string]string{
+				AuthURLAnnotation: "grpc://auth.default.svc:9090",
+			},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name: "empty",
+			annots: map[string]string{
+				AuthURLAnnotation: "",
+			},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetAuthURL(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAuthURL() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetAuthURL() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	BackendNamespaceKey = "backend.namespace"
+	// BackendNamespaceAnnotation maps names of backend services to the namespaces they belong to,
+	// e.g. {"service1": "namespace1"}, services not in the map are in the namespace of the ingress
+	BackendNamespaceAnnotation = BfeAnnotationPrefix + BackendNamespaceKey
+)
+
+const (
+	// BackendGrantConfigMap is the ConfigMap published in the namespace of backend services,
+	// which grants ingresses in other namespaces to reference the services
+	BackendGrantConfigMap = "bfe-backend-grant"
+	// BackendGrantNamespacesKey is the key of the grant ConfigMap, which is a comma separated list of
+	// namespaces of ingresses, or "*" for all namespaces
+	BackendGrantNamespacesKey = "namespaces"
+	// BackendGrantServicesKey is the key of the grant ConfigMap, which is a comma separated list of
+	// services can be referenced, all services in the namespace can be referenced if not set
+	BackendGrantServicesKey = "services"
+)
+
+// BackendNamespaces define struct of annotation "backend.namespace"
+type BackendNamespaces map[string]string
+
+// GetBackendNamespaces parse annotation "backend.namespace"
+func GetBackendNamespaces(annotations map[string]string) (BackendNamespaces, error) {
+	value, ok := annotations[BackendNamespaceAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	var namespaces BackendNamespaces
+	if err := json.Unmarshal([]byte(value), &namespaces); err != nil {
+		return nil, fmt.Errorf("annotation %s is illegal, error: %s", BackendNamespaceAnnotation, err)
+	}
+	for service, namespace := range namespaces {
+		if len(validation.IsDNS1123Label(namespace)) > 0 {
+			return nil, fmt.Errorf("annotation %s is illegal, namespace [%s] of service [%s] is invalid", BackendNamespaceAnnotation, namespace, service)
+		}
+	}
+	return namespaces, nil
+}
+
+// Namespace returns the namespace of the backend service, which is the namespace of the ingress by default
+func (n BackendNamespaces) Namespace(ingressNamespace, service string) string {
+	if namespace, ok := n[service]; ok {
+		return namespace
+	}
+	return ingressNamespace
+}
+
+// CheckBackendGrant checks whether the service can be referenced by ingresses in namespace from,
+// data is the data of ConfigMap BackendGrantConfigMap in the namespace of the service
+func CheckBackendGrant(data map[string]string, from, service string) error {
+	if !grantContains(data[BackendGrantNamespacesKey], from) {
+		return fmt.Errorf("namespace %s is not in key [%s] of ConfigMap %s", from, BackendGrantNamespacesKey, BackendGrantConfigMap)
+	}
+	if services, ok := data[BackendGrantServicesKey]; ok && !grantContains(services, service) {
+		return fmt.Errorf("service %s is not in key [%s] of ConfigMap %s", service, BackendGrantServicesKey, BackendGrantConfigMap)
+	}
+	return nil
+}
+
+// grantContains checks whether a comma separated list contains name, "*" matches all names
+func grantContains(list, name string) bool {
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || item == name {
+			return true
+		}
+	}
+	return false
+}
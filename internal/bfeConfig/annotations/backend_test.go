@@ -0,0 +1,132 @@
+// Copyright (c) 2021 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetBackendNamespaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   map[string]string
+		want    BackendNamespaces
+		wantErr bool
+	}{
+		{
+			name:  "not set",
+			value: map[string]string{},
+			want:  nil,
+		},
+		{
+			name:  "normal",
+			value: map[string]string{BackendNamespaceAnnotation: `{"service1": "ns1", "service2": "ns2"}`},
+			want:  BackendNamespaces{"service1": "ns1", "service2": "ns2"},
+		},
+		{
+			name:    "abnormal json",
+			value:   map[string]string{BackendNamespaceAnnotation: `{"service1": 1}`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid namespace",
+			value:   map[string]string{BackendNamespaceAnnotation: `{"service1": "ns/1"}`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetBackendNamespaces(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetBackendNamespaces() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetBackendNamespaces() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackendNamespaces_Namespace(t *testing.T) {
+	namespaces := BackendNamespaces{"service1": "ns1"}
+	if got := namespaces.Namespace("default", "service1"); got != "ns1" {
+		t.Errorf("Namespace() = %v, want %v", got, "ns1")
+	}
+	if got := namespaces.Namespace("default", "service2"); got != "default" {
+		t.Errorf("Namespace() = %v, want %v", got, "default")
+	}
+	if got := BackendNamespaces(nil).Namespace("default", "service1"); got != "default" {
+		t.Errorf("Namespace() = %v, want %v", got, "default")
+	}
+}
+
+func TestCheckBackendGrant(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		from    string
+		service string
+		wantErr bool
+	}{
+		{
+			name:    "namespace granted",
+			data:    map[string]string{BackendGrantNamespacesKey: "ns1, ns2"},
+			from:    "ns2",
+			service: "service1",
+		},
+		{
+			name:    "all namespaces granted",
+			data:    map[string]string{BackendGrantNamespacesKey: "*"},
+			from:    "ns3",
+			service: "service1",
+		},
+		{
+			name:    "namespace not granted",
+			data:    map[string]string{BackendGrantNamespacesKey: "ns1"},
+			from:    "ns2",
+			service: "service1",
+			wantErr: true,
+		},
+		{
+			name:    "no namespaces",
+			data:    map[string]string{},
+			from:    "ns1",
+			service: "service1",
+			wantErr: true,
+		},
+		{
+			name:    "service granted",
+			data:    map[string]string{BackendGrantNamespacesKey: "ns1", BackendGrantServicesKey: "service1,service2"},
+			from:    "ns1",
+			service: "service2",
+		},
+		{
+			name:    "service not granted",
+			data:    map[string]string{BackendGrantNamespacesKey: "ns1", BackendGrantServicesKey: "service1"},
+			from:    "ns1",
+			service: "service2",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckBackendGrant(tt.data, tt.from, tt.service); (err != nil) != tt.wantErr {
+				t.Errorf("CheckBackendGrant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+const blockAnnotationPrefix = BfeAnnotationPrefix + "block."
+
+// the annotations related to IP whitelist and blacklist, values of lists are delimited by ','
+const (
+	BlockWhitelistAnnotation = blockAnnotationPrefix + "whitelist"
+	BlockBlacklistAnnotation = blockAnnotationPrefix + "blacklist"
+	// the ConfigMaps in the namespace of the ingress, IPs of all keys in the ConfigMap are used
+	BlockWhitelistConfigMapAnnotation = blockAnnotationPrefix + "whitelist-configmap"
+	BlockBlacklistConfigMapAnnotation = blockAnnotationPrefix + "blacklist-configmap"
+)
+
+// BlockIPBlocklistKey is the key of the global ConfigMap, connections from these IPs are closed for all ingresses
+const BlockIPBlocklistKey = "block.ip-blocklist"
+
+// IPRange is a range of IP addresses, from Start to End inclusively
+type IPRange struct {
+	Start net.IP
+	End   net.IP
+}
+
+// BlockList defines the IP whitelist and blacklist of an Ingress.
+// Refer to https://www.bfe-networks.net/en_us/modules/mod_block/mod_block/.
+type BlockList struct {
+	// Whitelist is nil if whitelist is not set, otherwise only requests from these IPs are allowed
+	Whitelist []IPRange
+	// Blacklist are IPs whose requests are denied
+	Blacklist []IPRange
+
+	WhitelistConfigMap string
+	BlacklistConfigMap string
+}
+
+// GetBlockList parse annotations "block.*", nil is returned if none of them is set.
+// IPs in the referenced ConfigMaps are added by AddConfigMap.
+func GetBlockList(annotations map[string]string) (*BlockList, error) {
+	var err error
+	blockList := &BlockList{
+		WhitelistConfigMap: annotations[BlockWhitelistConfigMapAnnotation],
+		BlacklistConfigMap: annotations[BlockBlacklistConfigMapAnnotation],
+	}
+
+	if value, ok := annotations[BlockWhitelistAnnotation]; ok {
+		if blockList.Whitelist, err = parseIPRangeList("annotation "+BlockWhitelistAnnotation, value); err != nil {
+			return nil, err
+		}
+	}
+	if value, ok := annotations[BlockBlacklistAnnotation]; ok {
+		if blockList.Blacklist, err = parseIPRangeList("annotation "+BlockBlacklistAnnotation, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, item := range []struct {
+		annotation string
+		list       []IPRange
+	}{
+		{BlockWhitelistAnnotation, blockList.Whitelist},
+		{BlockBlacklistAnnotation, blockList.Blacklist},
+	} {
+		if _, ok := annotations[item.annotation]; ok && len(item.list) == 0 {
+			return nil, fmt.Errorf("annotation %s is illegal, at least one IP is required", item.annotation)
+		}
+	}
+	if blockList.Whitelist == nil && blockList.Blacklist == nil &&
+		len(blockList.WhitelistConfigMap) == 0 && len(blockList.BlacklistConfigMap) == 0 {
+		return nil, nil
+	}
+	return blockList, nil
+}
+
+// AddConfigMap adds IPs in data of the ConfigMap to the whitelist or blacklist which references the ConfigMap
+func (b *BlockList) AddConfigMap(name string, data map[string]string) error {
+	ranges, err := ParseIPRanges(data)
+	if err != nil {
+		return fmt.Errorf("configmap %s: %s", name, err)
+	}
+
+	if name == b.WhitelistConfigMap {
+		if len(ranges) == 0 {
+			return fmt.Errorf("configmap %s is illegal, at least one IP is required", name)
+		}
+		b.Whitelist = append(b.Whitelist, ranges...)
+	}
+	if name == b.BlacklistConfigMap {
+		b.Blacklist = append(b.Blacklist, ranges...)
+	}
+	return nil
+}
+
+// ParseIPRanges parse IPs in data of a ConfigMap, values are IPs or CIDRs delimited by ',' or new lines,
+// lines beginning with '#' are comments
+func ParseIPRanges(data map[string]string) ([]IPRange, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var ranges []IPRange
+	for _, key := range keys {
+		for _, line := range strings.Split(data[key], "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			lineRanges, err := parseIPRangeList("key "+key, line)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, lineRanges...)
+		}
+	}
+	return ranges, nil
+}
+
+// parseIPRangeList parse IPs or CIDRs delimited by ',', e.g. "10.0.0.0/8, 192.168.1.1"
+func parseIPRangeList(source, value string) ([]IPRange, error) {
+	var ranges []IPRange
+	for _, item := range splitList(value) {
+		ipRange, err := parseIPRange(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s is illegal, %s", source, err)
+		}
+		ranges = append(ranges, ipRange)
+	}
+	return ranges, nil
+}
+
+func parseIPRange(value string) (IPRange, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return IPRange{}, fmt.Errorf("invalid IP [%s]", value)
+		}
+		return IPRange{Start: ip, End: ip}, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return IPRange{}, fmt.Errorf("invalid CIDR [%s]", value)
+	}
+	end := make(net.IP, len(ipNet.IP))
+	for i := range ipNet.IP {
+		end[i] = ipNet.IP[i] | ^ipNet.Mask[i]
+	}
+	return IPRange{Start: ipNet.IP, End: end}, nil
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+// formatIPRanges formats IP ranges as ["start-end", ...] for comparison
+func formatIPRanges(ranges []IPRange) []string {
+	var list []string
+	for _, r := range ranges {
+		list = append(list, r.Start.String()+"-"+r.End.String())
+	}
+	return list
+}
+
+func TestGetBlockList(t *testing.T) {
+	tests := []struct {
+		name          string
+		annots        map[string]string
+		configMap     map[string]string
+		wantNil       bool
+		wantWhitelist []string
+		wantBlacklist []string
+		wantErr       bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			wantNil: true,
+			wantErr: false,
+		},
+		{
+			name: "whitelist and blacklist",
+			annots: map[string]string{
+				BlockWhitelistAnnotation: "10.0.0.0/8, 192.168.1.1",
+				BlockBlacklistAnnotation: "10.1.0.0/16,2001:db8::/32",
+			},
+			wantWhitelist: []string{"10.0.0.0-10.255.255.255", "192.168.1.1-192.168.1.1"},
+			wantBlacklist: []string{"10.1.0.0-10.1.255.255", "2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"},
+			wantErr:       false,
+		},
+		{
+			name: "whitelist from configmap",
+			annots: map[string]string{
+				BlockWhitelistAnnotation:          "192.168.1.1",
+				BlockWhitelistConfigMapAnnotation: "office",
+			},
+			configMap: map[string]string{
+				"beijing":  "# office in beijing\n172.16.0.0/24\n172.16.1.0/24",
+				"shanghai": "172.17.0.0/24",
+			},
+			wantWhitelist: []string{
+				"192.168.1.1-192.168.1.1",
+				"172.16.0.0-172.16.0.255",
+				"172.16.1.0-172.16.1.255",
+				"172.17.0.0-172.17.0.255",
+			},
+			wantErr: false,
+		},
+		{
+			name: "illegal ip",
+			annots: map[string]string{
+				BlockBlacklistAnnotation: "10.0.0.256",
+			},
+			wantErr: true,
+		},
+		{
+			name: "illegal cidr",
+			annots: map[string]string{
+				BlockWhitelistAnnotation: "10.0.0.0/33",
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty whitelist",
+			annots: map[string]string{
+				BlockWhitelistAnnotation: " ",
+			},
+			wantErr: true,
+		},
+		{
+			name: "illegal configmap",
+			annots: map[string]string{
+				BlockBlacklistConfigMapAnnotation: "bad-ranges",
+			},
+			configMap: map[string]string{
+				"ranges": "10.0.0.0/8\nexample.com",
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty whitelist configmap",
+			annots: map[string]string{
+				BlockWhitelistConfigMapAnnotation: "office",
+			},
+			configMap: map[string]string{
+				"ranges": "# no office",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetBlockList(tt.annots)
+			if err == nil && got != nil && tt.configMap != nil {
+				for _, name := range GetConfigMapReferences(tt.annots) {
+					if err = got.AddConfigMap(name, tt.configMap); err != nil {
+						break
+					}
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetBlockList() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("GetBlockList() got = %v, wantNil %v", got, tt.wantNil)
+				return
+			}
+			if got == nil {
+				return
+			}
+			if whitelist := formatIPRanges(got.Whitelist); !reflect.DeepEqual(whitelist, tt.wantWhitelist) {
+				t.Errorf("GetBlockList() whitelist = %v, want %v", whitelist, tt.wantWhitelist)
+			}
+			if blacklist := formatIPRanges(got.Blacklist); !reflect.DeepEqual(blacklist, tt.wantBlacklist) {
+				t.Errorf("GetBlockList() blacklist = %v, want %v", blacklist, tt.wantBlacklist)
+			}
+		})
+	}
+}
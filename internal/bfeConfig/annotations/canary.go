@@ -0,0 +1,144 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// annotations of canary release, a canary ingress takes a percentage of the traffic of the main ingress with the same host and path
+const (
+	CanaryAnnotation       = BfeAnnotationPrefix + "canary"
+	CanaryWeightAnnotation = BfeAnnotationPrefix + "canary.weight"
+	// CanaryHashByCookieAnnotation makes requests with the same cookie value routed to the same ingress
+	CanaryHashByCookieAnnotation = BfeAnnotationPrefix + "canary.hash-by-cookie"
+	// CanaryHashByHeaderAnnotation makes requests with the same header value routed to the same ingress
+	CanaryHashByHeaderAnnotation = BfeAnnotationPrefix + "canary.hash-by-header"
+	// CanaryByHeaderAnnotation is a header to override the weight, see CanaryHeaderAlways and CanaryHeaderNever
+	CanaryByHeaderAnnotation = BfeAnnotationPrefix + "canary.by-header"
+)
+
+// values of the header set by canary.by-header, case insensitive
+const (
+	CanaryHeaderAlways = "always"
+	CanaryHeaderNever  = "never"
+)
+
+// canaryHashBuckets is the number of hash buckets of bfe condition, each percent of weight takes canaryHashBuckets/100 buckets
+const canaryHashBuckets = 10000
+
+// Canary defines the canary release of an Ingress
+type Canary struct {
+	// Weight is the percentage of traffic routed to the canary ingress, in [0, 100]
+	Weight       int
+	HashByCookie string
+	HashByHeader string
+	ByHeader     string
+}
+
+// IsCanary returns true if the ingress is a canary ingress
+func IsCanary(annotations map[string]string) bool {
+	canary, err := getBool(annotations, CanaryAnnotation, false)
+	return err == nil && canary
+}
+
+// GetCanary parse annotations "canary*", nil is returned if the ingress is not a canary ingress
+func GetCanary(annotations map[string]string) (*Canary, error) {
+	isCanary, err := getBool(annotations, CanaryAnnotation, false)
+	if err != nil || !isCanary {
+		return nil, err
+	}
+
+	canary := &Canary{
+		HashByCookie: strings.TrimSpace(annotations[CanaryHashByCookieAnnotation]),
+		HashByHeader: strings.TrimSpace(annotations[CanaryHashByHeaderAnnotation]),
+		ByHeader:     strings.TrimSpace(annotations[CanaryByHeaderAnnotation]),
+	}
+
+	value, ok := annotations[CanaryWeightAnnotation]
+	if ok {
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 || weight > 100 {
+			return nil, fmt.Errorf("annotation %s is illegal, should be an integer in [0, 100]", CanaryWeightAnnotation)
+		}
+		canary.Weight = weight
+	} else if len(canary.ByHeader) == 0 {
+		return nil, fmt.Errorf("annotation %s or %s is required for canary ingress", CanaryWeightAnnotation, CanaryByHeaderAnnotation)
+	}
+
+	if len(canary.HashByCookie) > 0 && len(canary.HashByHeader) > 0 {
+		return nil, fmt.Errorf("annotation %s and %s can't be set at the same time", CanaryHashByCookieAnnotation, CanaryHashByHeaderAnnotation)
+	}
+	for annotation, name := range map[string]string{
+		CanaryHashByCookieAnnotation: canary.HashByCookie,
+		CanaryHashByHeaderAnnotation: canary.HashByHeader,
+		CanaryByHeaderAnnotation:     canary.ByHeader,
+	} {
+		if !isValidConditionValue(name) || strings.ContainsAny(name, " \t:") {
+			return nil, fmt.Errorf("annotation %s is illegal, [%s] is not a valid name", annotation, name)
+		}
+	}
+
+	return canary, nil
+}
+
+// GetCanaryExpression generates bfe condition of the canary ingress, empty string is returned if not a canary ingress
+func GetCanaryExpression(annotations map[string]string) (string, error) {
+	canary, err := GetCanary(annotations)
+	if err != nil || canary == nil {
+		return "", err
+	}
+	return canary.expression(), nil
+}
+
+func (c *Canary) expression() string {
+	weight := c.weightPrimitive()
+	if len(c.ByHeader) == 0 {
+		if len(weight) == 0 {
+			// no traffic is routed to the canary ingress
+			return "!default_t()"
+		}
+		return weight
+	}
+
+	always := fmt.Sprintf("req_header_value_in(\"%s\", \"%s\", true)", c.ByHeader, CanaryHeaderAlways)
+	if len(weight) == 0 {
+		return always
+	}
+	never := fmt.Sprintf("!req_header_value_in(\"%s\", \"%s\", true)", c.ByHeader, CanaryHeaderNever)
+	return fmt.Sprintf("(%s||(%s&&%s))", always, never, weight)
+}
+
+// weightPrimitive generates primitive matching Weight percent of requests, empty string is returned if Weight is 0.
+// Requests are hashed by client IP, unless hash-by-cookie or hash-by-header is set.
+func (c *Canary) weightPrimitive() string {
+	if c.Weight == 0 {
+		return ""
+	}
+	if c.Weight == 100 {
+		return "default_t()"
+	}
+
+	buckets := fmt.Sprintf("0-%d", c.Weight*canaryHashBuckets/100-1)
+	if len(c.HashByCookie) > 0 {
+		return fmt.Sprintf("req_cookie_value_hash_in(\"%s\", \"%s\", false)", c.HashByCookie, buckets)
+	}
+	if len(c.HashByHeader) > 0 {
+		return fmt.Sprintf("req_header_value_hash_in(\"%s\", \"%s\", false)", c.HashByHeader, buckets)
+	}
+	return fmt.Sprintf("req_cip_hash_in(\"%s\")", buckets)
+}
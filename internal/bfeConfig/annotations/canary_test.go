@@ -0,0 +1,150 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+)
+
+func TestGetCanaryExpression(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "not canary",
+			annots: map[string]string{CanaryWeightAnnotation: "20"},
+			want:   "",
+		},
+		{
+			name: "weight by client ip",
+			annots: map[string]string{
+				CanaryAnnotation:       "true",
+				CanaryWeightAnnotation: "20",
+			},
+			want: `req_cip_hash_in("0-1999")`,
+		},
+		{
+			name: "weight by cookie",
+			annots: map[string]string{
+				CanaryAnnotation:             "true",
+				CanaryWeightAnnotation:       "5",
+				CanaryHashByCookieAnnotation: "uid",
+			},
+			want: `req_cookie_value_hash_in("uid", "0-499", false)`,
+		},
+		{
+			name: "weight by header with override",
+			annots: map[string]string{
+				CanaryAnnotation:             "true",
+				CanaryWeightAnnotation:       "50",
+				CanaryHashByHeaderAnnotation: "X-User",
+				CanaryByHeaderAnnotation:     "X-Canary",
+			},
+			want: `(req_header_value_in("X-Canary", "always", true)||(!req_header_value_in("X-Canary", "never", true)&&req_header_value_hash_in("X-User", "0-4999", false)))`,
+		},
+		{
+			name: "header only",
+			annots: map[string]string{
+				CanaryAnnotation:         "true",
+				CanaryByHeaderAnnotation: "X-Canary",
+			},
+			want: `req_header_value_in("X-Canary", "always", true)`,
+		},
+		{
+			name: "weight 0",
+			annots: map[string]string{
+				CanaryAnnotation:       "true",
+				CanaryWeightAnnotation: "0",
+			},
+			want: `!default_t()`,
+		},
+		{
+			name: "weight 100",
+			annots: map[string]string{
+				CanaryAnnotation:       "true",
+				CanaryWeightAnnotation: "100",
+			},
+			want: `default_t()`,
+		},
+		{
+			name: "weight required",
+			annots: map[string]string{
+				CanaryAnnotation: "true",
+			},
+			wantErr: true,
+		},
+		{
+			name: "weight out of range",
+			annots: map[string]string{
+				CanaryAnnotation:       "true",
+				CanaryWeightAnnotation: "101",
+			},
+			wantErr: true,
+		},
+		{
+			name: "hash by cookie and header",
+			annots: map[string]string{
+				CanaryAnnotation:             "true",
+				CanaryWeightAnnotation:       "10",
+				CanaryHashByCookieAnnotation: "uid",
+				CanaryHashByHeaderAnnotation: "X-User",
+			},
+			wantErr: true,
+		},
+		{
+			name: "illegal header",
+			annots: map[string]string{
+				CanaryAnnotation:         "true",
+				CanaryByHeaderAnnotation: "X-Canary: always",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetCanaryExpression(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetCanaryExpression() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetCanaryExpression() got %v, want %v", got, tt.want)
+			}
+			if len(got) > 0 {
+				if _, err := condition.Build(got); err != nil {
+					t.Errorf("condition.Build(%s) error = %v", got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCanaryEqual(t *testing.T) {
+	main := map[string]string{HeaderAnnotation: "a: b"}
+	canary := map[string]string{HeaderAnnotation: "a: b", CanaryAnnotation: "true", CanaryWeightAnnotation: "10"}
+	canary2 := map[string]string{HeaderAnnotation: "a: b", CanaryAnnotation: "true", CanaryWeightAnnotation: "20"}
+
+	if Equal(main, canary) {
+		t.Errorf("Equal() of main and canary should be false")
+	}
+	if !Equal(canary, canary2) {
+		t.Errorf("Equal() of canaries of the same rule should be true")
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the keys of compression, used both in annotations (with BfeAnnotationPrefix) and in the global ConfigMap (without prefix)
+const (
+	CompressTypeKey    = "compress.type"
+	CompressQualityKey = "compress.quality"
+	CompressMinSizeKey = "compress.min-size"
+	CompressTypesKey   = "compress.types"
+)
+
+// values of compress.type
+const (
+	CompressGzip   = "gzip"
+	CompressBrotli = "brotli"
+	CompressOff    = "off"
+)
+
+const (
+	DefaultCompressQuality = 6
+	// MaxCompressMinSize is the max value of compress.min-size, since the size is matched by listing all smaller values of Content-Length
+	MaxCompressMinSize = 1024
+	// CompressAnyType means responses of all content types are compressed
+	CompressAnyType = "*"
+)
+
+// DefaultCompressTypes are the content types compressed if compress.types is not set
+var DefaultCompressTypes = []string{
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/javascript",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+}
+
+// quality ranges of compression, same as mod_compress
+var compressQualityRanges = map[string][2]int{
+	CompressGzip:   {-2, 9},
+	CompressBrotli: {0, 11},
+}
+
+// Compress defines the compression of responses of an Ingress.
+// A nil field means it's not set, so the value of the global default is used.
+type Compress struct {
+	Type    *string
+	Quality *int
+	MinSize *int
+	Types   []string
+}
+
+// GetCompress parse annotations "compress.*"
+func GetCompress(annotations map[string]string) (*Compress, error) {
+	return parseCompress(annotations, BfeAnnotationPrefix)
+}
+
+// ParseCompress parse keys "compress.*" in the global ConfigMap
+func ParseCompress(data map[string]string) (*Compress, error) {
+	return parseCompress(data, "")
+}
+
+func parseCompress(values map[string]string, prefix string) (*Compress, error) {
+	compress := &Compress{}
+
+	if value, ok := values[prefix+CompressTypeKey]; ok {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != CompressGzip && value != CompressBrotli && value != CompressOff {
+			return nil, fmt.Errorf("annotation %s is illegal, should be one of %s,%s,%s", prefix+CompressTypeKey, CompressGzip, CompressBrotli, CompressOff)
+		}
+		compress.Type = &value
+	}
+
+	if value, ok := values[prefix+CompressQualityKey]; ok {
+		quality, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s is illegal, should be an integer", prefix+CompressQualityKey)
+		}
+		compress.Quality = &quality
+	}
+
+	if value, ok := values[prefix+CompressMinSizeKey]; ok {
+		minSize, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || minSize < 0 || minSize > MaxCompressMinSize {
+			return nil, fmt.Errorf("annotation %s is illegal, should be an integer in [0, %d]", prefix+CompressMinSizeKey, MaxCompressMinSize)
+		}
+		compress.MinSize = &minSize
+	}
+
+	if value, ok := values[prefix+CompressTypesKey]; ok {
+		types := splitList(strings.ToLower(value))
+		if len(types) == 0 {
+			return nil, fmt.Errorf("annotation %s is illegal, content type is required", prefix+CompressTypesKey)
+		}
+		for _, contentType := range types {
+			if contentType == CompressAnyType {
+				continue
+			}
+			if !strings.Contains(contentType, "/") || strings.ContainsAny(contentType, "|\"\\") || !isValidHeaderValue(contentType) {
+				return nil, fmt.Errorf("annotation %s is illegal, content type [%s] is invalid", prefix+CompressTypesKey, contentType)
+			}
+		}
+		compress.Types = types
+	}
+
+	return compress, nil
+}
+
+// Merge returns the compression whose unset fields are filled with defaults
+func (c *Compress) Merge(defaults *Compress) *Compress {
+	merged := *c
+	if defaults == nil {
+		return &merged
+	}
+
+	if merged.Type == nil {
+		merged.Type = defaults.Type
+	}
+	if merged.Quality == nil {
+		merged.Quality = defaults.Quality
+	}
+	if merged.MinSize == nil {
+		merged.MinSize = defaults.MinSize
+	}
+	if merged.Types == nil {
+		merged.Types = defaults.Types
+	}
+	return &merged
+}
+
+// Check checks the quality, which can only be checked after merged with defaults
+func (c *Compress) Check() error {
+	if !c.Enabled() || c.Quality == nil {
+		return nil
+	}
+
+	qualityRange := compressQualityRanges[*c.Type]
+	if *c.Quality < qualityRange[0] || *c.Quality > qualityRange[1] {
+		return fmt.Errorf("%s of %s should be in [%d, %d]", CompressQualityKey, *c.Type, qualityRange[0], qualityRange[1])
+	}
+	return nil
+}
+
+// Enabled returns true if responses should be compressed
+func (c *Compress) Enabled() bool {
+	return c.Type != nil && *c.Type != CompressOff
+}
+
+// QualityValue returns the quality, DefaultCompressQuality is used if not set
+func (c *Compress) QualityValue() int {
+	if c.Quality == nil {
+		return DefaultCompressQuality
+	}
+	return *c.Quality
+}
+
+// MinSizeValue returns the min size of responses to compress, 0 is used if not set
+func (c *Compress) MinSizeValue() int {
+	if c.MinSize == nil {
+		return 0
+	}
+	return *c.MinSize
+}
+
+// ContentTypes returns the content types to compress, nil means all content types
+func (c *Compress) ContentTypes() []string {
+	types := c.Types
+	if types == nil {
+		types = DefaultCompressTypes
+	}
+	if contains(types, CompressAnyType) {
+		return nil
+	}
+	return types
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetCompress(t *testing.T) {
+	tests := []struct {
+		name        string
+		annots      map[string]string
+		defaults    map[string]string
+		wantEnabled bool
+		wantQuality int
+		wantMinSize int
+		wantTypes   []string
+		wantErr     bool
+	}{
+		{
+			name:        "not set",
+			annots:      map[string]string{},
+			wantEnabled: false,
+			wantQuality: DefaultCompressQuality,
+			wantTypes:   DefaultCompressTypes,
+		},
+		{
+			name: "gzip",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressTypeKey:    "GZIP",
+				BfeAnnotationPrefix + CompressQualityKey: "9",
+				BfeAnnotationPrefix + CompressMinSizeKey: "256",
+				BfeAnnotationPrefix + CompressTypesKey:   "application/json, text/html",
+			},
+			wantEnabled: true,
+			wantQuality: 9,
+			wantMinSize: 256,
+			wantTypes:   []string{"application/json", "text/html"},
+		},
+		{
+			name: "all types",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressTypeKey:  "brotli",
+				BfeAnnotationPrefix + CompressTypesKey: "*",
+			},
+			wantEnabled: true,
+			wantQuality: DefaultCompressQuality,
+			wantTypes:   nil,
+		},
+		{
+			name: "defaults",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressQualityKey: "3",
+			},
+			defaults: map[string]string{
+				CompressTypeKey:    "gzip",
+				CompressQualityKey: "5",
+				CompressMinSizeKey: "1024",
+			},
+			wantEnabled: true,
+			wantQuality: 3,
+			wantMinSize: 1024,
+			wantTypes:   DefaultCompressTypes,
+		},
+		{
+			name: "disabled by ingress",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressTypeKey: "off",
+			},
+			defaults: map[string]string{
+				CompressTypeKey: "brotli",
+			},
+			wantEnabled: false,
+			wantQuality: DefaultCompressQuality,
+			wantTypes:   DefaultCompressTypes,
+		},
+		{
+			name: "invalid type",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressTypeKey: "deflate",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid quality",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressTypeKey:    "gzip",
+				BfeAnnotationPrefix + CompressQualityKey: "11",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid quality of default type",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressQualityKey: "10",
+			},
+			defaults: map[string]string{
+				CompressTypeKey: "gzip",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid min size",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressMinSizeKey: "1025",
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty types",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressTypesKey: " , ",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid type in types",
+			annots: map[string]string{
+				BfeAnnotationPrefix + CompressTypesKey: "text/html|text/plain",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaults, err := ParseCompress(tt.defaults)
+			if err != nil {
+				t.Fatalf("ParseCompress() error = %v", err)
+			}
+
+			got, err := GetCompress(tt.annots)
+			if err == nil {
+				got = got.Merge(defaults)
+				err = got.Check()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCompress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got.Enabled() != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", got.Enabled(), tt.wantEnabled)
+			}
+			if got.QualityValue() != tt.wantQuality {
+				t.Errorf("QualityValue() = %v, want %v", got.QualityValue(), tt.wantQuality)
+			}
+			if got.MinSizeValue() != tt.wantMinSize {
+				t.Errorf("MinSizeValue() = %v, want %v", got.MinSizeValue(), tt.wantMinSize)
+			}
+			if !reflect.DeepEqual(got.ContentTypes(), tt.wantTypes) {
+				t.Errorf("ContentTypes() = %v, want %v", got.ContentTypes(), tt.wantTypes)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const corsAnnotationPrefix = BfeAnnotationPrefix + "cors."
+
+// the annotations related to CORS, values of lists are delimited by ','
+const (
+	CorsAllowOriginsAnnotation     = corsAnnotationPrefix + "allow-origins"
+	CorsAllowMethodsAnnotation     = corsAnnotationPrefix + "allow-methods"
+	CorsAllowHeadersAnnotation     = corsAnnotationPrefix + "allow-headers"
+	CorsExposeHeadersAnnotation    = corsAnnotationPrefix + "expose-headers"
+	CorsAllowCredentialsAnnotation = corsAnnotationPrefix + "allow-credentials"
+	CorsMaxAgeAnnotation           = corsAnnotationPrefix + "max-age"
+)
+
+const (
+	// CorsOriginVariable means the origin of the request is allowed
+	CorsOriginVariable = "%origin"
+
+	corsMaxAgeMin = -1
+	corsMaxAgeMax = 86400
+)
+
+var corsMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPatch,
+}
+
+// Cors defines the CORS policy of an Ingress.
+// Refer to https://www.bfe-networks.net/en_us/modules/mod_cors/mod_cors/.
+type Cors struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           *int
+}
+
+// GetCors parse annotations "cors.*", nil is returned if "cors.allow-origins" is not set
+func GetCors(annotations map[string]string) (*Cors, error) {
+	if _, ok := annotations[CorsAllowOriginsAnnotation]; !ok {
+		for key := range annotations {
+			if strings.HasPrefix(key, corsAnnotationPrefix) {
+				return nil, fmt.Errorf("annotation %s is required when %s is set", CorsAllowOriginsAnnotation, key)
+			}
+		}
+		return nil, nil
+	}
+
+	var err error
+	cors := &Cors{
+		AllowOrigins:  splitList(annotations[CorsAllowOriginsAnnotation]),
+		AllowMethods:  splitList(annotations[CorsAllowMethodsAnnotation]),
+		AllowHeaders:  splitList(annotations[CorsAllowHeadersAnnotation]),
+		ExposeHeaders: splitList(annotations[CorsExposeHeadersAnnotation]),
+	}
+	if cors.AllowCredentials, err = getBool(annotations, CorsAllowCredentialsAnnotation, false); err != nil {
+		return nil, err
+	}
+	if value, ok := annotations[CorsMaxAgeAnnotation]; ok {
+		maxAge, err := strconv.Atoi(value)
+		if err != nil || maxAge < corsMaxAgeMin || maxAge > corsMaxAgeMax {
+			return nil, fmt.Errorf("annotation %s is illegal, should be an integer in [%d, %d]", CorsMaxAgeAnnotation, corsMaxAgeMin, corsMaxAgeMax)
+		}
+		cors.MaxAge = &maxAge
+	}
+
+	if err := cors.check(); err != nil {
+		return nil, err
+	}
+	return cors, nil
+}
+
+// check checks the CORS policy with the same restrictions as mod_cors
+func (c *Cors) check() error {
+	if len(c.AllowOrigins) == 0 {
+		return fmt.Errorf("annotation %s is illegal, at least one origin is required", CorsAllowOriginsAnnotation)
+	}
+	for _, origin := range c.AllowOrigins {
+		if strings.HasPrefix(origin, "%") && origin != CorsOriginVariable {
+			return fmt.Errorf("annotation %s is illegal, only variable %s is supported", CorsAllowOriginsAnnotation, CorsOriginVariable)
+		}
+		if (origin == "*" || origin == "null") && len(c.AllowOrigins) != 1 {
+			return fmt.Errorf("annotation %s is illegal, %s can't be used with other origins", CorsAllowOriginsAnnotation, origin)
+		}
+		if origin == "*" && c.AllowCredentials {
+			return fmt.Errorf("annotation %s can't be true when %s is *", CorsAllowCredentialsAnnotation, CorsAllowOriginsAnnotation)
+		}
+	}
+	if err := checkWildcardList(CorsAllowOriginsAnnotation, c.AllowOrigins); err != nil {
+		return err
+	}
+
+	for i, method := range c.AllowMethods {
+		c.AllowMethods[i] = strings.ToUpper(method)
+		if c.AllowMethods[i] != "*" && !contains(corsMethods, c.AllowMethods[i]) {
+			return fmt.Errorf("annotation %s is illegal, unsupported method %s", CorsAllowMethodsAnnotation, method)
+		}
+	}
+	if err := checkWildcardList(CorsAllowMethodsAnnotation, c.AllowMethods); err != nil {
+		return err
+	}
+
+	for _, item := range []struct {
+		annotation string
+		headers    []string
+	}{
+		{CorsAllowHeadersAnnotation, c.AllowHeaders},
+		{CorsExposeHeadersAnnotation, c.ExposeHeaders},
+	} {
+		for _, header := range item.headers {
+			if header != "*" && !isValidHeaderName(header) {
+				return fmt.Errorf("annotation %s is illegal, invalid header name [%s]", item.annotation, header)
+			}
+		}
+		if err := checkWildcardList(item.annotation, item.headers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkWildcardList checks "*" is the only element if it's used in the list
+func checkWildcardList(annotation string, list []string) error {
+	for _, item := range list {
+		if strings.Contains(item, "*") && (item != "*" || len(list) != 1) {
+			return fmt.Errorf("annotation %s is illegal, * should be the only element", annotation)
+		}
+	}
+	return nil
+}
+
+// splitList splits a list delimited by ',', and removes empty elements
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
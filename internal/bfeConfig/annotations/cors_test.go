@@ -0,0 +1,122 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetCors(t *testing.T) {
+	maxAge := 600
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    *Cors
+		wantErr bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "full policy",
+			annots: map[string]string{
+				CorsAllowOriginsAnnotation:     "https://a.example.com, https://b.example.com",
+				CorsAllowMethodsAnnotation:     "get,POST",
+				CorsAllowHeadersAnnotation:     "Content-Type, Authorization",
+				CorsExposeHeadersAnnotation:    "X-Request-Id",
+				CorsAllowCredentialsAnnotation: "true",
+				CorsMaxAgeAnnotation:           "600",
+			},
+			want: &Cors{
+				AllowOrigins:     []string{"https://a.example.com", "https://b.example.com"},
+				AllowMethods:     []string{"GET", "POST"},
+				AllowHeaders:     []string{"Content-Type", "Authorization"},
+				ExposeHeaders:    []string{"X-Request-Id"},
+				AllowCredentials: true,
+				MaxAge:           &maxAge,
+			},
+			wantErr: false,
+		},
+		{
+			name: "origin of request",
+			annots: map[string]string{
+				CorsAllowOriginsAnnotation: "%origin",
+			},
+			want: &Cors{
+				AllowOrigins: []string{"%origin"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "allow-origins not set",
+			annots: map[string]string{
+				CorsAllowMethodsAnnotation: "GET",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "wildcard origin with credentials",
+			annots: map[string]string{
+				CorsAllowOriginsAnnotation:     "*",
+				CorsAllowCredentialsAnnotation: "true",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "wildcard with other origins",
+			annots: map[string]string{
+				CorsAllowOriginsAnnotation: "*, https://a.example.com",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unsupported method",
+			annots: map[string]string{
+				CorsAllowOriginsAnnotation: "https://a.example.com",
+				CorsAllowMethodsAnnotation: "GET, FETCH",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal max-age",
+			annots: map[string]string{
+				CorsAllowOriginsAnnotation: "https://a.example.com",
+				CorsMaxAgeAnnotation:       "86401",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetCors(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetCors() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCors() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+// the keys of error pages, ErrorsRedirectKey is used both in annotations (with BfeAnnotationPrefix)
+// and in the global ConfigMap (without prefix)
+const (
+	// ErrorsRedirectKey maps status codes to redirect URLs, e.g. {"500": "https://status.example.com/"}
+	ErrorsRedirectKey = "errors.redirect"
+	// ErrorsPageKeyPrefix is the prefix of pages in the global ConfigMap, e.g. errors.page.500.html
+	ErrorsPageKeyPrefix = "errors.page."
+
+	ErrorsRedirectAnnotation = BfeAnnotationPrefix + ErrorsRedirectKey
+	// ErrorsPagesConfigMapAnnotation is the ConfigMap in the namespace of the ingress, which contains pages, e.g. key 500.html
+	ErrorsPagesConfigMapAnnotation = BfeAnnotationPrefix + "errors.pages-configmap"
+)
+
+// MaxErrorPageSize is the max size of an error page supported by mod_errors
+const MaxErrorPageSize = 2 * 1024 * 1024
+
+// content types of error pages, by the extension of keys
+var errorPageContentTypes = map[string]string{
+	"":      "text/html; charset=utf-8",
+	".html": "text/html; charset=utf-8",
+	".json": "application/json",
+	".txt":  "text/plain; charset=utf-8",
+}
+
+// ErrorAction defines how a response with an error status code is replaced, only one of Page and RedirectURL is set.
+// Refer to https://www.bfe-networks.net/en_us/modules/mod_errors/mod_errors/.
+type ErrorAction struct {
+	// Page is returned with the original status code
+	Page        string
+	ContentType string
+	RedirectURL string
+}
+
+// ErrorActions maps status codes to actions
+type ErrorActions map[int]*ErrorAction
+
+// StatusCodes returns status codes in ascending order
+func (a ErrorActions) StatusCodes() []int {
+	codes := make([]int, 0, len(a))
+	for code := range a {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+// GetErrorsPagesConfigMap returns name of the ConfigMap referenced by annotation "errors.pages-configmap"
+func GetErrorsPagesConfigMap(annotations map[string]string) (string, bool, error) {
+	name, ok := annotations[ErrorsPagesConfigMapAnnotation]
+	if ok && len(name) == 0 {
+		return "", false, fmt.Errorf("annotation %s is illegal, configmap name is required", ErrorsPagesConfigMapAnnotation)
+	}
+	return name, ok, nil
+}
+
+// GetErrorActions parse annotations "errors.*", pages are data of the ConfigMap referenced by "errors.pages-configmap".
+// nil is returned if no annotation is set.
+func GetErrorActions(annotations map[string]string, pages map[string]string) (ErrorActions, error) {
+	_, pagesOk, err := GetErrorsPagesConfigMap(annotations)
+	if err != nil {
+		return nil, err
+	}
+	value, redirectOk := annotations[ErrorsRedirectAnnotation]
+	if !pagesOk && !redirectOk {
+		return nil, nil
+	}
+
+	actions := make(ErrorActions)
+	if pagesOk {
+		if err := parseErrorPages(actions, pages, ""); err != nil {
+			return nil, fmt.Errorf("annotation %s is illegal, %s", ErrorsPagesConfigMapAnnotation, err)
+		}
+	}
+	if redirectOk {
+		if err := parseErrorRedirects(actions, value); err != nil {
+			return nil, fmt.Errorf("annotation %s is illegal, %s", ErrorsRedirectAnnotation, err)
+		}
+	}
+	return actions, nil
+}
+
+// ParseErrorActions parse keys "errors.redirect" and "errors.page.*" in the global ConfigMap
+func ParseErrorActions(data map[string]string) (ErrorActions, error) {
+	actions := make(ErrorActions)
+	if err := parseErrorPages(actions, data, ErrorsPageKeyPrefix); err != nil {
+		return nil, err
+	}
+	if value, ok := data[ErrorsRedirectKey]; ok {
+		if err := parseErrorRedirects(actions, value); err != nil {
+			return nil, fmt.Errorf("%s is illegal, %s", ErrorsRedirectKey, err)
+		}
+	}
+	return actions, nil
+}
+
+// parseErrorPages parse pages in keys of format <prefix><status code>[.html|.json|.txt]
+func parseErrorPages(actions ErrorActions, data map[string]string, prefix string) error {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, prefix)
+		ext := ""
+		if i := strings.Index(name, "."); i >= 0 {
+			name, ext = name[:i], name[i:]
+		}
+		contentType, ok := errorPageContentTypes[ext]
+		if !ok {
+			return fmt.Errorf("key %s is illegal, extension should be .html, .json or .txt", key)
+		}
+		code, err := parseErrorStatusCode(name)
+		if err != nil {
+			return fmt.Errorf("key %s is illegal, %s", key, err)
+		}
+		if _, ok := actions[code]; ok {
+			return fmt.Errorf("key %s is illegal, page of status code %d is duplicated", key, code)
+		}
+		if len(data[key]) > MaxErrorPageSize {
+			return fmt.Errorf("key %s is illegal, page size should not be larger than %d bytes", key, MaxErrorPageSize)
+		}
+		actions[code] = &ErrorAction{
+			Page:        data[key],
+			ContentType: contentType,
+		}
+	}
+	return nil
+}
+
+// parseErrorRedirects parse redirects in format of {"500": "https://status.example.com/"}
+func parseErrorRedirects(actions ErrorActions, value string) error {
+	var redirects map[string]string
+	if err := json.Unmarshal([]byte(value), &redirects); err != nil {
+		return fmt.Errorf("should be a JSON object of status codes and URLs, error: %s", err)
+	}
+
+	for name, redirectURL := range redirects {
+		code, err := parseErrorStatusCode(name)
+		if err != nil {
+			return err
+		}
+		if _, ok := actions[code]; ok {
+			return fmt.Errorf("status code %d has both page and redirect", code)
+		}
+		if !isValidRedirectURL(redirectURL) {
+			return fmt.Errorf("invalid redirect url [%s] of status code %d", redirectURL, code)
+		}
+		actions[code] = &ErrorAction{
+			RedirectURL: redirectURL,
+		}
+	}
+	return nil
+}
+
+// parseErrorStatusCode parse status code of 4xx or 5xx
+func parseErrorStatusCode(value string) (int, error) {
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 400 || code > 599 || bfe_http.StatusTextGet(code) == "" {
+		return 0, fmt.Errorf("invalid status code [%s], should be a 4xx or 5xx status code", value)
+	}
+	return code, nil
+}
+
+// isValidRedirectURL returns true if the url is an absolute URL or a path
+func isValidRedirectURL(value string) bool {
+	if len(value) == 0 || strings.IndexFunc(value, unicode.IsControl) >= 0 || strings.ContainsRune(value, ' ') {
+		return false
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (len(u.Scheme) > 0 && len(u.Host) > 0) || strings.HasPrefix(value, "/")
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetErrorActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		pages   map[string]string
+		want    ErrorActions
+		wantErr bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "pages and redirect",
+			annots: map[string]string{
+				ErrorsPagesConfigMapAnnotation: "error-pages",
+				ErrorsRedirectAnnotation:       `{"404": "/not-found", "503": "https://status.example.com/"}`,
+			},
+			pages: map[string]string{
+				"500":      "<p>error</p>",
+				"502.html": "<p>bad gateway</p>",
+				"504.json": `{"error": "timeout"}`,
+			},
+			want: ErrorActions{
+				404: {RedirectURL: "/not-found"},
+				500: {Page: "<p>error</p>", ContentType: "text/html; charset=utf-8"},
+				502: {Page: "<p>bad gateway</p>", ContentType: "text/html; charset=utf-8"},
+				503: {RedirectURL: "https://status.example.com/"},
+				504: {Page: `{"error": "timeout"}`, ContentType: "application/json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "empty configmap",
+			annots: map[string]string{
+				ErrorsPagesConfigMapAnnotation: "",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "page and redirect of the same status code",
+			annots: map[string]string{
+				ErrorsPagesConfigMapAnnotation: "error-pages",
+				ErrorsRedirectAnnotation:       `{"500": "/error"}`,
+			},
+			pages: map[string]string{
+				"500.html": "<p>error</p>",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "duplicated page",
+			annots: map[string]string{
+				ErrorsPagesConfigMapAnnotation: "error-pages",
+			},
+			pages: map[string]string{
+				"500.html": "<p>error</p>",
+				"500.txt":  "error",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unsupported extension",
+			annots: map[string]string{
+				ErrorsPagesConfigMapAnnotation: "error-pages",
+			},
+			pages: map[string]string{
+				"500.png": "",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "too large page",
+			annots: map[string]string{
+				ErrorsPagesConfigMapAnnotation: "error-pages",
+			},
+			pages: map[string]string{
+				"500.html": strings.Repeat("a", MaxErrorPageSize+1),
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal status code",
+			annots: map[string]string{
+				ErrorsRedirectAnnotation: `{"302": "/error"}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "relative url",
+			annots: map[string]string{
+				ErrorsRedirectAnnotation: `{"500": "error.html"}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "not json",
+			annots: map[string]string{
+				ErrorsRedirectAnnotation: `500=/error`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetErrorActions(tt.annots, tt.pages)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetErrorActions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetErrorActions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrorActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    ErrorActions
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			data:    map[string]string{},
+			want:    ErrorActions{},
+			wantErr: false,
+		},
+		{
+			name: "pages and redirect",
+			data: map[string]string{
+				"errors.page.500.html": "<p>error</p>",
+				ErrorsRedirectKey:      `{"503": "https://status.example.com/"}`,
+				"ssl-redirect":         "true",
+			},
+			want: ErrorActions{
+				500: {Page: "<p>error</p>", ContentType: "text/html; charset=utf-8"},
+				503: {RedirectURL: "https://status.example.com/"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "illegal page",
+			data: map[string]string{
+				"errors.page.5xx.html": "<p>error</p>",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseErrorActions(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseErrorActions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseErrorActions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
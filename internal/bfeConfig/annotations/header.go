@@ -0,0 +1,138 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const headerAnnotationPrefix = BfeAnnotationPrefix + "header."
+
+// the annotations related to modifying request headers before forwarded to backends
+const (
+	HeaderRequestSetAnnotation    = headerAnnotationPrefix + "request-set"
+	HeaderRequestAddAnnotation    = headerAnnotationPrefix + "request-add"
+	HeaderRequestDeleteAnnotation = headerAnnotationPrefix + "request-delete"
+)
+
+// the annotations related to modifying response headers before sent to clients
+const (
+	HeaderResponseSetAnnotation    = headerAnnotationPrefix + "response-set"
+	HeaderResponseAddAnnotation    = headerAnnotationPrefix + "response-add"
+	HeaderResponseDeleteAnnotation = headerAnnotationPrefix + "response-delete"
+)
+
+// HeaderAction is an action of mod_header, e.g. {Cmd: "REQ_HEADER_SET", Params: ["X-Tenant-Id", "tenant-a"]}.
+// Refer to https://www.bfe-networks.net/en_us/modules/mod_header/mod_header/.
+type HeaderAction struct {
+	Cmd    string
+	Params []string
+}
+
+// GetHeaderActions parse annotations "header.*".
+// Headers are deleted first, then set, then added, and headers of the same annotation are sorted by name.
+func GetHeaderActions(annotations map[string]string) ([]HeaderAction, error) {
+	var actions []HeaderAction
+	for _, item := range []struct {
+		annotation string
+		cmd        string
+	}{
+		{HeaderRequestDeleteAnnotation, "REQ_HEADER_DEL"},
+		{HeaderRequestSetAnnotation, "REQ_HEADER_SET"},
+		{HeaderRequestAddAnnotation, "REQ_HEADER_ADD"},
+		{HeaderResponseDeleteAnnotation, "RSP_HEADER_DEL"},
+		{HeaderResponseSetAnnotation, "RSP_HEADER_SET"},
+		{HeaderResponseAddAnnotation, "RSP_HEADER_ADD"},
+	} {
+		value, ok := annotations[item.annotation]
+		if !ok {
+			continue
+		}
+
+		var err error
+		var itemActions []HeaderAction
+		if strings.HasSuffix(item.cmd, "_DEL") {
+			itemActions, err = parseHeaderNames(item.annotation, item.cmd, value)
+		} else {
+			itemActions, err = parseHeaderValues(item.annotation, item.cmd, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, itemActions...)
+	}
+
+	return actions, nil
+}
+
+// parseHeaderNames parse annotation in format of ["X-Debug", "X-Internal"]
+func parseHeaderNames(annotation, cmd, value string) ([]HeaderAction, error) {
+	var names []string
+	if err := json.Unmarshal([]byte(value), &names); err != nil {
+		return nil, fmt.Errorf("annotation %s is illegal, should be a JSON list of header names, error: %s", annotation, err)
+	}
+
+	sort.Strings(names)
+	actions := make([]HeaderAction, 0, len(names))
+	for _, name := range names {
+		if !isValidHeaderName(name) {
+			return nil, fmt.Errorf("annotation %s is illegal, invalid header name [%s]", annotation, name)
+		}
+		actions = append(actions, HeaderAction{Cmd: cmd, Params: []string{name}})
+	}
+	return actions, nil
+}
+
+// parseHeaderValues parse annotation in format of {"X-Tenant-Id": "tenant-a", "X-Client-Ip": "%bfe_client_ip"}
+func parseHeaderValues(annotation, cmd, value string) ([]HeaderAction, error) {
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(value), &headers); err != nil {
+		return nil, fmt.Errorf("annotation %s is illegal, should be a JSON object of header names and values, error: %s", annotation, err)
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	actions := make([]HeaderAction, 0, len(names))
+	for _, name := range names {
+		if !isValidHeaderName(name) {
+			return nil, fmt.Errorf("annotation %s is illegal, invalid header name [%s]", annotation, name)
+		}
+		if len(headers[name]) == 0 || !isValidHeaderValue(headers[name]) {
+			return nil, fmt.Errorf("annotation %s is illegal, invalid value of header [%s]", annotation, name)
+		}
+		actions = append(actions, HeaderAction{Cmd: cmd, Params: []string{name, headers[name]}})
+	}
+	return actions, nil
+}
+
+// isValidHeaderName returns true if name is a token defined in RFC 7230
+func isValidHeaderName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, c := range name {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", c) {
+			return false
+		}
+	}
+	return true
+}
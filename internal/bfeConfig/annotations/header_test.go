@@ -0,0 +1,98 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetHeaderActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    []HeaderAction
+		wantErr bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "request and response headers",
+			annots: map[string]string{
+				HeaderRequestSetAnnotation:     `{"X-Tenant-Id": "tenant-a", "X-Forwarded-For": "%bfe_client_ip"}`,
+				HeaderRequestDeleteAnnotation:  `["X-Debug"]`,
+				HeaderResponseAddAnnotation:    `{"X-Request-Id": "%bfe_log_id"}`,
+				HeaderResponseDeleteAnnotation: `["Server", "X-Powered-By"]`,
+			},
+			want: []HeaderAction{
+				{Cmd: "REQ_HEADER_DEL", Params: []string{"X-Debug"}},
+				{Cmd: "REQ_HEADER_SET", Params: []string{"X-Forwarded-For", "%bfe_client_ip"}},
+				{Cmd: "REQ_HEADER_SET", Params: []string{"X-Tenant-Id", "tenant-a"}},
+				{Cmd: "RSP_HEADER_DEL", Params: []string{"Server"}},
+				{Cmd: "RSP_HEADER_DEL", Params: []string{"X-Powered-By"}},
+				{Cmd: "RSP_HEADER_ADD", Params: []string{"X-Request-Id", "%bfe_log_id"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "illegal json",
+			annots: map[string]string{
+				HeaderRequestSetAnnotation: `["X-Tenant-Id"]`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal header name",
+			annots: map[string]string{
+				HeaderResponseDeleteAnnotation: `["X Powered By"]`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal header value",
+			annots: map[string]string{
+				HeaderResponseSetAnnotation: `{"X-Frame-Options": "DENY\r\nSet-Cookie: a=b"}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "empty header value",
+			annots: map[string]string{
+				HeaderRequestAddAnnotation: `{"X-Tenant-Id": ""}`,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetHeaderActions(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetHeaderActions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetHeaderActions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
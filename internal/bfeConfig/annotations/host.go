@@ -0,0 +1,95 @@
+// Copyright (c) 2021 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// WildcardHostSingleLabel makes "*" match exactly one label, e.g. "*.foo.com" matches "a.foo.com" but not "a.b.foo.com"
+	WildcardHostSingleLabel = "single-label"
+	// WildcardHostMultiLabel makes "*" match one or more labels, e.g. "*.foo.com" matches "a.foo.com" and "a.b.foo.com"
+	WildcardHostMultiLabel = "multi-label"
+)
+
+// HostAliases define struct of annotation "router.host-aliases"
+// example: {"foo.com": ["www.foo.com", "foo.org"]}
+type HostAliases map[string][]string
+
+// GetHostAliases parse annotation "router.host-aliases", hosts and aliases are normalized by NormalizeHost
+func GetHostAliases(annotations map[string]string) (HostAliases, error) {
+	value, ok := annotations[HostAliasesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	var raw HostAliases
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("annotation %s is illegal, error: %s", HostAliasesAnnotation, err)
+	}
+
+	aliases := make(HostAliases)
+	for host, list := range raw {
+		host = NormalizeHost(host)
+		if len(host) == 0 || !isValidHost(host) {
+			return nil, fmt.Errorf("annotation %s is illegal, host [%s] is invalid", HostAliasesAnnotation, host)
+		}
+		for _, alias := range list {
+			alias = NormalizeHost(alias)
+			if !isValidHost(alias) {
+				return nil, fmt.Errorf("annotation %s is illegal, alias [%s] of host [%s] is invalid", HostAliasesAnnotation, alias, host)
+			}
+			if alias != host && !contains(aliases[host], alias) {
+				aliases[host] = append(aliases[host], alias)
+			}
+		}
+	}
+	return aliases, nil
+}
+
+// GetWildcardHost parse annotation "router.wildcard-host", WildcardHostSingleLabel is returned if not set
+func GetWildcardHost(annotations map[string]string) (string, error) {
+	value, ok := annotations[WildcardHostAnnotation]
+	if !ok {
+		return WildcardHostSingleLabel, nil
+	}
+
+	value = strings.TrimSpace(value)
+	if value != WildcardHostSingleLabel && value != WildcardHostMultiLabel {
+		return "", fmt.Errorf("annotation %s is illegal, should be %s or %s", WildcardHostAnnotation, WildcardHostSingleLabel, WildcardHostMultiLabel)
+	}
+	return value, nil
+}
+
+// NormalizeHost converts host to lower case and removes the optional port, as hosts are matched
+// case-insensitively and regardless of port
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// isValidHost checks a host name, a wildcard host should start with "*."
+func isValidHost(host string) bool {
+	return len(validation.IsDNS1123Subdomain(strings.TrimPrefix(host, "*."))) == 0
+}
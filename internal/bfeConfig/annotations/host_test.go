@@ -0,0 +1,124 @@
+// Copyright (c) 2021 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetHostAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   map[string]string
+		want    HostAliases
+		wantErr bool
+	}{
+		{
+			name:  "not set",
+			value: map[string]string{},
+			want:  nil,
+		},
+		{
+			name:  "normal",
+			value: map[string]string{HostAliasesAnnotation: `{"foo.com": ["www.foo.com", "*.foo.org"]}`},
+			want:  HostAliases{"foo.com": {"www.foo.com", "*.foo.org"}},
+		},
+		{
+			name:  "normalized",
+			value: map[string]string{HostAliasesAnnotation: `{"Foo.com": ["WWW.foo.com:8080", "foo.com", "www.foo.com"]}`},
+			want:  HostAliases{"foo.com": {"www.foo.com"}},
+		},
+		{
+			name:    "abnormal json",
+			value:   map[string]string{HostAliasesAnnotation: `{"foo.com": "www.foo.com"}`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid alias",
+			value:   map[string]string{HostAliasesAnnotation: `{"foo.com": ["www.*.foo.com"]}`},
+			wantErr: true,
+		},
+		{
+			name:    "empty host",
+			value:   map[string]string{HostAliasesAnnotation: `{"": ["www.foo.com"]}`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetHostAliases(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetHostAliases() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetHostAliases() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetWildcardHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "not set",
+			value: map[string]string{},
+			want:  WildcardHostSingleLabel,
+		},
+		{
+			name:  "multi-label",
+			value: map[string]string{WildcardHostAnnotation: "multi-label"},
+			want:  WildcardHostMultiLabel,
+		},
+		{
+			name:    "invalid",
+			value:   map[string]string{WildcardHostAnnotation: "multi"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetWildcardHost(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetWildcardHost() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetWildcardHost() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	tests := map[string]string{
+		"foo.com":        "foo.com",
+		"Foo.COM":        "foo.com",
+		"foo.com:8080":   "foo.com",
+		" *.Foo.com:80 ": "*.foo.com",
+		"":               "",
+	}
+	for host, want := range tests {
+		if got := NormalizeHost(host); got != want {
+			t.Errorf("NormalizeHost(%s) = %s, want %s", host, got, want)
+		}
+	}
+}
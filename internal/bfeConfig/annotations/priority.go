@@ -14,25 +14,47 @@
 package annotations
 
 const (
-	PriorityBasic        = 10
-	PriorityHeader       = 20
-	PriorityCookie       = 30
-	PriorityCookieHeader = 40
+	PriorityBasic = 10
 )
 
+// the weights of advanced conditions in priority, a rule with more conditions always has
+// higher priority, rules with the same number of conditions are ordered by the weights
+const (
+	priorityConditionCount = 100
+	priorityProtocol       = 1
+	priorityCIDR           = 2
+	priorityMethod         = 4
+	priorityQuery          = 8
+	priorityHeader         = 16
+	priorityCookie         = 32
+	priorityCondition      = 64
+)
+
+var priorityWeights = map[string]int{
+	CookieAnnotation:    priorityCookie,
+	HeaderAnnotation:    priorityHeader,
+	QueryAnnotation:     priorityQuery,
+	MethodAnnotation:    priorityMethod,
+	CIDRAnnotation:      priorityCIDR,
+	ProtocolAnnotation:  priorityProtocol,
+	ConditionAnnotation: priorityCondition,
+}
+
 func Priority(annotations map[string]string) int {
-	_, ok1 := annotations[CookieAnnotation]
-	_, ok2 := annotations[HeaderAnnotation]
-
-	if ok1 && ok2 {
-		return PriorityCookieHeader
-	} else if ok1 {
-		return PriorityCookie
-	} else if ok2 {
-		return PriorityHeader
-	} else {
-		return PriorityBasic
+	priority := PriorityBasic
+	for annotation, weight := range priorityWeights {
+		value, ok := annotations[annotation]
+		if !ok {
+			continue
+		}
+		count := 1
+		// each item in the structured form of router.header and router.cookie is a condition
+		if annotation == CookieAnnotation || annotation == HeaderAnnotation {
+			count = routerMatchCount(value)
+		}
+		priority += count * (priorityConditionCount + weight)
 	}
+	return priority
 }
 
 func Equal(annotations1, annotations2 map[string]string) bool {
@@ -40,6 +62,22 @@ func Equal(annotations1, annotations2 map[string]string) bool {
 		return true
 	}
 
-	return annotations1[CookieAnnotation] == annotations2[CookieAnnotation] &&
-		annotations1[HeaderAnnotation] == annotations2[HeaderAnnotation]
+	// a canary ingress takes part of the traffic of the main ingress, so they don't conflict
+	if IsCanary(annotations1) != IsCanary(annotations2) {
+		return false
+	}
+
+	// compare generated conditions, so that equivalent annotations, e.g. "GET,POST" and "post, get", are detected
+	expression1, err1 := GetRouteExpression(annotations1)
+	expression2, err2 := GetRouteExpression(annotations2)
+	if err1 == nil && err2 == nil {
+		return expression1 == expression2
+	}
+
+	for annotation := range priorityWeights {
+		if annotations1[annotation] != annotations2[annotation] {
+			return false
+		}
+	}
+	return true
 }
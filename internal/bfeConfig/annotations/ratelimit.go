@@ -0,0 +1,142 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const rateLimitAnnotationPrefix = BfeAnnotationPrefix + "ratelimit."
+
+// the annotations related to rate limiting
+const (
+	RateLimitRPSAnnotation    = rateLimitAnnotationPrefix + "rps"
+	RateLimitBurstAnnotation  = rateLimitAnnotationPrefix + "burst"
+	RateLimitKeyAnnotation    = rateLimitAnnotationPrefix + "key"
+	RateLimitActionAnnotation = rateLimitAnnotationPrefix + "action"
+)
+
+// the keys which requests are counted by
+const (
+	RateLimitKeyClientIP = "client-ip"
+	RateLimitKeyPath     = "path"
+	RateLimitKeyHeader   = "header"
+	RateLimitKeyCookie   = "cookie"
+)
+
+const (
+	// RateLimitActionClose means the connection is closed directly when the limit is exceeded
+	RateLimitActionClose = "close"
+
+	rateLimitMax = 1000000
+)
+
+// RateLimit defines the rate limit of an Ingress.
+// Refer to https://www.bfe-networks.net/en_us/modules/mod_prison/mod_prison/.
+type RateLimit struct {
+	RPS   int32
+	Burst int32
+	// KeyType is one of client-ip, path, header and cookie
+	KeyType string
+	// KeyName is the name of header or cookie
+	KeyName string
+	Action  string
+}
+
+// Threshold returns the max number of requests allowed in one second
+func (r *RateLimit) Threshold() int32 {
+	return r.RPS + r.Burst
+}
+
+// GetRateLimit parse annotations "ratelimit.*", nil is returned if "ratelimit.rps" is not set
+func GetRateLimit(annotations map[string]string) (*RateLimit, error) {
+	if _, ok := annotations[RateLimitRPSAnnotation]; !ok {
+		for key := range annotations {
+			if strings.HasPrefix(key, rateLimitAnnotationPrefix) {
+				return nil, fmt.Errorf("annotation %s is required when %s is set", RateLimitRPSAnnotation, key)
+			}
+		}
+		return nil, nil
+	}
+
+	rps, err := getRateLimitNumber(annotations, RateLimitRPSAnnotation, 1)
+	if err != nil {
+		return nil, err
+	}
+	burst, err := getRateLimitNumber(annotations, RateLimitBurstAnnotation, 0)
+	if err != nil {
+		return nil, err
+	}
+	rateLimit := &RateLimit{
+		RPS:     rps,
+		Burst:   burst,
+		KeyType: RateLimitKeyClientIP,
+		Action:  RateLimitActionClose,
+	}
+
+	if key, ok := annotations[RateLimitKeyAnnotation]; ok {
+		if rateLimit.KeyType, rateLimit.KeyName, err = parseRateLimitKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if action, ok := annotations[RateLimitActionAnnotation]; ok {
+		// mod_prison can't reply requests with a custom status code, so only closing the connection is supported
+		if action != RateLimitActionClose {
+			return nil, fmt.Errorf("annotation %s is illegal, only %s is supported", RateLimitActionAnnotation, RateLimitActionClose)
+		}
+	}
+
+	return rateLimit, nil
+}
+
+// getRateLimitNumber parse an integer in [min, 1000000], 0 is returned if the annotation is not set
+func getRateLimitNumber(annotations map[string]string, annotation string, min int64) (int32, error) {
+	value, ok := annotations[annotation]
+	if !ok {
+		return 0, nil
+	}
+	number, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || number < min || number > rateLimitMax {
+		return 0, fmt.Errorf("annotation %s is illegal, should be an integer in [%d, %d]", annotation, min, rateLimitMax)
+	}
+	return int32(number), nil
+}
+
+// parseRateLimitKey parse key in format of "client-ip", "path", "header:<name>" or "cookie:<name>"
+func parseRateLimitKey(key string) (string, string, error) {
+	keyType, keyName := key, ""
+	i := strings.Index(key, ":")
+	if i >= 0 {
+		keyType, keyName = key[:i], key[i+1:]
+	}
+
+	switch keyType {
+	case RateLimitKeyClientIP, RateLimitKeyPath:
+		if i >= 0 {
+			return "", "", fmt.Errorf("annotation %s is illegal, key %s doesn't need a name", RateLimitKeyAnnotation, keyType)
+		}
+	case RateLimitKeyHeader, RateLimitKeyCookie:
+		if !isValidHeaderName(keyName) {
+			return "", "", fmt.Errorf("annotation %s is illegal, invalid %s name [%s]", RateLimitKeyAnnotation, keyType, keyName)
+		}
+	default:
+		return "", "", fmt.Errorf("annotation %s is illegal, should be one of client-ip, path, header:<name> and cookie:<name>", RateLimitKeyAnnotation)
+	}
+
+	return keyType, keyName, nil
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetRateLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    *RateLimit
+		wantErr bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "default key and action",
+			annots: map[string]string{
+				RateLimitRPSAnnotation: "10",
+			},
+			want: &RateLimit{
+				RPS:     10,
+				KeyType: RateLimitKeyClientIP,
+				Action:  RateLimitActionClose,
+			},
+			wantErr: false,
+		},
+		{
+			name: "header key with burst",
+			annots: map[string]string{
+				RateLimitRPSAnnotation:    "10",
+				RateLimitBurstAnnotation:  "5",
+				RateLimitKeyAnnotation:    "header:X-Api-Key",
+				RateLimitActionAnnotation: "close",
+			},
+			want: &RateLimit{
+				RPS:     10,
+				Burst:   5,
+				KeyType: RateLimitKeyHeader,
+				KeyName: "X-Api-Key",
+				Action:  RateLimitActionClose,
+			},
+			wantErr: false,
+		},
+		{
+			name: "cookie key",
+			annots: map[string]string{
+				RateLimitRPSAnnotation: "1",
+				RateLimitKeyAnnotation: "cookie:session",
+			},
+			want: &RateLimit{
+				RPS:     1,
+				KeyType: RateLimitKeyCookie,
+				KeyName: "session",
+				Action:  RateLimitActionClose,
+			},
+			wantErr: false,
+		},
+		{
+			name: "rps not set",
+			annots: map[string]string{
+				RateLimitBurstAnnotation: "5",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal rps",
+			annots: map[string]string{
+				RateLimitRPSAnnotation: "0",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal burst",
+			annots: map[string]string{
+				RateLimitRPSAnnotation:   "10",
+				RateLimitBurstAnnotation: "-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "illegal key",
+			annots: map[string]string{
+				RateLimitRPSAnnotation: "10",
+				RateLimitKeyAnnotation: "query:uid",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "header key without name",
+			annots: map[string]string{
+				RateLimitRPSAnnotation: "10",
+				RateLimitKeyAnnotation: "header:",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "client-ip key with name",
+			annots: map[string]string{
+				RateLimitRPSAnnotation: "10",
+				RateLimitKeyAnnotation: "client-ip:X-Real-Ip",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "unsupported action",
+			annots: map[string]string{
+				RateLimitRPSAnnotation:    "10",
+				RateLimitActionAnnotation: "429",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetRateLimit(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetRateLimit() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRateLimit() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
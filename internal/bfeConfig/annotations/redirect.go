@@ -20,8 +20,9 @@ import (
 )
 
 const (
-	redirectAnnotationPrefix          = BfeAnnotationPrefix + "redirect."
-	defaultRedirectResponseStatusCode = 302
+	redirectAnnotationPrefix             = BfeAnnotationPrefix + "redirect."
+	defaultRedirectResponseStatusCode    = 302
+	defaultSSLRedirectResponseStatusCode = 308
 )
 
 // the annotations related to how to set the location in the redirection response's header
@@ -35,6 +36,12 @@ const (
 // RedirectResponseStatusAnnotation is used to set the status code of the redirection response manually
 const RedirectResponseStatusAnnotation = redirectAnnotationPrefix + "response-status"
 
+// the annotations related to redirecting HTTP requests of TLS hosts to HTTPS
+const (
+	RedirectSSLAnnotation     = redirectAnnotationPrefix + "ssl-redirect"
+	RedirectSSLHSTSAnnotation = redirectAnnotationPrefix + "ssl-redirect-hsts"
+)
+
 // GetRedirectAction try to parse the cmd and the param of the redirection action from the annotations
 func GetRedirectAction(annotations map[string]string) (cmd, param string, err error) {
 	switch {
@@ -54,9 +61,28 @@ func GetRedirectAction(annotations map[string]string) (cmd, param string, err er
 }
 
 func GetRedirectStatusCode(annotations map[string]string) (int, error) {
+	return getRedirectStatusCode(annotations, defaultRedirectResponseStatusCode)
+}
+
+// GetSSLRedirectStatusCode returns the status code of the redirection from HTTP to HTTPS
+func GetSSLRedirectStatusCode(annotations map[string]string) (int, error) {
+	return getRedirectStatusCode(annotations, defaultSSLRedirectResponseStatusCode)
+}
+
+// GetSSLRedirect parse annotation "redirect.ssl-redirect", defaultValue is used if the annotation is not set
+func GetSSLRedirect(annotations map[string]string, defaultValue bool) (bool, error) {
+	return getBool(annotations, RedirectSSLAnnotation, defaultValue)
+}
+
+// GetSSLRedirectHSTS parse annotation "redirect.ssl-redirect-hsts", defaultValue is used if the annotation is not set
+func GetSSLRedirectHSTS(annotations map[string]string, defaultValue bool) (bool, error) {
+	return getBool(annotations, RedirectSSLHSTSAnnotation, defaultValue)
+}
+
+func getRedirectStatusCode(annotations map[string]string, defaultStatusCode int) (int, error) {
 	statusCodeStr := annotations[RedirectResponseStatusAnnotation]
 	if statusCodeStr == "" {
-		return defaultRedirectResponseStatusCode, nil
+		return defaultStatusCode, nil
 	}
 
 	statusCodeInt64, err := strconv.ParseInt(statusCodeStr, 10, 64)
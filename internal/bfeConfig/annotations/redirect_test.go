@@ -0,0 +1,123 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"testing"
+)
+
+func TestGetSSLRedirect(t *testing.T) {
+	tests := []struct {
+		name         string
+		annots       map[string]string
+		defaultValue bool
+		want         bool
+		wantErr      bool
+	}{
+		{
+			name:         "not set, default disabled",
+			annots:       map[string]string{},
+			defaultValue: false,
+			want:         false,
+			wantErr:      false,
+		},
+		{
+			name:         "not set, default enabled",
+			annots:       map[string]string{},
+			defaultValue: true,
+			want:         true,
+			wantErr:      false,
+		},
+		{
+			name:         "disabled by annotation",
+			annots:       map[string]string{RedirectSSLAnnotation: "false"},
+			defaultValue: true,
+			want:         false,
+			wantErr:      false,
+		},
+		{
+			name:         "enabled by annotation",
+			annots:       map[string]string{RedirectSSLAnnotation: "true"},
+			defaultValue: false,
+			want:         true,
+			wantErr:      false,
+		},
+		{
+			name:         "illegal value",
+			annots:       map[string]string{RedirectSSLAnnotation: "on"},
+			defaultValue: false,
+			want:         false,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetSSLRedirect(tt.annots, tt.defaultValue)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetSSLRedirect() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetSSLRedirect() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSSLRedirectStatusCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "default",
+			annots:  map[string]string{RedirectSSLAnnotation: "true"},
+			want:    308,
+			wantErr: false,
+		},
+		{
+			name: "set by annotation",
+			annots: map[string]string{
+				RedirectSSLAnnotation:            "true",
+				RedirectResponseStatusAnnotation: "301",
+			},
+			want:    301,
+			wantErr: false,
+		},
+		{
+			name: "illegal value",
+			annots: map[string]string{
+				RedirectSSLAnnotation:            "true",
+				RedirectResponseStatusAnnotation: "moved",
+			},
+			want:    0,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetSSLRedirectStatusCode(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetSSLRedirectStatusCode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetSSLRedirectStatusCode() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
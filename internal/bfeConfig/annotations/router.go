@@ -15,39 +15,90 @@ package annotations
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
 )
 
 const (
-	CookieKey = "router.cookie"
-	HeaderKey = "router.header"
+	CookieKey   = "router.cookie"
+	HeaderKey   = "router.header"
+	QueryKey    = "router.query"
+	MethodKey   = "router.method"
+	CIDRKey     = "router.cidr"
+	ProtocolKey = "router.protocol"
+	// ConditionKey is a raw bfe condition expression, e.g. req_query_key_in("debug")
+	ConditionKey = "router.condition"
+	// PathRegexKey makes paths of type ImplementationSpecific regular expressions
+	PathRegexKey = "router.path-regex"
+	// PriorityKey overrides the order of rules of the same host, rules with higher priority are matched first
+	PriorityKey = "router.priority"
+	// HostAliasesKey maps hosts of rules to their aliases, which are served by the same rules
+	HostAliasesKey = "router.host-aliases"
+	// WildcardHostKey defines how many labels the leading "*" of wildcard hosts matches
+	WildcardHostKey = "router.wildcard-host"
 
-	CookieAnnotation = BfeAnnotationPrefix + CookieKey
-	HeaderAnnotation = BfeAnnotationPrefix + HeaderKey
+	CookieAnnotation       = BfeAnnotationPrefix + CookieKey
+	HeaderAnnotation       = BfeAnnotationPrefix + HeaderKey
+	QueryAnnotation        = BfeAnnotationPrefix + QueryKey
+	MethodAnnotation       = BfeAnnotationPrefix + MethodKey
+	CIDRAnnotation         = BfeAnnotationPrefix + CIDRKey
+	ProtocolAnnotation     = BfeAnnotationPrefix + ProtocolKey
+	ConditionAnnotation    = BfeAnnotationPrefix + ConditionKey
+	PathRegexAnnotation    = BfeAnnotationPrefix + PathRegexKey
+	PriorityAnnotation     = BfeAnnotationPrefix + PriorityKey
+	HostAliasesAnnotation  = BfeAnnotationPrefix + HostAliasesKey
+	WildcardHostAnnotation = BfeAnnotationPrefix + WildcardHostKey
 )
 
+// routerAnnotations are the annotations of advanced conditions, in the order of primitives in the route expression
+var routerAnnotations = []struct {
+	annotation string
+	primitive  func(value string) (string, error)
+}{
+	{CookieAnnotation, cookiePrimitive},
+	{HeaderAnnotation, headerPrimitive},
+	{QueryAnnotation, queryPrimitive},
+	{MethodAnnotation, methodPrimitive},
+	{CIDRAnnotation, cidrPrimitive},
+	{ProtocolAnnotation, protocolPrimitive},
+	{ConditionAnnotation, conditionExpression},
+}
+
 func GetRouteExpression(annotations map[string]string) (string, error) {
-	var primitive1 string
-	var err error
-	if primitive1, err = cookiePrimitive(annotations[CookieAnnotation]); err != nil {
-		return "", err
+	var primitives []string
+	for _, router := range routerAnnotations {
+		primitive, err := router.primitive(annotations[router.annotation])
+		if err != nil {
+			return "", err
+		}
+		if len(primitive) > 0 {
+			primitives = append(primitives, primitive)
+		}
 	}
 
-	var primitive2 string
-	if primitive2, err = headerPrimitive(annotations[HeaderAnnotation]); err != nil {
-		return "", err
-	}
+	return strings.Join(primitives, "&&"), nil
+}
 
-	if len(primitive1) > 0 && len(primitive2) > 0 {
-		return primitive1 + "&&" + primitive2, nil
-	}
-	if len(primitive1) > 0 {
-		return primitive1, nil
+// GetPathRegex parse annotation "router.path-regex", false is returned if not set
+func GetPathRegex(annotations map[string]string) (bool, error) {
+	return getBool(annotations, PathRegexAnnotation, false)
+}
+
+// GetRoutePriority parse annotation "router.priority", 0 is returned if not set
+func GetRoutePriority(annotations map[string]string) (int, error) {
+	value, ok := annotations[PriorityAnnotation]
+	if !ok {
+		return 0, nil
 	}
-	if len(primitive2) > 0 {
-		return primitive2, nil
+
+	priority, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("annotation %s is illegal, should be an integer", PriorityAnnotation)
 	}
-	return "", nil
+	return int(priority), nil
 }
 
 // cookiePrimitive generates bfe condition primitive for cookie match
@@ -55,6 +106,9 @@ func cookiePrimitive(cookie string) (string, error) {
 	if len(cookie) == 0 {
 		return "", nil
 	}
+	if isRouterMatchList(cookie) {
+		return routerMatchPrimitive(cookieMatchKind, cookie)
+	}
 	index := strings.Index(cookie, ":")
 	if index == -1 || index == len(cookie)-1 {
 		return "", fmt.Errorf("cookie annotation[%s] is illegal", cookie)
@@ -65,11 +119,14 @@ func cookiePrimitive(cookie string) (string, error) {
 
 }
 
-// cookiePrimitive generates bfe condition primitive for header match
+// headerPrimitive generates bfe condition primitive for header match
 func headerPrimitive(header string) (string, error) {
 	if len(header) == 0 {
 		return "", nil
 	}
+	if isRouterMatchList(header) {
+		return routerMatchPrimitive(headerMatchKind, header)
+	}
 	index := strings.Index(header, ":")
 	if index == -1 || index == len(header)-1 {
 		return "", fmt.Errorf("header annotation[%s] is illegal", header)
@@ -78,3 +135,106 @@ func headerPrimitive(header string) (string, error) {
 	con := fmt.Sprintf("req_header_value_in(\"%s\", \"%v\", false)", strings.TrimSpace(header[:index]), strings.TrimSpace(header[index+1:]))
 	return con, nil
 }
+
+// queryPrimitive generates bfe condition primitive for query match
+func queryPrimitive(query string) (string, error) {
+	if len(query) == 0 {
+		return "", nil
+	}
+	index := strings.Index(query, ":")
+	if index <= 0 || index == len(query)-1 || strings.ContainsAny(query, "\"\\") {
+		return "", fmt.Errorf("query annotation[%s] is illegal", query)
+	}
+
+	con := fmt.Sprintf("req_query_value_in(\"%s\", \"%v\", false)", strings.TrimSpace(query[:index]), strings.TrimSpace(query[index+1:]))
+	return con, nil
+}
+
+// methodPrimitive generates bfe condition primitive for method match, methods are delimited by ','
+func methodPrimitive(method string) (string, error) {
+	if len(method) == 0 {
+		return "", nil
+	}
+
+	var methods []string
+	for _, m := range splitList(strings.ToUpper(method)) {
+		if !isValidMethod(m) {
+			return "", fmt.Errorf("method annotation[%s] is illegal", method)
+		}
+		if !contains(methods, m) {
+			methods = append(methods, m)
+		}
+	}
+	if len(methods) == 0 {
+		return "", fmt.Errorf("method annotation[%s] is illegal", method)
+	}
+	sort.Strings(methods)
+
+	return fmt.Sprintf("req_method_in(\"%s\")", strings.Join(methods, "|")), nil
+}
+
+// cidrPrimitive generates bfe condition primitive for client IP match, IPs or CIDRs are delimited by ','
+func cidrPrimitive(cidr string) (string, error) {
+	if len(cidr) == 0 {
+		return "", nil
+	}
+
+	ranges, err := parseIPRangeList(fmt.Sprintf("cidr annotation[%s]", cidr), cidr)
+	if err != nil {
+		return "", err
+	}
+	var primitives []string
+	for _, ipRange := range ranges {
+		primitive := fmt.Sprintf("req_cip_range(\"%s\", \"%s\")", ipRange.Start, ipRange.End)
+		if !contains(primitives, primitive) {
+			primitives = append(primitives, primitive)
+		}
+	}
+	if len(primitives) == 0 {
+		return "", fmt.Errorf("cidr annotation[%s] is illegal", cidr)
+	}
+	sort.Strings(primitives)
+
+	if len(primitives) == 1 {
+		return primitives[0], nil
+	}
+	return "(" + strings.Join(primitives, "||") + ")", nil
+}
+
+// protocolPrimitive generates bfe condition primitive for protocol match, http or https
+func protocolPrimitive(protocol string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "":
+		return "", nil
+	case "https":
+		return "req_proto_secure()", nil
+	case "http":
+		return "!req_proto_secure()", nil
+	default:
+		return "", fmt.Errorf("protocol annotation[%s] is illegal, should be http or https", protocol)
+	}
+}
+
+// conditionExpression checks the raw bfe condition expression by bfe condition parser,
+// the expression is enclosed in parentheses so that it can be joined with other primitives
+func conditionExpression(expression string) (string, error) {
+	expression = strings.TrimSpace(expression)
+	if len(expression) == 0 {
+		return "", nil
+	}
+	if _, err := condition.Build(expression); err != nil {
+		return "", fmt.Errorf("condition annotation[%s] is illegal, %s", expression, err)
+	}
+
+	return "(" + expression + ")", nil
+}
+
+// isValidMethod returns true if method is a token of upper case letters, e.g. GET
+func isValidMethod(method string) bool {
+	for _, c := range method {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return len(method) > 0
+}
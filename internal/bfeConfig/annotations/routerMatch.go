@@ -0,0 +1,197 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// match types of items in the structured form of router.header and router.cookie
+const (
+	RouterMatchExact  = "exact"
+	RouterMatchPrefix = "prefix"
+	RouterMatchSuffix = "suffix"
+	RouterMatchRegex  = "regex"
+	RouterMatchExist  = "exist"
+	RouterMatchAbsent = "absent"
+)
+
+// RouterMatch is an item in the structured form of router.header and router.cookie, e.g.
+// [{"name": "X-Env", "values": ["canary", "beta"], "match": "exact", "ignoreCase": true}]
+// All items in the list should be matched.
+type RouterMatch struct {
+	Name string `json:"name"`
+	// Values are the accepted values, one of them should be matched; for regex match, only one pattern is allowed
+	Values []string `json:"values,omitempty"`
+	// Match is the match type, RouterMatchExact is used if not set
+	Match      string `json:"match,omitempty"`
+	IgnoreCase bool   `json:"ignoreCase,omitempty"`
+}
+
+// routerMatchKind defines the bfe condition primitives of header or cookie
+type routerMatchKind struct {
+	name   string
+	key    string
+	exact  string
+	prefix string
+	suffix string
+	// regex is empty if not supported
+	regex string
+}
+
+var (
+	headerMatchKind = routerMatchKind{
+		name:   "header",
+		key:    "req_header_key_in",
+		exact:  "req_header_value_in",
+		prefix: "req_header_value_prefix_in",
+		suffix: "req_header_value_suffix_in",
+		regex:  "req_header_value_regmatch",
+	}
+	cookieMatchKind = routerMatchKind{
+		name:   "cookie",
+		key:    "req_cookie_key_in",
+		exact:  "req_cookie_value_in",
+		prefix: "req_cookie_value_prefix_in",
+		suffix: "req_cookie_value_suffix_in",
+	}
+)
+
+// isRouterMatchList returns true if value of router.header or router.cookie is in the structured form
+func isRouterMatchList(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), "[")
+}
+
+// parseRouterMatchList parses the structured form of router.header or router.cookie
+func parseRouterMatchList(value string) ([]RouterMatch, error) {
+	var matches []RouterMatch
+	if err := json.Unmarshal([]byte(value), &matches); err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("at least one condition is required")
+	}
+	return matches, nil
+}
+
+// routerMatchPrimitive generates bfe condition primitives for the structured form of router.header or router.cookie,
+// primitives of all items are joined with "&&"
+func routerMatchPrimitive(kind routerMatchKind, value string) (string, error) {
+	matches, err := parseRouterMatchList(value)
+	if err != nil {
+		return "", fmt.Errorf("%s annotation[%s] is illegal, %s", kind.name, value, err)
+	}
+
+	var primitives []string
+	for _, match := range matches {
+		primitive, err := match.primitive(kind)
+		if err != nil {
+			return "", fmt.Errorf("%s annotation[%s] is illegal, %s", kind.name, value, err)
+		}
+		if !contains(primitives, primitive) {
+			primitives = append(primitives, primitive)
+		}
+	}
+	// sort to make equivalent annotations generate the same expression
+	sort.Strings(primitives)
+
+	return strings.Join(primitives, "&&"), nil
+}
+
+func (m RouterMatch) primitive(kind routerMatchKind) (string, error) {
+	name := strings.TrimSpace(m.Name)
+	if len(name) == 0 || !isValidConditionValue(name) {
+		return "", fmt.Errorf("name [%s] is invalid", m.Name)
+	}
+
+	match := strings.ToLower(strings.TrimSpace(m.Match))
+	switch match {
+	case RouterMatchExist, RouterMatchAbsent:
+		if len(m.Values) > 0 {
+			return "", fmt.Errorf("values of [%s] should be empty for %s match", name, match)
+		}
+		primitive := fmt.Sprintf("%s(\"%s\")", kind.key, name)
+		if match == RouterMatchAbsent {
+			primitive = "!" + primitive
+		}
+		return primitive, nil
+
+	case RouterMatchRegex:
+		if len(kind.regex) == 0 {
+			return "", fmt.Errorf("regex match is not supported by %s", kind.name)
+		}
+		if len(m.Values) != 1 {
+			return "", fmt.Errorf("exactly one pattern is required for regex match of [%s]", name)
+		}
+		pattern := m.Values[0]
+		if m.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		if _, err := regexp.Compile(pattern); err != nil || strings.ContainsAny(pattern, "`\n") {
+			return "", fmt.Errorf("pattern [%s] of [%s] is invalid", m.Values[0], name)
+		}
+		// use raw string, so that the pattern is not escaped
+		return fmt.Sprintf("%s(\"%s\", `%s`)", kind.regex, name, pattern), nil
+
+	case "", RouterMatchExact, RouterMatchPrefix, RouterMatchSuffix:
+		if len(m.Values) == 0 {
+			return "", fmt.Errorf("values of [%s] are required", name)
+		}
+		var values []string
+		for _, value := range m.Values {
+			value = strings.TrimSpace(value)
+			if len(value) == 0 || !isValidConditionValue(value) {
+				return "", fmt.Errorf("value [%s] of [%s] is invalid", value, name)
+			}
+			if !contains(values, value) {
+				values = append(values, value)
+			}
+		}
+		sort.Strings(values)
+
+		function := kind.exact
+		if match == RouterMatchPrefix {
+			function = kind.prefix
+		} else if match == RouterMatchSuffix {
+			function = kind.suffix
+		}
+		return fmt.Sprintf("%s(\"%s\", \"%s\", %t)", function, name, strings.Join(values, "|"), m.IgnoreCase), nil
+
+	default:
+		return "", fmt.Errorf("match type [%s] of [%s] is invalid", m.Match, name)
+	}
+}
+
+// isValidConditionValue returns true if value can be used in a string of bfe condition,
+// '|' is not allowed since it's the delimiter of values
+func isValidConditionValue(value string) bool {
+	return !strings.ContainsAny(value, "|\"\\") && isValidHeaderValue(value)
+}
+
+// routerMatchCount returns the number of conditions in router.header or router.cookie
+func routerMatchCount(value string) int {
+	if !isRouterMatchList(value) {
+		return 1
+	}
+	matches, err := parseRouterMatchList(value)
+	if err != nil {
+		return 1
+	}
+	return len(matches)
+}
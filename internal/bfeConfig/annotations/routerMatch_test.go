@@ -0,0 +1,144 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+)
+
+func TestRouterMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "multi headers",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "X-Env", "values": ["canary", "beta"], "ignoreCase": true}, {"name": "X-Debug", "match": "exist"}]`,
+			},
+			want: `req_header_key_in("X-Debug")&&req_header_value_in("X-Env", "beta|canary", true)`,
+		},
+		{
+			name: "header prefix, suffix and absent",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "User-Agent", "values": ["Mozilla"], "match": "prefix"}, {"name": "Host", "values": [".com"], "match": "suffix"}, {"name": "X-Test", "match": "absent"}]`,
+			},
+			want: `!req_header_key_in("X-Test")&&req_header_value_prefix_in("User-Agent", "Mozilla", false)&&req_header_value_suffix_in("Host", ".com", false)`,
+		},
+		{
+			name: "header regex",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "X-Version", "values": ["^v\\d+$"], "match": "regex", "ignoreCase": true}]`,
+			},
+			want: "req_header_value_regmatch(\"X-Version\", `(?i)^v\\d+$`)",
+		},
+		{
+			name: "header and cookie",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "X-Env", "values": ["canary"]}]`,
+				CookieAnnotation: `[{"name": "uid", "match": "exist"}, {"name": "group", "values": ["a", "b"]}]`,
+			},
+			want: `req_cookie_key_in("uid")&&req_cookie_value_in("group", "a|b", false)&&req_header_value_in("X-Env", "canary", false)`,
+		},
+		{
+			name: "cookie regex not supported",
+			fields: map[string]string{
+				CookieAnnotation: `[{"name": "uid", "values": ["^1"], "match": "regex"}]`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid regex",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "X-Version", "values": ["("], "match": "regex"}]`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "value with delimiter",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "X-Env", "values": ["a|b"]}]`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "values of exist",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "X-Env", "values": ["a"], "match": "exist"}]`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown match",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "X-Env", "values": ["a"], "match": "contain"}]`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty list",
+			fields: map[string]string{
+				HeaderAnnotation: `[]`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid json",
+			fields: map[string]string{
+				HeaderAnnotation: `[{"name": "X-Env"`,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetRouteExpression(tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetRouteExpression() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetRouteExpression() got %v, want %v", got, tt.want)
+			}
+			if err == nil {
+				if _, err := condition.Build(got); err != nil {
+					t.Errorf("condition.Build(%s) error = %v", got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterMatchPriority(t *testing.T) {
+	one := Priority(map[string]string{HeaderAnnotation: `[{"name": "X-Env", "values": ["canary"]}]`})
+	two := Priority(map[string]string{HeaderAnnotation: `[{"name": "X-Env", "values": ["canary"]}, {"name": "X-Debug", "match": "exist"}]`})
+	cookie := Priority(map[string]string{CookieAnnotation: "uid: 1"})
+
+	if one != Priority(map[string]string{HeaderAnnotation: "X-Env: canary"}) {
+		t.Errorf("Priority() of one condition in structured form should be the same as legacy form")
+	}
+	if two <= cookie || cookie <= one {
+		t.Errorf("Priority() not in order, two conditions %d, cookie %d, one header %d", two, cookie, one)
+	}
+
+	if !Equal(map[string]string{HeaderAnnotation: `[{"name": "a", "values": ["1", "2"]}, {"name": "b", "match": "exist"}]`},
+		map[string]string{HeaderAnnotation: `[{"name": "b", "match": "exist"}, {"name": "a", "values": ["2", "1"]}]`}) {
+		t.Errorf("Equal() of equivalent structured conditions should be true")
+	}
+}
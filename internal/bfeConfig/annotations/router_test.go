@@ -184,3 +184,198 @@ func Test_headerAnnotation_Check(t *testing.T) {
 		})
 	}
 }
+
+func Test_advancedAnnotation_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "query build",
+			fields: map[string]string{
+				QueryAnnotation: "version : v2",
+			},
+			want: "req_query_value_in(\"version\", \"v2\", false)",
+		},
+		{
+			name: "query without value",
+			fields: map[string]string{
+				QueryAnnotation: "version",
+			},
+			wantErr: true,
+		},
+		{
+			name: "method build",
+			fields: map[string]string{
+				MethodAnnotation: "post, get,POST",
+			},
+			want: "req_method_in(\"GET|POST\")",
+		},
+		{
+			name: "method illegal",
+			fields: map[string]string{
+				MethodAnnotation: "GET|POST",
+			},
+			wantErr: true,
+		},
+		{
+			name: "cidr build",
+			fields: map[string]string{
+				CIDRAnnotation: "10.0.0.0/8",
+			},
+			want: "req_cip_range(\"10.0.0.0\", \"10.255.255.255\")",
+		},
+		{
+			name: "cidr build multiple",
+			fields: map[string]string{
+				CIDRAnnotation: "192.168.1.1, 10.0.0.0/8",
+			},
+			want: "(req_cip_range(\"10.0.0.0\", \"10.255.255.255\")||req_cip_range(\"192.168.1.1\", \"192.168.1.1\"))",
+		},
+		{
+			name: "cidr illegal",
+			fields: map[string]string{
+				CIDRAnnotation: "10.0.0.0/33",
+			},
+			wantErr: true,
+		},
+		{
+			name: "protocol build",
+			fields: map[string]string{
+				ProtocolAnnotation: "HTTPS",
+			},
+			want: "req_proto_secure()",
+		},
+		{
+			name: "protocol illegal",
+			fields: map[string]string{
+				ProtocolAnnotation: "h2",
+			},
+			wantErr: true,
+		},
+		{
+			name: "all build",
+			fields: map[string]string{
+				CookieAnnotation:   "c: 1",
+				HeaderAnnotation:   "h: 2",
+				QueryAnnotation:    "q: 3",
+				MethodAnnotation:   "GET",
+				CIDRAnnotation:     "10.0.0.1",
+				ProtocolAnnotation: "http",
+			},
+			want: "req_cookie_value_in(\"c\", \"1\", false)&&req_header_value_in(\"h\", \"2\", false)&&" +
+				"req_query_value_in(\"q\", \"3\", false)&&req_method_in(\"GET\")&&" +
+				"req_cip_range(\"10.0.0.1\", \"10.0.0.1\")&&!req_proto_secure()",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetRouteExpression(tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetRouteExpression() [%s] error = %v, wantErr %v", tt.name, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetRouteExpression() [%s] fail, got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Priority(t *testing.T) {
+	basic := Priority(map[string]string{})
+	cookie := Priority(map[string]string{CookieAnnotation: "c: 1"})
+	header := Priority(map[string]string{HeaderAnnotation: "h: 1"})
+	query := Priority(map[string]string{QueryAnnotation: "q: 1"})
+	method := Priority(map[string]string{MethodAnnotation: "GET"})
+	cidr := Priority(map[string]string{CIDRAnnotation: "10.0.0.1"})
+	protocol := Priority(map[string]string{ProtocolAnnotation: "https"})
+	methodProtocol := Priority(map[string]string{MethodAnnotation: "GET", ProtocolAnnotation: "https"})
+
+	if basic != PriorityBasic {
+		t.Errorf("Priority() of basic rule is %d, want %d", basic, PriorityBasic)
+	}
+	ordered := []int{methodProtocol, cookie, header, query, method, cidr, protocol, basic}
+	for i := 1; i < len(ordered); i++ {
+		if ordered[i-1] <= ordered[i] {
+			t.Errorf("Priority() not in order, %v", ordered)
+		}
+	}
+}
+
+func Test_Equal(t *testing.T) {
+	if !Equal(map[string]string{MethodAnnotation: "GET,POST"}, map[string]string{MethodAnnotation: "post, get"}) {
+		t.Errorf("Equal() of same methods should be true")
+	}
+	if Equal(map[string]string{MethodAnnotation: "GET"}, map[string]string{MethodAnnotation: "GET", ProtocolAnnotation: "https"}) {
+		t.Errorf("Equal() of different conditions should be false")
+	}
+	if Equal(map[string]string{QueryAnnotation: "a: 1"}, map[string]string{HeaderAnnotation: "a: 1"}) {
+		t.Errorf("Equal() of different condition types should be false")
+	}
+}
+
+func Test_conditionAnnotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "condition build",
+			fields: map[string]string{
+				ConditionAnnotation: ` req_query_key_in("debug") || req_cip_range("10.0.0.0", "10.255.255.255") `,
+			},
+			want: `(req_query_key_in("debug") || req_cip_range("10.0.0.0", "10.255.255.255"))`,
+		},
+		{
+			name: "condition with header",
+			fields: map[string]string{
+				HeaderAnnotation:    "X-Env: canary",
+				ConditionAnnotation: `!req_method_in("DELETE")`,
+			},
+			want: `req_header_value_in("X-Env", "canary", false)&&(!req_method_in("DELETE"))`,
+		},
+		{
+			name: "unknown primitive",
+			fields: map[string]string{
+				ConditionAnnotation: `req_unknown_in("a")`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "syntax error",
+			fields: map[string]string{
+				ConditionAnnotation: `req_method_in("GET") &&`,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetRouteExpression(tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetRouteExpression() [%s] error = %v, wantErr %v", tt.name, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetRouteExpression() [%s] fail, got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRoutePriority(t *testing.T) {
+	if priority, err := GetRoutePriority(map[string]string{PriorityAnnotation: " 10 "}); err != nil || priority != 10 {
+		t.Errorf("GetRoutePriority() got %d, error %v", priority, err)
+	}
+	if priority, err := GetRoutePriority(map[string]string{}); err != nil || priority != 0 {
+		t.Errorf("GetRoutePriority() of unset got %d, error %v", priority, err)
+	}
+	if _, err := GetRoutePriority(map[string]string{PriorityAnnotation: "high"}); err == nil {
+		t.Errorf("GetRoutePriority() of illegal value should fail")
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the keys of security headers, which are used both in annotations (with BfeAnnotationPrefix)
+// and in the global ConfigMap (without prefix)
+const (
+	SecurityHeadersHSTSKey                  = "security-headers.hsts"
+	SecurityHeadersHSTSMaxAgeKey            = "security-headers.hsts-max-age"
+	SecurityHeadersHSTSIncludeSubDomainsKey = "security-headers.hsts-include-subdomains"
+	SecurityHeadersHSTSPreloadKey           = "security-headers.hsts-preload"
+	SecurityHeadersFrameOptionsKey          = "security-headers.frame-options"
+	SecurityHeadersContentTypeOptionsKey    = "security-headers.content-type-options"
+	SecurityHeadersCSPKey                   = "security-headers.content-security-policy"
+	SecurityHeadersReferrerPolicyKey        = "security-headers.referrer-policy"
+)
+
+const (
+	HSTSHeader                  = "Strict-Transport-Security"
+	FrameOptionsHeader          = "X-Frame-Options"
+	ContentTypeOptionsHeader    = "X-Content-Type-Options"
+	ContentSecurityPolicyHeader = "Content-Security-Policy"
+	ReferrerPolicyHeader        = "Referrer-Policy"
+
+	// DefaultHSTSMaxAge is one year, which is also the minimum max-age required by HSTS preload lists
+	DefaultHSTSMaxAge = 31536000
+)
+
+var referrerPolicies = []string{
+	"no-referrer",
+	"no-referrer-when-downgrade",
+	"origin",
+	"origin-when-cross-origin",
+	"same-origin",
+	"strict-origin",
+	"strict-origin-when-cross-origin",
+	"unsafe-url",
+}
+
+// SecurityHeaders defines the security response headers of an Ingress.
+// A nil field means it's not set, so the value of the global default is used.
+// An empty string means the header is disabled.
+type SecurityHeaders struct {
+	HSTS                  *bool
+	HSTSMaxAge            *int64
+	HSTSIncludeSubDomains *bool
+	HSTSPreload           *bool
+	FrameOptions          *string
+	ContentTypeOptions    *string
+	ContentSecurityPolicy *string
+	ReferrerPolicy        *string
+}
+
+// GetSecurityHeaders parse annotations "security-headers.*"
+func GetSecurityHeaders(annotations map[string]string) (*SecurityHeaders, error) {
+	return parseSecurityHeaders(annotations, BfeAnnotationPrefix)
+}
+
+// ParseSecurityHeaders parse keys "security-headers.*" in the global ConfigMap
+func ParseSecurityHeaders(data map[string]string) (*SecurityHeaders, error) {
+	return parseSecurityHeaders(data, "")
+}
+
+func parseSecurityHeaders(values map[string]string, prefix string) (*SecurityHeaders, error) {
+	var err error
+	headers := &SecurityHeaders{}
+
+	if headers.HSTS, err = parseOptionalBool(values, prefix+SecurityHeadersHSTSKey); err != nil {
+		return nil, err
+	}
+	if headers.HSTSIncludeSubDomains, err = parseOptionalBool(values, prefix+SecurityHeadersHSTSIncludeSubDomainsKey); err != nil {
+		return nil, err
+	}
+	if headers.HSTSPreload, err = parseOptionalBool(values, prefix+SecurityHeadersHSTSPreloadKey); err != nil {
+		return nil, err
+	}
+
+	if value, ok := values[prefix+SecurityHeadersHSTSMaxAgeKey]; ok {
+		maxAge, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || maxAge < 0 {
+			return nil, fmt.Errorf("annotation %s is illegal, should be a non-negative integer", prefix+SecurityHeadersHSTSMaxAgeKey)
+		}
+		headers.HSTSMaxAge = &maxAge
+	}
+
+	if value, ok := values[prefix+SecurityHeadersFrameOptionsKey]; ok {
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if value != "" && value != "DENY" && value != "SAMEORIGIN" {
+			return nil, fmt.Errorf("annotation %s is illegal, should be DENY or SAMEORIGIN", prefix+SecurityHeadersFrameOptionsKey)
+		}
+		headers.FrameOptions = &value
+	}
+
+	if value, ok := values[prefix+SecurityHeadersContentTypeOptionsKey]; ok {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" && value != "nosniff" {
+			return nil, fmt.Errorf("annotation %s is illegal, should be nosniff", prefix+SecurityHeadersContentTypeOptionsKey)
+		}
+		headers.ContentTypeOptions = &value
+	}
+
+	if value, ok := values[prefix+SecurityHeadersCSPKey]; ok {
+		value = strings.TrimSpace(value)
+		if !isValidHeaderValue(value) {
+			return nil, fmt.Errorf("annotation %s is illegal, control characters are not allowed", prefix+SecurityHeadersCSPKey)
+		}
+		headers.ContentSecurityPolicy = &value
+	}
+
+	if value, ok := values[prefix+SecurityHeadersReferrerPolicyKey]; ok {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" && !contains(referrerPolicies, value) {
+			return nil, fmt.Errorf("annotation %s is illegal, should be one of %s", prefix+SecurityHeadersReferrerPolicyKey, strings.Join(referrerPolicies, ","))
+		}
+		headers.ReferrerPolicy = &value
+	}
+
+	return headers, nil
+}
+
+// Merge returns the security headers whose unset fields are filled with defaults
+func (h *SecurityHeaders) Merge(defaults *SecurityHeaders) *SecurityHeaders {
+	merged := *h
+	if defaults == nil {
+		return &merged
+	}
+
+	if merged.HSTS == nil {
+		merged.HSTS = defaults.HSTS
+	}
+	if merged.HSTSMaxAge == nil {
+		merged.HSTSMaxAge = defaults.HSTSMaxAge
+	}
+	if merged.HSTSIncludeSubDomains == nil {
+		merged.HSTSIncludeSubDomains = defaults.HSTSIncludeSubDomains
+	}
+	if merged.HSTSPreload == nil {
+		merged.HSTSPreload = defaults.HSTSPreload
+	}
+	if merged.FrameOptions == nil {
+		merged.FrameOptions = defaults.FrameOptions
+	}
+	if merged.ContentTypeOptions == nil {
+		merged.ContentTypeOptions = defaults.ContentTypeOptions
+	}
+	if merged.ContentSecurityPolicy == nil {
+		merged.ContentSecurityPolicy = defaults.ContentSecurityPolicy
+	}
+	if merged.ReferrerPolicy == nil {
+		merged.ReferrerPolicy = defaults.ReferrerPolicy
+	}
+	return &merged
+}
+
+// Check checks the HSTS policy, which can only be checked after merged with defaults
+func (h *SecurityHeaders) Check() error {
+	if !boolValue(h.HSTSPreload) {
+		return nil
+	}
+
+	if !boolValue(h.HSTSIncludeSubDomains) {
+		return fmt.Errorf("%s requires %s to be true", SecurityHeadersHSTSPreloadKey, SecurityHeadersHSTSIncludeSubDomainsKey)
+	}
+	if h.HSTSMaxAge != nil && *h.HSTSMaxAge < DefaultHSTSMaxAge {
+		return fmt.Errorf("%s requires %s to be at least %d", SecurityHeadersHSTSPreloadKey, SecurityHeadersHSTSMaxAgeKey, DefaultHSTSMaxAge)
+	}
+	return nil
+}
+
+// HSTSEnabled returns true if header Strict-Transport-Security should be sent
+func (h *SecurityHeaders) HSTSEnabled() bool {
+	return boolValue(h.HSTS)
+}
+
+// HSTSValue returns the value of header Strict-Transport-Security
+func (h *SecurityHeaders) HSTSValue() string {
+	maxAge := int64(DefaultHSTSMaxAge)
+	if h.HSTSMaxAge != nil {
+		maxAge = *h.HSTSMaxAge
+	}
+
+	value := fmt.Sprintf("max-age=%d", maxAge)
+	if boolValue(h.HSTSIncludeSubDomains) {
+		value += "; includeSubDomains"
+	}
+	if boolValue(h.HSTSPreload) {
+		value += "; preload"
+	}
+	return value
+}
+
+// ResponseHeaders returns the security headers except Strict-Transport-Security, in the format of [name, value]
+func (h *SecurityHeaders) ResponseHeaders() [][2]string {
+	var headers [][2]string
+	for _, header := range []struct {
+		name  string
+		value *string
+	}{
+		{FrameOptionsHeader, h.FrameOptions},
+		{ContentTypeOptionsHeader, h.ContentTypeOptions},
+		{ContentSecurityPolicyHeader, h.ContentSecurityPolicy},
+		{ReferrerPolicyHeader, h.ReferrerPolicy},
+	} {
+		if header.value != nil && len(*header.value) > 0 {
+			headers = append(headers, [2]string{header.name, *header.value})
+		}
+	}
+	return headers
+}
+
+// parseOptionalBool parse a bool value, nil is returned if the key is not set
+func parseOptionalBool(values map[string]string, key string) (*bool, error) {
+	if _, ok := values[key]; !ok {
+		return nil, nil
+	}
+
+	b, err := getBool(values, key, false)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+// isValidHeaderValue returns false if value contains control characters, e.g. CR and LF
+func isValidHeaderValue(value string) bool {
+	for _, c := range value {
+		if (c < ' ' && c != '\t') || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
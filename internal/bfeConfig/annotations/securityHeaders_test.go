@@ -0,0 +1,176 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		annots   map[string]string
+		defaults map[string]string
+		wantHSTS string
+		wantRsp  [][2]string
+		wantErr  bool
+	}{
+		{
+			name:     "not set",
+			annots:   map[string]string{},
+			wantHSTS: "",
+			wantRsp:  nil,
+			wantErr:  false,
+		},
+		{
+			name: "hsts",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersHSTSKey:                  "true",
+				BfeAnnotationPrefix + SecurityHeadersHSTSMaxAgeKey:            "63072000",
+				BfeAnnotationPrefix + SecurityHeadersHSTSIncludeSubDomainsKey: "true",
+				BfeAnnotationPrefix + SecurityHeadersHSTSPreloadKey:           "true",
+			},
+			wantHSTS: "max-age=63072000; includeSubDomains; preload",
+			wantRsp:  nil,
+			wantErr:  false,
+		},
+		{
+			name: "hsts from defaults",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersHSTSIncludeSubDomainsKey: "false",
+			},
+			defaults: map[string]string{
+				SecurityHeadersHSTSKey:                  "true",
+				SecurityHeadersHSTSIncludeSubDomainsKey: "true",
+			},
+			wantHSTS: "max-age=31536000",
+			wantRsp:  nil,
+			wantErr:  false,
+		},
+		{
+			name: "response headers",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersFrameOptionsKey:       "sameorigin",
+				BfeAnnotationPrefix + SecurityHeadersContentTypeOptionsKey: "nosniff",
+				BfeAnnotationPrefix + SecurityHeadersCSPKey:                "default-src 'self'",
+			},
+			defaults: map[string]string{
+				SecurityHeadersCSPKey:            "default-src *",
+				SecurityHeadersReferrerPolicyKey: "no-referrer",
+			},
+			wantHSTS: "",
+			wantRsp: [][2]string{
+				{FrameOptionsHeader, "SAMEORIGIN"},
+				{ContentTypeOptionsHeader, "nosniff"},
+				{ContentSecurityPolicyHeader, "default-src 'self'"},
+				{ReferrerPolicyHeader, "no-referrer"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "disable default header",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersFrameOptionsKey: "",
+			},
+			defaults: map[string]string{
+				SecurityHeadersFrameOptionsKey: "DENY",
+			},
+			wantHSTS: "",
+			wantRsp:  nil,
+			wantErr:  false,
+		},
+		{
+			name: "illegal max-age",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersHSTSMaxAgeKey: "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "illegal frame options",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersFrameOptionsKey: "ALLOW-FROM https://example.com",
+			},
+			wantErr: true,
+		},
+		{
+			name: "illegal referrer policy",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersReferrerPolicyKey: "always",
+			},
+			wantErr: true,
+		},
+		{
+			name: "illegal csp",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersCSPKey: "default-src 'self'\r\nSet-Cookie: a=b",
+			},
+			wantErr: true,
+		},
+		{
+			name: "preload without includeSubDomains",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersHSTSKey:        "true",
+				BfeAnnotationPrefix + SecurityHeadersHSTSPreloadKey: "true",
+			},
+			wantErr: true,
+		},
+		{
+			name: "preload with short max-age",
+			annots: map[string]string{
+				BfeAnnotationPrefix + SecurityHeadersHSTSKey:                  "true",
+				BfeAnnotationPrefix + SecurityHeadersHSTSPreloadKey:           "true",
+				BfeAnnotationPrefix + SecurityHeadersHSTSIncludeSubDomainsKey: "true",
+			},
+			defaults: map[string]string{
+				SecurityHeadersHSTSMaxAgeKey: "300",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaults, err := ParseSecurityHeaders(tt.defaults)
+			if err != nil {
+				t.Fatalf("ParseSecurityHeaders() error = %v", err)
+			}
+
+			got, err := GetSecurityHeaders(tt.annots)
+			if err == nil {
+				got = got.Merge(defaults)
+				err = got.Check()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetSecurityHeaders() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			hsts := ""
+			if got.HSTSEnabled() {
+				hsts = got.HSTSValue()
+			}
+			if hsts != tt.wantHSTS {
+				t.Errorf("GetSecurityHeaders() hsts = %v, want %v", hsts, tt.wantHSTS)
+			}
+			if rsp := got.ResponseHeaders(); !reflect.DeepEqual(rsp, tt.wantRsp) {
+				t.Errorf("GetSecurityHeaders() response headers = %v, want %v", rsp, tt.wantRsp)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// StaticConfigMapAnnotation is the ConfigMap in the namespace of the ingress, whose keys are names of static files
+	StaticConfigMapAnnotation = BfeAnnotationPrefix + "static.configmap"
+	// StaticRootAnnotation is the directory of static files in the BFE container, e.g. a mounted volume
+	StaticRootAnnotation = BfeAnnotationPrefix + "static.root"
+	// StaticDefaultFileAnnotation is the file returned if the requested file doesn't exist, e.g. index.html
+	StaticDefaultFileAnnotation = BfeAnnotationPrefix + "static.default-file"
+	// StaticExpiresAnnotation is the duration that static files are cached by clients, e.g. 1h
+	StaticExpiresAnnotation = BfeAnnotationPrefix + "static.expires"
+)
+
+// StaticMimeTypesKey is the key of the global ConfigMap, which maps file extensions to content types,
+// e.g. {".wasm": "application/wasm"}
+const StaticMimeTypesKey = "static.mime-types"
+
+// Static defines the static files served by BFE instead of the backend of an Ingress, only one of ConfigMap and Root is set.
+// Refer to https://www.bfe-networks.net/en_us/modules/mod_static/mod_static/.
+type Static struct {
+	ConfigMap   string
+	Root        string
+	DefaultFile string
+	// Expires is nil if not set, so header Cache-Control is not sent
+	Expires *time.Duration
+}
+
+// GetStaticConfigMap returns name of the ConfigMap referenced by annotation "static.configmap"
+func GetStaticConfigMap(annotations map[string]string) (string, bool, error) {
+	name, ok := annotations[StaticConfigMapAnnotation]
+	if ok && len(name) == 0 {
+		return "", false, fmt.Errorf("annotation %s is illegal, configmap name is required", StaticConfigMapAnnotation)
+	}
+	return name, ok, nil
+}
+
+// GetStatic parse annotations "static.*", nil is returned if no static file is served
+func GetStatic(annotations map[string]string) (*Static, error) {
+	configMap, configMapOk, err := GetStaticConfigMap(annotations)
+	if err != nil {
+		return nil, err
+	}
+	root, rootOk := annotations[StaticRootAnnotation]
+	if configMapOk && rootOk {
+		return nil, fmt.Errorf("annotation %s and %s can't be set at the same time", StaticConfigMapAnnotation, StaticRootAnnotation)
+	}
+	if !configMapOk && !rootOk {
+		for _, key := range []string{StaticDefaultFileAnnotation, StaticExpiresAnnotation} {
+			if _, ok := annotations[key]; ok {
+				return nil, fmt.Errorf("annotation %s requires annotation %s or %s", key, StaticConfigMapAnnotation, StaticRootAnnotation)
+			}
+		}
+		return nil, nil
+	}
+
+	static := &Static{ConfigMap: configMap}
+	if rootOk {
+		if !filepath.IsAbs(root) || filepath.Clean(root) != root {
+			return nil, fmt.Errorf("annotation %s is illegal, should be a clean absolute path", StaticRootAnnotation)
+		}
+		static.Root = root
+	}
+
+	if value, ok := annotations[StaticDefaultFileAnnotation]; ok {
+		if !isValidStaticFile(value, rootOk) {
+			return nil, fmt.Errorf("annotation %s is illegal, file [%s] is invalid", StaticDefaultFileAnnotation, value)
+		}
+		static.DefaultFile = value
+	}
+
+	if value, ok := annotations[StaticExpiresAnnotation]; ok {
+		expires, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || expires < 0 {
+			return nil, fmt.Errorf("annotation %s is illegal, should be a non-negative duration, e.g. 1h", StaticExpiresAnnotation)
+		}
+		static.Expires = &expires
+	}
+
+	return static, nil
+}
+
+// CacheControl returns the value of header Cache-Control, empty string is returned if Expires is not set
+func (s *Static) CacheControl() string {
+	if s.Expires == nil {
+		return ""
+	}
+	return fmt.Sprintf("max-age=%d", int64(s.Expires.Seconds()))
+}
+
+// ParseStaticMimeTypes parse key "static.mime-types" in the global ConfigMap
+func ParseStaticMimeTypes(data map[string]string) (map[string]string, error) {
+	mimeTypes := make(map[string]string)
+	value, ok := data[StaticMimeTypesKey]
+	if !ok {
+		return mimeTypes, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &mimeTypes); err != nil {
+		return nil, fmt.Errorf("%s is illegal, should be a JSON object of file extensions and content types", StaticMimeTypesKey)
+	}
+	for ext, contentType := range mimeTypes {
+		if !strings.HasPrefix(ext, ".") || len(ext) == 1 || strings.ContainsAny(ext, "/\\") {
+			return nil, fmt.Errorf("%s is illegal, file extension [%s] should begin with '.'", StaticMimeTypesKey, ext)
+		}
+		if !strings.Contains(contentType, "/") || !isValidHeaderValue(contentType) {
+			return nil, fmt.Errorf("%s is illegal, content type [%s] is invalid", StaticMimeTypesKey, contentType)
+		}
+	}
+	return mimeTypes, nil
+}
+
+// isValidStaticFile checks the name of a static file, which is a key of the ConfigMap,
+// or a relative path in the root directory if subPath is true
+func isValidStaticFile(name string, subPath bool) bool {
+	if len(name) == 0 || strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return false
+	}
+	if !subPath {
+		return !strings.Contains(name, "/") && name != "." && name != ".."
+	}
+	return path.Clean(name) == name && name != ".." && !strings.HasPrefix(name, "../")
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetStatic(t *testing.T) {
+	hour := time.Hour
+	tests := []struct {
+		name             string
+		annots           map[string]string
+		want             *Static
+		wantCacheControl string
+		wantErr          bool
+	}{
+		{
+			name:   "not set",
+			annots: map[string]string{},
+			want:   nil,
+		},
+		{
+			name: "configmap",
+			annots: map[string]string{
+				StaticConfigMapAnnotation:   "site",
+				StaticDefaultFileAnnotation: "index.html",
+				StaticExpiresAnnotation:     "1h",
+			},
+			want:             &Static{ConfigMap: "site", DefaultFile: "index.html", Expires: &hour},
+			wantCacheControl: "max-age=3600",
+		},
+		{
+			name: "root",
+			annots: map[string]string{
+				StaticRootAnnotation:        "/var/www",
+				StaticDefaultFileAnnotation: "app/index.html",
+			},
+			want: &Static{Root: "/var/www", DefaultFile: "app/index.html"},
+		},
+		{
+			name: "both configmap and root",
+			annots: map[string]string{
+				StaticConfigMapAnnotation: "site",
+				StaticRootAnnotation:      "/var/www",
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty configmap",
+			annots: map[string]string{
+				StaticConfigMapAnnotation: "",
+			},
+			wantErr: true,
+		},
+		{
+			name: "relative root",
+			annots: map[string]string{
+				StaticRootAnnotation: "var/www",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unclean root",
+			annots: map[string]string{
+				StaticRootAnnotation: "/var/www/../etc",
+			},
+			wantErr: true,
+		},
+		{
+			name: "sub path of configmap",
+			annots: map[string]string{
+				StaticConfigMapAnnotation:   "site",
+				StaticDefaultFileAnnotation: "app/index.html",
+			},
+			wantErr: true,
+		},
+		{
+			name: "default file out of root",
+			annots: map[string]string{
+				StaticRootAnnotation:        "/var/www",
+				StaticDefaultFileAnnotation: "../index.html",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid expires",
+			annots: map[string]string{
+				StaticConfigMapAnnotation: "site",
+				StaticExpiresAnnotation:   "-1h",
+			},
+			wantErr: true,
+		},
+		{
+			name: "expires without static",
+			annots: map[string]string{
+				StaticExpiresAnnotation: "1h",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStatic(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetStatic() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetStatic() = %v, want %v", got, tt.want)
+			}
+			if got != nil && got.CacheControl() != tt.wantCacheControl {
+				t.Errorf("CacheControl() = %v, want %v", got.CacheControl(), tt.wantCacheControl)
+			}
+		})
+	}
+}
+
+func TestParseStaticMimeTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "not set",
+			data: map[string]string{},
+			want: map[string]string{},
+		},
+		{
+			name: "mime types",
+			data: map[string]string{
+				StaticMimeTypesKey: `{".wasm": "application/wasm"}`,
+			},
+			want: map[string]string{".wasm": "application/wasm"},
+		},
+		{
+			name: "invalid json",
+			data: map[string]string{
+				StaticMimeTypesKey: `[".wasm"]`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid extension",
+			data: map[string]string{
+				StaticMimeTypesKey: `{"wasm": "application/wasm"}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid content type",
+			data: map[string]string{
+				StaticMimeTypesKey: `{".wasm": "wasm"}`,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStaticMimeTypes(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStaticMimeTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStaticMimeTypes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ package annotations
 
 import (
 	"encoding/json"
+	"reflect"
 )
 
 var (
@@ -23,15 +24,26 @@ var (
 )
 
 type statusMsg struct {
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
+	Status  string        `json:"status"`
+	Message string        `json:"message,omitempty"`
+	Routes  []RouteStatus `json:"routes,omitempty"`
 }
 
-func GenErrorMsg(err error) string {
+// RouteStatus is the order of a route rule among route rules of the same host, rules are matched in the order
+type RouteStatus struct {
+	Host string `json:"host"`
+	// Path ends with "*" for prefix match, and starts with "~" for regex match
+	Path  string `json:"path"`
+	Order int    `json:"order"`
+}
+
+// GenStatusMsg generates the status with the order of route rules, routes are ignored if err is not nil
+func GenStatusMsg(err error, routes []RouteStatus) string {
 	var status = statusMsg{}
 
 	if err == nil {
 		status.Status = "success"
+		status.Routes = routes
 	} else {
 		status.Status = "error"
 		status.Message = err.Error()
@@ -41,8 +53,8 @@ func GenErrorMsg(err error) string {
 	return string(jsons)
 }
 
-// CompareStatus check errMsg with status, return 0 if equal
-func CompareStatus(e error, status string) int {
+// CompareStatusRoutes check errMsg and order of route rules with status, return 0 if equal
+func CompareStatusRoutes(e error, routes []RouteStatus, status string) int {
 	if len(status) == 0 {
 		return 1
 	}
@@ -52,7 +64,7 @@ func CompareStatus(e error, status string) int {
 		return 1
 	}
 
-	if e == nil && s.Status == "success" {
+	if e == nil && s.Status == "success" && (len(routes) == 0 && len(s.Routes) == 0 || reflect.DeepEqual(routes, s.Routes)) {
 		return 0
 	}
 
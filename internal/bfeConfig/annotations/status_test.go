@@ -0,0 +1,48 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGenStatusMsg(t *testing.T) {
+	routes := []RouteStatus{{Host: "example.com", Path: "/foo*", Order: 2}}
+
+	status := GenStatusMsg(nil, routes)
+	want := `{"status":"success","routes":[{"host":"example.com","path":"/foo*","order":2}]}`
+	if status != want {
+		t.Errorf("GenStatusMsg() got %s, want %s", status, want)
+	}
+	if CompareStatusRoutes(nil, routes, status) != 0 {
+		t.Errorf("CompareStatusRoutes() of same routes should be 0")
+	}
+	if CompareStatusRoutes(nil, []RouteStatus{{Host: "example.com", Path: "/foo*", Order: 1}}, status) == 0 {
+		t.Errorf("CompareStatusRoutes() of changed order should not be 0")
+	}
+
+	err := errors.New("conflict")
+	status = GenStatusMsg(err, routes)
+	if status != `{"status":"error","message":"conflict"}` {
+		t.Errorf("GenStatusMsg() of error got %s", status)
+	}
+	if CompareStatusRoutes(err, nil, status) != 0 {
+		t.Errorf("CompareStatusRoutes() of same error should be 0")
+	}
+	if CompareStatusRoutes(nil, nil, `{"status":"success"}`) != 0 {
+		t.Errorf("CompareStatusRoutes() of success without routes should be 0")
+	}
+}
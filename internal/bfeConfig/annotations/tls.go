@@ -0,0 +1,30 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+const (
+	AcmeKey        = "tls.acme"
+	AcmeAnnotation = BfeAnnotationPrefix + AcmeKey
+
+	// AcmeManagedKey is set by the controller on Secrets holding certificates issued through ACME
+	AcmeManagedKey        = "tls.acme-managed"
+	AcmeManagedAnnotation = BfeAnnotationPrefix + AcmeManagedKey
+)
+
+// GetAcme parse annotation "tls.acme"
+// It returns true if certificates of the ingress should be obtained through ACME.
+func GetAcme(annotations map[string]string) (bool, error) {
+	return getBool(annotations, AcmeAnnotation, false)
+}
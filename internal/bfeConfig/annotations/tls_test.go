@@ -0,0 +1,65 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"testing"
+)
+
+func TestGetAcme(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "not set",
+			annots:  map[string]string{},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "enabled",
+			annots:  map[string]string{AcmeAnnotation: "true"},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "disabled",
+			annots:  map[string]string{AcmeAnnotation: "false"},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "illegal value",
+			annots:  map[string]string{AcmeAnnotation: "yes please"},
+			want:    false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetAcme(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAcme() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetAcme() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
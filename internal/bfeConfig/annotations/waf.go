@@ -0,0 +1,79 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bfenetworks/bfe/bfe_modules/mod_waf/waf_rule"
+)
+
+const (
+	// WafAnnotation is the mode of the web application firewall, e.g. block
+	WafAnnotation = BfeAnnotationPrefix + "waf"
+	// WafRulesAnnotation is the list of WAF rules delimited by ',', e.g. RuleBashCmd
+	WafRulesAnnotation = BfeAnnotationPrefix + "waf.rules"
+)
+
+// modes of the web application firewall
+const (
+	// WafModeBlock means requests hit by WAF rules are denied
+	WafModeBlock = "block"
+	// WafModeDetect means requests hit by WAF rules are only logged
+	WafModeDetect = "detect"
+	WafModeOff    = "off"
+)
+
+// DefaultWafRules are the WAF rules used if annotation "waf.rules" is not set, that is all rules implemented by mod_waf
+var DefaultWafRules = []string{waf_rule.RuleBashCmd}
+
+// Waf defines the web application firewall of an Ingress
+type Waf struct {
+	Mode  string
+	Rules []string
+}
+
+// GetWaf parse annotations "waf" and "waf.rules", nil is returned if WAF is not enabled
+func GetWaf(annotations map[string]string) (*Waf, error) {
+	value, ok := annotations[WafAnnotation]
+	mode := strings.ToLower(strings.TrimSpace(value))
+	if ok && mode != WafModeBlock && mode != WafModeDetect && mode != WafModeOff {
+		return nil, fmt.Errorf("annotation %s is illegal, should be one of %s,%s,%s", WafAnnotation, WafModeBlock, WafModeDetect, WafModeOff)
+	}
+
+	rulesValue, rulesOk := annotations[WafRulesAnnotation]
+	if rulesOk && !ok {
+		return nil, fmt.Errorf("annotation %s requires annotation %s", WafRulesAnnotation, WafAnnotation)
+	}
+	if !ok || mode == WafModeOff {
+		return nil, nil
+	}
+
+	rules := DefaultWafRules
+	if rulesOk {
+		rules = splitList(rulesValue)
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("annotation %s is illegal, WAF rule is required", WafRulesAnnotation)
+		}
+		for _, rule := range rules {
+			if !waf_rule.IsValidRule(rule) {
+				return nil, fmt.Errorf("annotation %s is illegal, unknown WAF rule %s", WafRulesAnnotation, rule)
+			}
+		}
+	}
+
+	return &Waf{Mode: mode, Rules: rules}, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetWaf(t *testing.T) {
+	tests := []struct {
+		name    string
+		annots  map[string]string
+		want    *Waf
+		wantErr bool
+	}{
+		{
+			name:   "not set",
+			annots: map[string]string{},
+			want:   nil,
+		},
+		{
+			name: "block",
+			annots: map[string]string{
+				WafAnnotation: "Block",
+			},
+			want: &Waf{Mode: WafModeBlock, Rules: DefaultWafRules},
+		},
+		{
+			name: "detect with rules",
+			annots: map[string]string{
+				WafAnnotation:      "detect",
+				WafRulesAnnotation: "RuleBashCmd, ",
+			},
+			want: &Waf{Mode: WafModeDetect, Rules: []string{"RuleBashCmd"}},
+		},
+		{
+			name: "off",
+			annots: map[string]string{
+				WafAnnotation:      "off",
+				WafRulesAnnotation: "RuleBashCmd",
+			},
+			want: nil,
+		},
+		{
+			name: "invalid mode",
+			annots: map[string]string{
+				WafAnnotation: "deny",
+			},
+			wantErr: true,
+		},
+		{
+			name: "rules without mode",
+			annots: map[string]string{
+				WafRulesAnnotation: "RuleBashCmd",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown rule",
+			annots: map[string]string{
+				WafAnnotation:      "block",
+				WafRulesAnnotation: "RuleSQLInjection",
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty rules",
+			annots: map[string]string{
+				WafAnnotation:      "block",
+				WafRulesAnnotation: "",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetWaf(tt.annots)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetWaf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetWaf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
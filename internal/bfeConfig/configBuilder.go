@@ -16,6 +16,8 @@ package bfeConfig
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,7 +26,9 @@ import (
 	netv1 "k8s.io/api/networking/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
 	"github.com/bfenetworks/ingress-bfe/internal/option"
 )
 
@@ -43,15 +47,20 @@ type ConfigBuilder struct {
 
 func NewConfigBuilder() *ConfigBuilder {
 	version := "init"
+	// products are updated by the server data config, and used by modules to group their rules
+	products := configs.NewProductTable()
 	return &ConfigBuilder{
-		serverDataConf: configs.NewServerDataConfig(version),
+		serverDataConf: configs.NewServerDataConfig(version, products),
 		clusterConf:    configs.NewClusterConfig(version),
 		tlsConf:        configs.NewTLSConfig(version),
-		modules:        modules.InitBFEModules(version),
+		modules:        modules.InitBFEModules(version, products),
 	}
 }
 
-func (c *ConfigBuilder) UpdateIngress(ingress *netv1.Ingress, services map[string]*corev1.Service, endpoints map[string]*corev1.Endpoints, secrets []*corev1.Secret) error {
+// UpdateIngress updates all configs with the ingress, secrets are Secrets used by TLS of the ingress,
+// configMaps and refSecrets are ConfigMaps and Secrets referenced by annotations of the ingress
+func (c *ConfigBuilder) UpdateIngress(ingress *netv1.Ingress, services map[string]*corev1.Service, endpoints map[string]*corev1.Endpoints,
+	secrets []*corev1.Secret, configMaps []*corev1.ConfigMap, refSecrets []*corev1.Secret) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -73,7 +82,18 @@ func (c *ConfigBuilder) UpdateIngress(ingress *netv1.Ingress, services map[strin
 
 	// update modules
 	for i, module := range c.modules {
-		if err := module.UpdateIngress(ingress); err != nil {
+		var err error
+		switch referrer := module.(type) {
+		case modules.ConfigMapSecretReferrer:
+			err = referrer.UpdateIngressWithReferences(ingress, configMaps, refSecrets)
+		case modules.ConfigMapReferrer:
+			err = referrer.UpdateIngressWithConfigMaps(ingress, configMaps)
+		case modules.SecretReferrer:
+			err = referrer.UpdateIngressWithSecrets(ingress, refSecrets)
+		default:
+			err = module.UpdateIngress(ingress)
+		}
+		if err != nil {
 			c.clusterConf.DeleteIngress(ingress.Namespace, ingress.Name)
 			c.serverDataConf.DeleteIngress(ingress.Namespace, ingress.Name)
 			c.tlsConf.DeleteIngress(ingress.Namespace, ingress.Name)
@@ -100,6 +120,23 @@ func (c *ConfigBuilder) DeleteIngress(namespace, name string) {
 	}
 }
 
+// RouteStatus returns the order of route rules of the ingress, which is reported in the status of the ingress
+func (c *ConfigBuilder) RouteStatus(namespace, name string) []annotations.RouteStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.serverDataConf.RouteStatus(namespace, name)
+}
+
+// RouteStatusChanged returns namespaced names of ingresses whose order of route rules has been changed by other ingresses
+// since the last call, statuses of such ingresses should be updated again
+func (c *ConfigBuilder) RouteStatusChanged() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.serverDataConf.RouteStatusChanged()
+}
+
 func (c *ConfigBuilder) UpdateService(service *corev1.Service, endpoint *corev1.Endpoints) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -121,6 +158,15 @@ func (c *ConfigBuilder) UpdateSecret(secret *corev1.Secret) error {
 	if err := c.tlsConf.UpdateSecret(secret); err != nil {
 		return err
 	}
+
+	// update modules which read data from Secrets referenced by annotations
+	for _, module := range c.modules {
+		if referrer, ok := module.(modules.SecretReferrer); ok {
+			if err := referrer.UpdateSecret(secret); err != nil {
+				return fmt.Errorf("%s: %s", module.Name(), err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -129,6 +175,65 @@ func (c *ConfigBuilder) DeleteSecret(namespace, name string) {
 	defer c.lock.Unlock()
 
 	c.tlsConf.DeleteSecret(namespace, name)
+
+	// fail closed for modules which read data from the Secret, until ingresses referencing it are synced again
+	for _, module := range c.modules {
+		if referrer, ok := module.(modules.SecretReferrer); ok {
+			referrer.DeleteSecret(namespace, name)
+		}
+	}
+}
+
+// UpdateConfigMap updates the default values of modules if the ConfigMap is the global ConfigMap
+func (c *ConfigBuilder) UpdateConfigMap(configMap *corev1.ConfigMap) error {
+	if util.NamespacedName(configMap.Namespace, configMap.Name) != option.Opts.Ingress.ConfigMap {
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.updateGlobalConfig(configMap.Data)
+}
+
+// DeleteConfigMap resets the default values of modules if the ConfigMap is the global ConfigMap
+func (c *ConfigBuilder) DeleteConfigMap(namespace, name string) {
+	if util.NamespacedName(namespace, name) != option.Opts.Ingress.ConfigMap {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.updateGlobalConfig(nil); err != nil {
+		log.Error(err, "fail to reset global config")
+	}
+}
+
+func (c *ConfigBuilder) updateGlobalConfig(data map[string]string) error {
+	var errs []string
+	for _, module := range c.modules {
+		handler, ok := module.(modules.GlobalConfigHandler)
+		if !ok {
+			continue
+		}
+		if err := handler.UpdateGlobalConfig(data); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", module.Name(), err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("fail to update global config, %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// AcmeCertificates returns certificates which should be obtained through ACME
+func (c *ConfigBuilder) AcmeCertificates() []configs.AcmeCertificate {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.tlsConf.AcmeCertificates()
 }
 
 func (c *ConfigBuilder) InitReload(ctx context.Context) {
@@ -15,6 +15,8 @@ package configs
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/server_cert_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_tls_conf/tls_rule_conf"
@@ -23,13 +25,22 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
 )
 
 const (
 	DefaultCNName = "example"
 )
 
+// AcmeCertificate is a certificate which should be obtained through ACME
+type AcmeCertificate struct {
+	Namespace  string
+	SecretName string
+	Hosts      []string
+}
+
 type certConf struct {
 	cert []byte
 	key  []byte
@@ -51,6 +62,7 @@ type TLSConfig struct {
 	tlsRuleVersion    string
 
 	ingress2secret *setmultimap.MultiMap
+	ingress2acme   map[string][]AcmeCertificate
 
 	serverCertConf *server_cert_conf.BfeServerCertConf
 	tlsRuleConf    *tls_rule_conf.BfeTlsRuleConf
@@ -60,6 +72,7 @@ type TLSConfig struct {
 func NewTLSConfig(version string) *TLSConfig {
 	tlsConf := &TLSConfig{
 		ingress2secret: setmultimap.New(),
+		ingress2acme:   make(map[string][]AcmeCertificate),
 		serverCertConf: newServerCertConf(version),
 		tlsRuleConf:    newTlsRuleConf(version),
 		certs:          make(map[string]certConf),
@@ -110,6 +123,19 @@ func (c *TLSConfig) UpdateIngress(ingress *netv1.Ingress, secrets []*corev1.Secr
 		c.ingress2secret.Put(ingressName, secretName)
 	}
 
+	acmeCerts, err := newAcmeCertificates(ingress)
+	if err != nil {
+		return err
+	}
+	delete(c.ingress2acme, ingressName)
+	for _, cert := range acmeCerts {
+		// secret may not exist until the certificate is issued
+		c.ingress2secret.Put(ingressName, util.NamespacedName(cert.Namespace, cert.SecretName))
+	}
+	if len(acmeCerts) > 0 {
+		c.ingress2acme[ingressName] = acmeCerts
+	}
+
 	for _, secret := range secrets {
 		if err := c.UpdateSecret(secret); err != nil {
 			return err
@@ -121,6 +147,7 @@ func (c *TLSConfig) UpdateIngress(ingress *netv1.Ingress, secrets []*corev1.Secr
 
 func (c *TLSConfig) DeleteIngress(namespace, name string) {
 	ingressName := util.NamespacedName(namespace, name)
+	delete(c.ingress2acme, ingressName)
 	if !c.ingress2secret.ContainsKey(ingressName) {
 		return
 	}
@@ -159,6 +186,30 @@ func (c *TLSConfig) deleteCert(name string) {
 	delete(c.certs, name)
 }
 
+// AcmeCertificates returns all certificates which should be obtained through ACME.
+// Hosts of ingresses sharing the same secret are merged into one certificate.
+func (c *TLSConfig) AcmeCertificates() []AcmeCertificate {
+	merged := make(map[string]*AcmeCertificate)
+	for _, certs := range c.ingress2acme {
+		for _, cert := range certs {
+			name := util.NamespacedName(cert.Namespace, cert.SecretName)
+			if _, ok := merged[name]; !ok {
+				merged[name] = &AcmeCertificate{Namespace: cert.Namespace, SecretName: cert.SecretName}
+			}
+			merged[name].Hosts = mergeHosts(merged[name].Hosts, cert.Hosts)
+		}
+	}
+
+	result := make([]AcmeCertificate, 0, len(merged))
+	for _, cert := range merged {
+		result = append(result, *cert)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return util.NamespacedName(result[i].Namespace, result[i].SecretName) < util.NamespacedName(result[j].Namespace, result[j].SecretName)
+	})
+	return result
+}
+
 func (c *TLSConfig) UpdateSecret(secret *corev1.Secret) error {
 	name := util.NamespacedName(secret.Namespace, secret.Name)
 	if !c.ingress2secret.ContainsValue(name) {
@@ -247,3 +298,50 @@ func getCertFilePath(name string) string {
 func getKeyFilePath(name string) string {
 	return CertKeyFilePath + name + ".key"
 }
+
+// newAcmeCertificates collects certificates to be obtained through ACME from spec.tls of the ingress
+func newAcmeCertificates(ingress *netv1.Ingress) ([]AcmeCertificate, error) {
+	enabled, err := annotations.GetAcme(ingress.Annotations)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	if !option.Opts.Ingress.AcmeEnabled() {
+		return nil, fmt.Errorf("annotation %s is set, but acme is not enabled in bfe ingress controller", annotations.AcmeAnnotation)
+	}
+
+	var certs []AcmeCertificate
+	for _, tls := range ingress.Spec.TLS {
+		if len(tls.SecretName) == 0 || len(tls.Hosts) == 0 {
+			return nil, fmt.Errorf("both hosts and secretName of tls should be set when %s is enabled", annotations.AcmeAnnotation)
+		}
+		for _, host := range tls.Hosts {
+			if strings.Contains(host, "*") {
+				return nil, fmt.Errorf("wildcard host[%s] is not supported by acme http-01 challenge", host)
+			}
+		}
+		certs = append(certs, AcmeCertificate{
+			Namespace:  ingress.Namespace,
+			SecretName: tls.SecretName,
+			Hosts:      mergeHosts(nil, tls.Hosts),
+		})
+	}
+	return certs, nil
+}
+
+// mergeHosts returns the sorted union of two host lists
+func mergeHosts(hosts1, hosts2 []string) []string {
+	set := make(map[string]bool)
+	for _, host := range hosts1 {
+		set[strings.ToLower(host)] = true
+	}
+	for _, host := range hosts2 {
+		set[strings.ToLower(host)] = true
+	}
+
+	hosts := make([]string, 0, len(set))
+	for host := range set {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
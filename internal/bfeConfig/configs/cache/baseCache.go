@@ -17,6 +17,7 @@ package cache
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -46,6 +47,7 @@ type httpBaseCache struct {
 
 // BuildRuleFunc is a function to build a Rule from a `netv1.HTTPIngressPath` of an Ingress
 // Generally, this function should check the Rule itself and return an error if the Rule is invalid
+// If the function returns a nil Rule, the HTTPIngressPath is skipped
 type BuildRuleFunc func(ingress *netv1.Ingress, host, path string, httpPath netv1.HTTPIngressPath) (Rule, error)
 
 // BeforeUpdateIngressFunc is a function to be called before updating the cache with an Ingress
@@ -144,16 +146,25 @@ func (c *BaseCache) UpdateByIngressFramework(
 		}
 	}
 
+	aliases, err := getHostAliases(ingress)
+	if err != nil {
+		return err
+	}
+
 	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
 	for _, rule := range ingress.Spec.Rules {
 		if rule.HTTP == nil || len(rule.HTTP.Paths) == 0 {
 			continue
 		}
 
+		// aliases of the host are served by the same rules
+		hosts := append([]string{rule.Host}, aliases[annotations.NormalizeHost(rule.Host)]...)
 		for _, p := range rule.HTTP.Paths {
-			if err := c.addRuleToBaseCache(ingress, rule.Host, p, buildRule); err != nil {
-				c.DeleteByIngress(ingressName)
-				return err
+			for _, host := range hosts {
+				if err := c.addRuleToBaseCache(ingress, host, p, buildRule); err != nil {
+					c.DeleteByIngress(ingressName)
+					return err
+				}
 			}
 		}
 	}
@@ -170,25 +181,46 @@ func (c *BaseCache) UpdateByIngressFramework(
 }
 
 func (c *BaseCache) addRuleToBaseCache(ingress *netv1.Ingress, host string, httpPath netv1.HTTPIngressPath, buildRule BuildRuleFunc) error {
+	host = annotations.NormalizeHost(host)
 	if err := checkHost(host); err != nil {
 		return err
 	}
 
+	wildcard, err := annotations.GetWildcardHost(ingress.Annotations)
+	if err != nil {
+		return err
+	}
 	if len(host) == 0 {
 		host = "*"
+	} else if strings.HasPrefix(host, "*.") && wildcard == annotations.WildcardHostMultiLabel {
+		host = MultiLabelWildcardPrefix + host[2:]
 	}
 
 	path := httpPath.Path
-	if err := checkPath(path); err != nil {
-		return err
+	regex := false
+	if httpPath.PathType == nil || *httpPath.PathType == netv1.PathTypeImplementationSpecific {
+		if regex, err = annotations.GetPathRegex(ingress.Annotations); err != nil {
+			return err
+		}
 	}
 
-	if httpPath.PathType == nil || *httpPath.PathType == netv1.PathTypePrefix || *httpPath.PathType == netv1.PathTypeImplementationSpecific {
-		path = path + "*"
+	if regex {
+		if err := checkRegexPath(path); err != nil {
+			return err
+		}
+		path = RegexPathPrefix + path
+	} else {
+		if err := checkPath(path); err != nil {
+			return err
+		}
+
+		if httpPath.PathType == nil || *httpPath.PathType == netv1.PathTypePrefix || *httpPath.PathType == netv1.PathTypeImplementationSpecific {
+			path = path + "*"
+		}
 	}
 
 	rule, err := buildRule(ingress, host, path, httpPath)
-	if err != nil {
+	if err != nil || rule == nil {
 		return err
 	}
 
@@ -256,6 +288,28 @@ func delRule(ruleList []Rule, ingress string) []Rule {
 	return result
 }
 
+// getHostAliases returns aliases of hosts of the ingress, hosts in annotation should be hosts of rules
+func getHostAliases(ingress *netv1.Ingress) (annotations.HostAliases, error) {
+	aliases, err := annotations.GetHostAliases(ingress.Annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	for host := range aliases {
+		found := false
+		for _, rule := range ingress.Spec.Rules {
+			if annotations.NormalizeHost(rule.Host) == host {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("annotation %s is illegal, host [%s] is not a host of rules", annotations.HostAliasesAnnotation, host)
+		}
+	}
+	return aliases, nil
+}
+
 func checkHost(host string) error {
 	// wildcard hostname: started with "*." is allowed
 	if strings.Count(host, "*") > 1 || (strings.Count(host, "*") == 1 && !strings.HasPrefix(host, "*.")) {
@@ -274,3 +328,15 @@ func checkPath(path string) error {
 	}
 	return nil
 }
+
+func checkRegexPath(path string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("path is not set")
+	}
+
+	// the pattern is enclosed in a raw string in condition
+	if _, err := regexp.Compile(path); err != nil || strings.ContainsAny(path, "`\n") {
+		return fmt.Errorf("regex path[%s] is illegal", path)
+	}
+	return nil
+}
@@ -16,12 +16,31 @@ package cache
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
 )
 
+// RegexPathPrefix is the prefix of regex paths in Rules, paths of other types are
+// either exact paths or prefix paths ending with "*"
+const RegexPathPrefix = "~"
+
+// IsRegexPath returns true if path of a Rule is a regex path
+func IsRegexPath(path string) bool {
+	return strings.HasPrefix(path, RegexPathPrefix)
+}
+
+// MultiLabelWildcardPrefix is the prefix of wildcard hosts in Rules whose "*" matches one or more labels,
+// wildcard hosts starting with "*." match exactly one label
+const MultiLabelWildcardPrefix = "**."
+
+// IsMultiLabelWildcardHost returns true if "*" of the host of a Rule matches one or more labels
+func IsMultiLabelWildcardHost(host string) bool {
+	return strings.HasPrefix(host, MultiLabelWildcardPrefix)
+}
+
 type BaseRule struct {
 	Ingress     string
 	Host        string
@@ -81,6 +100,14 @@ func buildCondition(host string, path string, annots map[string]string) (string,
 		statement = append(statement, primitive)
 	}
 
+	primitive, err = annotations.GetCanaryExpression(annots)
+	if err != nil {
+		return "", err
+	}
+	if len(primitive) > 0 {
+		statement = append(statement, primitive)
+	}
+
 	return strings.Join(statement, "&&"), nil
 }
 
@@ -90,10 +117,12 @@ func hostPrimitive(host string) (string, error) {
 		return "", nil
 	}
 
+	// port of the request host is ignored by bfe, so the regex is anchored at both ends
+	if IsMultiLabelWildcardHost(host) {
+		return fmt.Sprintf("req_host_regmatch(`(?i)^.+\\.%s$`)", regexp.QuoteMeta(strings.TrimPrefix(host, MultiLabelWildcardPrefix))), nil
+	}
 	if strings.HasPrefix(host, "*.") {
-		dn := host[2:]
-		dn = strings.ReplaceAll(dn, ".", "\\.")
-		return fmt.Sprintf(`req_host_regmatch("(?i)^[^\.]+%s")`, dn), nil
+		return fmt.Sprintf("req_host_regmatch(`(?i)^[^.]+\\.%s$`)", regexp.QuoteMeta(host[2:])), nil
 	}
 	return fmt.Sprintf(`req_host_in("%s")`, host), nil
 }
@@ -103,6 +132,10 @@ func pathPrimitive(path string) (string, error) {
 	if len(path) == 0 || path == "*" {
 		return "", nil // no restriction
 	}
+	if IsRegexPath(path) {
+		// regex is matched from the beginning of path
+		return fmt.Sprintf("req_path_regmatch(`^(?:%s)`)", strings.TrimPrefix(path, RegexPathPrefix)), nil
+	}
 	if path[len(path)-1] == '*' {
 		return fmt.Sprintf(`req_path_element_prefix_in("%s", false)`, path[:len(path)-1]), nil
 	}
@@ -0,0 +1,116 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cache
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultCond matches all requests, it is used for Rules without any restriction
+const defaultCond = "default_t()"
+
+// PriorityConds collects conditions of Rules in the order of priority, so that
+// requests matched by Rules with higher priority can be excluded from the condition of a Rule.
+// It is used by modules which check every matched rule, or which need to skip requests
+// matched by a Rule with higher priority.
+type PriorityConds struct {
+	rules []priorityCond
+}
+
+type priorityCond struct {
+	host string
+	path string
+	cond string
+}
+
+// Exclude returns the condition of rule, excluding requests matched by the Rules added before.
+// Only Rules whose host and path may match the same request as rule are excluded.
+func (p *PriorityConds) Exclude(rule Rule, cond string) string {
+	if len(cond) == 0 {
+		cond = defaultCond
+	}
+
+	seen := make(map[string]bool)
+	for _, higher := range p.rules {
+		if seen[higher.cond] || !hostsOverlap(higher.host, rule.GetHost()) || !pathsOverlap(higher.path, rule.GetPath()) {
+			continue
+		}
+		seen[higher.cond] = true
+		cond = fmt.Sprintf("%s&&!(%s)", cond, higher.cond)
+	}
+	return cond
+}
+
+// Add adds the condition of rule, Rules should be added in descending order of priority
+func (p *PriorityConds) Add(rule Rule, cond string) {
+	if len(cond) == 0 {
+		cond = defaultCond
+	}
+	p.rules = append(p.rules, priorityCond{host: rule.GetHost(), path: rule.GetPath(), cond: cond})
+}
+
+// hostsOverlap returns false if no request host can be matched by both hosts.
+// It may return true for wildcard hosts which do not overlap, which only makes the condition longer.
+func hostsOverlap(host1, host2 string) bool {
+	if host1 == host2 || len(host1) == 0 || host1 == "*" || len(host2) == 0 || host2 == "*" {
+		return true
+	}
+
+	wildcard1, wildcard2 := wildcardHost(host1), wildcardHost(host2)
+	switch {
+	case !wildcard1 && !wildcard2:
+		return false
+	case !wildcard1:
+		return wildcardHostMatch(host2, host1)
+	case !wildcard2:
+		return wildcardHostMatch(host1, host2)
+	}
+
+	suffix1, suffix2 := strings.TrimLeft(host1, "*"), strings.TrimLeft(host2, "*")
+	if !IsMultiLabelWildcardHost(host1) && !IsMultiLabelWildcardHost(host2) {
+		return suffix1 == suffix2
+	}
+	return strings.HasSuffix(suffix1, suffix2) || strings.HasSuffix(suffix2, suffix1)
+}
+
+// wildcardHostMatch returns true if the wildcard host matches the exact host
+func wildcardHostMatch(wildcard, host string) bool {
+	suffix := strings.TrimLeft(wildcard, "*")
+	if len(host) <= len(suffix) || !strings.HasSuffix(host, suffix) {
+		return false
+	}
+	return IsMultiLabelWildcardHost(wildcard) || !strings.Contains(host[:len(host)-len(suffix)], ".")
+}
+
+// pathsOverlap returns false if no request path can be matched by both paths.
+// Regex paths are considered to overlap with any path.
+func pathsOverlap(path1, path2 string) bool {
+	if path1 == path2 || len(path1) == 0 || path1 == "*" || len(path2) == 0 || path2 == "*" ||
+		IsRegexPath(path1) || IsRegexPath(path2) {
+		return true
+	}
+
+	prefix1, prefix2 := wildcardPath(path1), wildcardPath(path2)
+	path1, path2 = strings.TrimSuffix(path1, "*"), strings.TrimSuffix(path2, "*")
+	switch {
+	case prefix1 && prefix2:
+		return strings.HasPrefix(path1, path2) || strings.HasPrefix(path2, path1)
+	case prefix1:
+		return strings.HasPrefix(path2, path1)
+	case prefix2:
+		return strings.HasPrefix(path1, path2)
+	}
+	return false
+}
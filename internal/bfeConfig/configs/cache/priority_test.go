@@ -0,0 +1,153 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cache
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func TestPriorityConds(t *testing.T) {
+	// rules in the order of priority
+	rules := []BaseRule{
+		{Host: "a.foo.com", Path: "/api/v1"},
+		{Host: "a.foo.com", Path: "/api*"},
+		{Host: "*.foo.com", Path: "/api*"},
+		{Host: "**.foo.com", Path: "/*"},
+		{Host: "bar.com", Path: "~/(static|assets)/"},
+		{Host: "bar.com", Path: "*"},
+		{Host: "*", Path: "/api*"},
+		{Host: "*", Path: ""},
+	}
+	tests := []struct {
+		host string
+		path string
+		want int // index of the rule matching the request
+	}{
+		{host: "a.foo.com", path: "/api/v1", want: 0},
+		{host: "a.foo.com", path: "/api/v2", want: 1},
+		{host: "b.foo.com", path: "/api/v1", want: 2},
+		{host: "a.b.foo.com", path: "/api/v1", want: 3},
+		{host: "a.foo.com", path: "/web", want: 3},
+		{host: "bar.com", path: "/assets/a.js", want: 4},
+		{host: "bar.com", path: "/api", want: 5},
+		{host: "baz.com", path: "/api/v1", want: 6},
+		{host: "baz.com", path: "/web", want: 7},
+	}
+
+	var conds PriorityConds
+	var ruleConds []*condition.Condition
+	for _, rule := range rules {
+		cond, err := rule.GetCond()
+		if err != nil {
+			t.Fatalf("GetCond() error = %v", err)
+		}
+		ruleCond := conds.Exclude(rule, cond)
+		conds.Add(rule, cond)
+
+		c, err := condition.Build(ruleCond)
+		if err != nil {
+			t.Fatalf("condition.Build(%s) error = %v", ruleCond, err)
+		}
+		ruleConds = append(ruleConds, &c)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			req := newPathRequest(tt.host, tt.path)
+			for i, cond := range ruleConds {
+				if got := (*cond).Match(req); got != (i == tt.want) {
+					t.Errorf("rule %d %s%s matched = %v, want %v", i, rules[i].Host, rules[i].Path, got, i == tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPriorityConds_overlap(t *testing.T) {
+	tests := []struct {
+		name   string
+		higher []BaseRule
+		rule   BaseRule
+		want   int // number of excluded conditions
+	}{
+		{
+			name:   "other host",
+			higher: []BaseRule{{Host: "b.foo.com"}, {Host: "*.bar.com"}, {Host: "*.b.foo.com"}},
+			rule:   BaseRule{Host: "a.foo.com"},
+			want:   0,
+		},
+		{
+			name:   "wildcard host",
+			higher: []BaseRule{{Host: "a.foo.com"}, {Host: "*.foo.com"}, {Host: "**.foo.com"}, {Host: "*"}},
+			rule:   BaseRule{Host: "a.foo.com"},
+			want:   4,
+		},
+		{
+			name:   "exact host of wildcard rule",
+			higher: []BaseRule{{Host: "a.foo.com"}, {Host: "a.b.foo.com"}, {Host: "*.b.foo.com"}, {Host: "foo.com"}},
+			rule:   BaseRule{Host: "*.foo.com"},
+			want:   1,
+		},
+		{
+			name:   "exact host of multi-label wildcard rule",
+			higher: []BaseRule{{Host: "a.foo.com"}, {Host: "a.b.foo.com"}, {Host: "*.b.foo.com"}, {Host: "foo.com"}},
+			rule:   BaseRule{Host: "**.foo.com"},
+			want:   3,
+		},
+		{
+			name:   "other path",
+			higher: []BaseRule{{Host: "a.foo.com", Path: "/web"}, {Host: "a.foo.com", Path: "/web/*"}, {Host: "a.foo.com", Path: "/api/v1"}},
+			rule:   BaseRule{Host: "a.foo.com", Path: "/api*"},
+			want:   1,
+		},
+		{
+			name:   "regex path",
+			higher: []BaseRule{{Host: "a.foo.com", Path: "~/web"}},
+			rule:   BaseRule{Host: "a.foo.com", Path: "/api*"},
+			want:   1,
+		},
+		{
+			name:   "duplicated condition",
+			higher: []BaseRule{{Host: "a.foo.com", Path: "/api"}, {Host: "a.foo.com", Path: "/api"}},
+			rule:   BaseRule{Host: "a.foo.com", Path: "/api*"},
+			want:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conds PriorityConds
+			for _, rule := range tt.higher {
+				cond, _ := rule.GetCond()
+				conds.Add(rule, cond)
+			}
+			cond, _ := tt.rule.GetCond()
+			if got := strings.Count(conds.Exclude(tt.rule, cond), "&&!("); got != tt.want {
+				t.Errorf("Exclude() excluded %d conditions, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func newPathRequest(host, path string) *bfe_basic.Request {
+	return &bfe_basic.Request{
+		Session:     &bfe_basic.Session{},
+		HttpRequest: &bfe_http.Request{Host: host, URL: &url.URL{Path: path}},
+	}
+}
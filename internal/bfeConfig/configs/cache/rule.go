@@ -51,15 +51,27 @@ type Rule interface {
 // CompareRule compares the priority of two Rules.
 // The function can be used to sort a Rule list.
 func CompareRule(rule1, rule2 Rule) bool {
-	// host: exact match over wildcard match
-	// path: long path over short path
+	// host: exact match over wildcard match, long wildcard suffix over short one, single-label wildcard over multi-label one
+	// router.priority: higher priority over lower priority, for rules of the same host
+	// path: exact match over regex match over prefix match, long path over short path
+	// canary: canary ingress over main ingress
 
 	// compare host
-	if result := comparePriority(rule1.GetHost(), rule2.GetHost(), wildcardHost); result != 0 {
+	if result := compareHost(rule1.GetHost(), rule2.GetHost()); result != 0 {
 		return result > 0
 	}
 
+	// compare priority set by annotation
+	routePriority1, _ := annotations.GetRoutePriority(rule1.GetAnnotations())
+	routePriority2, _ := annotations.GetRoutePriority(rule2.GetAnnotations())
+	if routePriority1 != routePriority2 {
+		return routePriority1 > routePriority2
+	}
+
 	// compare path
+	if result := compareRegexPath(rule1.GetPath(), rule2.GetPath()); result != 0 {
+		return result > 0
+	}
 	if result := comparePriority(rule1.GetPath(), rule2.GetPath(), wildcardPath); result != 0 {
 		return result > 0
 	}
@@ -71,8 +83,25 @@ func CompareRule(rule1, rule2 Rule) bool {
 		return priority1 > priority2
 	}
 
+	// canary rule should be matched before the main rule
+	canary1, canary2 := annotations.IsCanary(rule1.GetAnnotations()), annotations.IsCanary(rule2.GetAnnotations())
+	if canary1 != canary2 {
+		return canary1
+	}
+
 	// check createTime
-	return rule1.GetCreateTime().Before(rule2.GetCreateTime())
+	if !rule1.GetCreateTime().Equal(rule2.GetCreateTime()) {
+		return rule1.GetCreateTime().Before(rule2.GetCreateTime())
+	}
+
+	// namespaced name of ingress, host and path keep the order stable for ingresses created at the same time
+	if rule1.GetIngress() != rule2.GetIngress() {
+		return rule1.GetIngress() < rule2.GetIngress()
+	}
+	if rule1.GetHost() != rule2.GetHost() {
+		return rule1.GetHost() < rule2.GetHost()
+	}
+	return rule1.GetPath() < rule2.GetPath()
 }
 
 func comparePriority(str1, str2 string, wildcard func(string) bool) int {
@@ -95,8 +124,53 @@ func comparePriority(str1, str2 string, wildcard func(string) bool) int {
 
 }
 
+// compareHost compares two hosts, exact host has higher priority than wildcard host.
+// For wildcard hosts, the one with longer suffix after "*" has higher priority, e.g. "*.bar.foo.com" over "*.foo.com",
+// and single-label wildcard has higher priority than multi-label wildcard of the same suffix.
+func compareHost(host1, host2 string) int {
+	if !wildcardHost(host1) || !wildcardHost(host2) {
+		return comparePriority(host1, host2, wildcardHost)
+	}
+
+	suffix1, suffix2 := strings.TrimLeft(host1, "*"), strings.TrimLeft(host2, "*")
+	if len(suffix1) != len(suffix2) {
+		if len(suffix1) > len(suffix2) {
+			return 1
+		}
+		return -1
+	}
+
+	multi1, multi2 := IsMultiLabelWildcardHost(host1), IsMultiLabelWildcardHost(host2)
+	if multi1 == multi2 {
+		return 0
+	}
+	if multi2 {
+		return 1
+	}
+	return -1
+}
+
+// compareRegexPath compares a regex path with a non-regex path, exact path has higher priority, prefix path has lower priority.
+// 0 is returned if both or neither of the paths are regex paths.
+func compareRegexPath(path1, path2 string) int {
+	regex1, regex2 := IsRegexPath(path1), IsRegexPath(path2)
+	if regex1 == regex2 {
+		return 0
+	}
+	if regex1 {
+		if wildcardPath(path2) {
+			return 1
+		}
+		return -1
+	}
+	if wildcardPath(path1) {
+		return -1
+	}
+	return 1
+}
+
 func wildcardPath(path string) bool {
-	if len(path) > 0 && strings.HasSuffix(path, "*") {
+	if len(path) > 0 && strings.HasSuffix(path, "*") && !IsRegexPath(path) {
 		return true
 	}
 
@@ -104,7 +178,7 @@ func wildcardPath(path string) bool {
 }
 
 func wildcardHost(host string) bool {
-	if len(host) > 0 && strings.HasPrefix(host, "*.") {
+	if host == "*" || strings.HasPrefix(host, "*.") || IsMultiLabelWildcardHost(host) {
 		return true
 	}
 
@@ -0,0 +1,175 @@
+// Copyright (c) 2021 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_http"
+)
+
+func Test_compareHost(t *testing.T) {
+	// hosts in the order of priority, a request matching more than one host should match the first one
+	hosts := []string{
+		"a.bar.foo.com",
+		"bar.foo.com",
+		"*.bar.foo.com",
+		"**.bar.foo.com",
+		"*.foo.com",
+		"**.foo.com",
+		"*",
+	}
+	for i := range hosts {
+		for j := range hosts {
+			want := 0
+			if i < j {
+				want = 1
+			} else if i > j {
+				want = -1
+			}
+			if got := compareHost(hosts[i], hosts[j]); got != want {
+				t.Errorf("compareHost(%s, %s) = %d, want %d", hosts[i], hosts[j], got, want)
+			}
+		}
+	}
+}
+
+func Test_compareRuleTieBreak(t *testing.T) {
+	created := time.Now()
+	// rules in the order of priority, rules of ingresses created at the same time are ordered by ingress, host and path
+	rules := []Rule{
+		NewBaseRule("default/a", "bar.com", "/foo", nil, created.Add(-time.Second)),
+		NewBaseRule("default/a", "bar.com", "/bar", nil, created),
+		NewBaseRule("default/a", "bar.com", "/foo", nil, created),
+		NewBaseRule("default/a", "foo.com", "/bar", nil, created),
+		NewBaseRule("default/b", "bar.com", "/bar", nil, created),
+		NewBaseRule("test/a", "bar.com", "/bar", nil, created),
+	}
+	for i := range rules {
+		for j := range rules {
+			if got := CompareRule(rules[i], rules[j]); got != (i < j) {
+				t.Errorf("CompareRule(%d, %d) = %v, want %v", i, j, got, i < j)
+			}
+		}
+	}
+}
+
+func Test_hostPrimitive(t *testing.T) {
+	tests := []struct {
+		host    string
+		match   []string
+		noMatch []string
+	}{
+		{
+			host:    "foo.com",
+			match:   []string{"foo.com", "FOO.com", "foo.com:8080"},
+			noMatch: []string{"a.foo.com", "foo.com.cn"},
+		},
+		{
+			host:    "*.foo.com",
+			match:   []string{"a.foo.com", "A.Foo.com", "a.foo.com:8443"},
+			noMatch: []string{"foo.com", "afoo.com", "a.b.foo.com", "a.foo.com.cn", "a.fooxcom"},
+		},
+		{
+			host:    "**.foo.com",
+			match:   []string{"a.foo.com", "a.b.foo.com", "A.B.foo.com:8080"},
+			noMatch: []string{"foo.com", "afoo.com", "a.foo.com.cn"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			primitive, err := hostPrimitive(tt.host)
+			if err != nil {
+				t.Fatalf("hostPrimitive() error = %v", err)
+			}
+			cond, err := condition.Build(primitive)
+			if err != nil {
+				t.Fatalf("condition.Build(%s) error = %v", primitive, err)
+			}
+			for _, host := range tt.match {
+				if !cond.Match(newRequest(host)) {
+					t.Errorf("%s should match host %s", primitive, host)
+				}
+			}
+			for _, host := range tt.noMatch {
+				if cond.Match(newRequest(host)) {
+					t.Errorf("%s should not match host %s", primitive, host)
+				}
+			}
+		})
+	}
+}
+
+func Test_pathPrimitive(t *testing.T) {
+	tests := []struct {
+		path    string
+		match   []string
+		noMatch []string
+	}{
+		{
+			path:    "/foo",
+			match:   []string{"/foo"},
+			noMatch: []string{"/foo/bar", "/foobar"},
+		},
+		{
+			path:    "/foo*",
+			match:   []string{"/foo", "/foo/bar"},
+			noMatch: []string{"/foobar", "/bar/foo"},
+		},
+		{
+			path:    "~/foo/v[0-9]+",
+			match:   []string{"/foo/v1", "/foo/v12/bar"},
+			noMatch: []string{"/foo/bar", "/bar/foo/v1"},
+		},
+		{
+			// every alternative is matched from the beginning of path
+			path:    "~/foo|/bar",
+			match:   []string{"/foo", "/foo/baz", "/bar", "/bar/baz"},
+			noMatch: []string{"/baz/bar", "/baz/foo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			primitive, err := pathPrimitive(tt.path)
+			if err != nil {
+				t.Fatalf("pathPrimitive() error = %v", err)
+			}
+			cond, err := condition.Build(primitive)
+			if err != nil {
+				t.Fatalf("condition.Build(%s) error = %v", primitive, err)
+			}
+			for _, path := range tt.match {
+				if !cond.Match(newPathRequest("foo.com", path)) {
+					t.Errorf("%s should match path %s", primitive, path)
+				}
+			}
+			for _, path := range tt.noMatch {
+				if cond.Match(newPathRequest("foo.com", path)) {
+					t.Errorf("%s should not match path %s", primitive, path)
+				}
+			}
+		})
+	}
+}
+
+func newRequest(host string) *bfe_basic.Request {
+	return &bfe_basic.Request{
+		Session:     &bfe_basic.Session{},
+		HttpRequest: &bfe_http.Request{Host: host},
+	}
+}
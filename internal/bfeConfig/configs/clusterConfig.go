@@ -46,6 +46,8 @@ type ClusterConfig struct {
 
 	ingress2Cluster *setmultimap.MultiMap
 	service2Cluster *setmultimap.MultiMap
+	// ingresses obtaining certificates through ACME, which require the backend of the challenge solver
+	acmeIngresses map[string]bool
 
 	gslbConf         gslb_conf.GslbConf
 	clusterTableConf cluster_table_conf.ClusterTableConf
@@ -60,6 +62,7 @@ func NewClusterConfig(version string) *ClusterConfig {
 	return &ClusterConfig{
 		ingress2Cluster: setmultimap.New(),
 		service2Cluster: setmultimap.New(),
+		acmeIngresses:   make(map[string]bool),
 		gslbConf: gslb_conf.GslbConf{
 			Clusters: &gslbCluster,
 			Hostname: &hostname,
@@ -85,6 +88,7 @@ func (c *ClusterConfig) UpdateIngress(ingress *netv1.Ingress, services map[strin
 	}
 
 	balance, _ := annotations.GetBalance(ingress.Annotations)
+	namespaces, _ := annotations.GetBackendNamespaces(ingress.Annotations)
 
 	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
 	for _, rule := range ingress.Spec.Rules {
@@ -93,10 +97,10 @@ func (c *ClusterConfig) UpdateIngress(ingress *netv1.Ingress, services map[strin
 			clusterName := util.ClusterName(ingressName, path.Backend.Service)
 
 			// cluster config
-			(*c.clusterTableConf.Config)[clusterName] = c.newClusterBackend(ingress.Namespace, path.Backend.Service, balance, services, endpoints)
+			(*c.clusterTableConf.Config)[clusterName] = c.newClusterBackend(ingress.Namespace, namespaces, path.Backend.Service, balance, services, endpoints)
 
 			// gslb config
-			(*c.gslbConf.Clusters)[clusterName] = c.newGslbClusterConf(ingress.Namespace, path.Backend.Service.Name, balance)
+			(*c.gslbConf.Clusters)[clusterName] = c.newGslbClusterConf(ingress.Namespace, namespaces, path.Backend.Service.Name, balance)
 
 			// put into map
 			c.ingress2Cluster.Put(ingressName, clusterName)
@@ -110,6 +114,13 @@ func (c *ClusterConfig) UpdateIngress(ingress *netv1.Ingress, services map[strin
 		c.addDefautBackend(endpoints[option.Opts.Ingress.DefaultBackend])
 	}
 
+	if acme, _ := annotations.GetAcme(ingress.Annotations); acme && option.Opts.Ingress.AcmeEnabled() {
+		c.acmeIngresses[ingressName] = true
+		c.addAcmeBackend()
+	} else {
+		c.deleteAcmeIngress(ingressName)
+	}
+
 	if err := cluster_table_conf.ClusterTableConfCheck(c.clusterTableConf); err != nil {
 		c.DeleteIngress(ingress.Namespace, ingress.Name)
 		return err
@@ -149,6 +160,8 @@ func (c *ClusterConfig) addDefautBackend(ep *corev1.Endpoints) {
 
 func (c *ClusterConfig) DeleteIngress(namespace, name string) {
 	ingressName := util.NamespacedName(namespace, name)
+	c.deleteAcmeIngress(ingressName)
+
 	clusters, ok := c.ingress2Cluster.Get(ingressName)
 	if !ok {
 		return
@@ -180,8 +193,47 @@ func (c *ClusterConfig) delDefautBackend() {
 	delete(*c.gslbConf.Clusters, util.DefaultClusterName())
 }
 
-// newClusterBackend makes cluster_table_conf.ClusterBackend configuration
-func (c *ClusterConfig) newClusterBackend(namespace string, backend *netv1.IngressServiceBackend, balance annotations.Balance, services map[string]*corev1.Service, endpoints map[string]*corev1.Endpoints) cluster_table_conf.ClusterBackend {
+// addAcmeBackend adds the cluster of the local ACME HTTP-01 challenge solver
+func (c *ClusterConfig) addAcmeBackend() {
+	// already exist
+	if _, ok := (*c.clusterTableConf.Config)[util.AcmeClusterName()]; ok {
+		return
+	}
+
+	subClusterName := "acme-solver"
+	instanceList := cluster_table_conf.SubClusterBackend{
+		newBackendConf("127.0.0.1", option.Opts.Ingress.AcmeSolverPort, defaultWeight),
+	}
+
+	subCluster := make(cluster_table_conf.ClusterBackend)
+	subCluster[subClusterName] = instanceList
+	(*c.clusterTableConf.Config)[util.AcmeClusterName()] = subCluster
+
+	gslbConf := make(gslb_conf.GslbClusterConf)
+	gslbConf[subClusterName] = defaultWeight
+	(*c.gslbConf.Clusters)[util.AcmeClusterName()] = gslbConf
+}
+
+// deleteAcmeIngress removes the backend of the challenge solver when no ingress obtains certificates through ACME
+func (c *ClusterConfig) deleteAcmeIngress(ingressName string) {
+	if !c.acmeIngresses[ingressName] {
+		return
+	}
+
+	delete(c.acmeIngresses, ingressName)
+	if len(c.acmeIngresses) == 0 {
+		c.delAcmeBackend()
+	}
+}
+
+func (c *ClusterConfig) delAcmeBackend() {
+	delete(*c.clusterTableConf.Config, util.AcmeClusterName())
+	delete(*c.gslbConf.Clusters, util.AcmeClusterName())
+}
+
+// newClusterBackend makes cluster_table_conf.ClusterBackend configuration,
+// services are in namespace of the ingress unless specified by namespaces
+func (c *ClusterConfig) newClusterBackend(namespace string, namespaces annotations.BackendNamespaces, backend *netv1.IngressServiceBackend, balance annotations.Balance, services map[string]*corev1.Service, endpoints map[string]*corev1.Endpoints) cluster_table_conf.ClusterBackend {
 
 	subClusters := make(cluster_table_conf.ClusterBackend)
 
@@ -191,14 +243,14 @@ func (c *ClusterConfig) newClusterBackend(namespace string, backend *netv1.Ingre
 	// check whether service exist in balance annotation
 	weights, ok := balance[backend.Name]
 	if !ok {
-		serviceName := util.NamespacedName(namespace, backend.Name)
+		serviceName := util.NamespacedName(namespaces.Namespace(namespace, backend.Name), backend.Name)
 		port := getTargetPort(backend.Port, services[serviceName])
 		subClusters[serviceName] = c.newSubClusterBackend(endpoints[serviceName], port)
 		return subClusters
 	}
 
 	for name := range weights {
-		serviceName := util.NamespacedName(namespace, name)
+		serviceName := util.NamespacedName(namespaces.Namespace(namespace, name), name)
 		port := getTargetPort(backend.Port, services[serviceName])
 		subClusters[serviceName] = c.newSubClusterBackend(endpoints[serviceName], port)
 	}
@@ -277,17 +329,17 @@ func newBackendConf(ip string, port int, weight int) *cluster_table_conf.Backend
 }
 
 // makeGslbClusterConf makes cluster_table_conf.ClusterBackend configuration
-func (c *ClusterConfig) newGslbClusterConf(namespace, service string, balance annotations.Balance) gslb_conf.GslbClusterConf {
+func (c *ClusterConfig) newGslbClusterConf(namespace string, namespaces annotations.BackendNamespaces, service string, balance annotations.Balance) gslb_conf.GslbClusterConf {
 	gslbConf := make(gslb_conf.GslbClusterConf)
 
 	weights, ok := balance[service]
 	if !ok {
-		gslbConf[util.NamespacedName(namespace, service)] = defaultWeight
+		gslbConf[util.NamespacedName(namespaces.Namespace(namespace, service), service)] = defaultWeight
 		return gslbConf
 	}
 
 	for name, weight := range weights {
-		gslbConf[util.NamespacedName(namespace, name)] = weight
+		gslbConf[util.NamespacedName(namespaces.Namespace(namespace, name), name)] = weight
 	}
 	return gslbConf
 }
@@ -313,7 +365,7 @@ func (c *ClusterConfig) UpdateService(service *corev1.Service, endpoint *corev1.
 			return fmt.Errorf("cluster [%s] error, port can not found in service", name)
 		} else {
 			(*c.clusterTableConf.Config)[name][serviceName] = c.newSubClusterBackend(endpoint, targetPort)
-			(*c.gslbConf.Clusters)[name] = c.newGslbClusterConf(service.Namespace, service.Name, nil)
+			(*c.gslbConf.Clusters)[name] = c.newGslbClusterConf(service.Namespace, nil, service.Name, nil)
 		}
 	}
 
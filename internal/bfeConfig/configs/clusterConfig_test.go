@@ -0,0 +1,83 @@
+// Copyright (c) 2021 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configs
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+func Test_acmeBackend(t *testing.T) {
+	opts := option.NewOptions()
+	opts.Ingress.AcmeDirectoryURL = "https://acme.example.com/directory"
+	option.Opts = opts
+	defer func() { option.Opts = nil }()
+
+	acme := map[string]string{annotations.AcmeAnnotation: "true"}
+	endpoints := map[string]*corev1.Endpoints{
+		"default/svc": {Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+			Ports:     []corev1.EndpointPort{{Port: 8080}},
+		}}},
+	}
+	c := NewClusterConfig("init")
+	hasBackend := func() bool {
+		_, ok := (*c.clusterTableConf.Config)[util.AcmeClusterName()]
+		return ok
+	}
+
+	steps := []struct {
+		name   string
+		action func() error
+		want   bool
+	}{
+		{"add plain ingress", func() error {
+			return c.UpdateIngress(newTestIngress("plain", nil, "plain.com"), nil, endpoints)
+		}, false},
+		{"add acme ingress foo", func() error {
+			return c.UpdateIngress(newTestIngress("foo", acme, "foo.com"), nil, endpoints)
+		}, true},
+		{"add acme ingress bar", func() error {
+			return c.UpdateIngress(newTestIngress("bar", acme, "bar.com"), nil, endpoints)
+		}, true},
+		{"delete acme ingress foo", func() error {
+			c.DeleteIngress("default", "foo")
+			return nil
+		}, true},
+		{"disable acme of ingress bar", func() error {
+			return c.UpdateIngress(newTestIngress("bar", nil, "bar.com"), nil, endpoints)
+		}, false},
+		{"enable acme of ingress bar", func() error {
+			return c.UpdateIngress(newTestIngress("bar", acme, "bar.com"), nil, endpoints)
+		}, true},
+		{"delete acme ingress bar while plain ingress exists", func() error {
+			c.DeleteIngress("default", "bar")
+			return nil
+		}, false},
+	}
+	for _, step := range steps {
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		if got := hasBackend(); got != step.want {
+			t.Errorf("%s: acme backend exists = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
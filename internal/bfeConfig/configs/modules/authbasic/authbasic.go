@@ -0,0 +1,234 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authbasic is the module of basic authentication.
+// This file implements operate rule cache, generate and reload config file methods.
+package authbasic
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_auth_basic"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+const (
+	ConfigNameAuthBasic = "mod_auth_basic"
+	RuleData            = "mod_auth_basic/auth_basic_rule.data"
+	// UserFileDir is the directory of user files, one file for each Secret
+	UserFileDir = "mod_auth_basic/users/"
+)
+
+type ModAuthBasicConfig struct {
+	version            string
+	products           *configs.ProductTable
+	authBasicRuleCache *authBasicRuleCache
+	authBasicConfFile  *mod_auth_basic.AuthBasicConfFile
+	// userFiles are the user files referenced by authBasicConfFile, file name => users
+	userFiles map[string][]string
+	// dumpedUserFiles are the user files on the disk
+	dumpedUserFiles map[string]bool
+}
+
+func NewAuthBasicConfig(version string, products *configs.ProductTable) *ModAuthBasicConfig {
+	return &ModAuthBasicConfig{
+		version:            version,
+		products:           products,
+		authBasicRuleCache: newAuthBasicRuleCache(version),
+		authBasicConfFile:  newAuthBasicConfFile(version),
+		userFiles:          make(map[string][]string),
+		dumpedUserFiles:    make(map[string]bool),
+	}
+}
+
+func newAuthBasicConfFile(version string) *mod_auth_basic.AuthBasicConfFile {
+	ruleList := make(mod_auth_basic.RuleFileList, 0)
+	productRuleList := mod_auth_basic.ProductRulesFile{
+		configs.DefaultProduct: &ruleList,
+	}
+	return &mod_auth_basic.AuthBasicConfFile{
+		Version: &version,
+		Config:  &productRuleList,
+	}
+}
+
+func (c *ModAuthBasicConfig) Name() string {
+	return ConfigNameAuthBasic
+}
+
+func (c *ModAuthBasicConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	return c.UpdateIngressWithSecrets(ingress, nil)
+}
+
+// UpdateIngressWithSecrets implements modules.SecretReferrer
+func (c *ModAuthBasicConfig) UpdateIngressWithSecrets(ingress *netv1.Ingress, secrets []*corev1.Secret) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.authBasicRuleCache.ContainsIngress(ingressName) {
+		c.authBasicRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.authBasicRuleCache.UpdateByIngress(ingress, secrets)
+}
+
+func (c *ModAuthBasicConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.authBasicRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.authBasicRuleCache.DeleteByIngress(ingressName)
+}
+
+// UpdateSecret implements modules.SecretReferrer
+func (c *ModAuthBasicConfig) UpdateSecret(secret *corev1.Secret) error {
+	return c.authBasicRuleCache.UpdateSecret(secret)
+}
+
+// DeleteSecret implements modules.SecretReferrer
+func (c *ModAuthBasicConfig) DeleteSecret(namespace, name string) {
+	c.authBasicRuleCache.DeleteSecret(namespace, name)
+}
+
+func (c *ModAuthBasicConfig) Reload() error {
+	if err := c.updateAuthBasicConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.authBasicConfFile.Version != c.version {
+		// dump user files, which are loaded together with the config file
+		if err := c.dumpUserFiles(); err != nil {
+			return err
+		}
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.authBasicConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameAuthBasic)
+		if err != nil {
+			return err
+		}
+		c.version = *c.authBasicConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModAuthBasicConfig) dumpUserFiles() error {
+	for fileName, users := range c.userFiles {
+		if err := util.DumpFile(fileName, []byte(strings.Join(users, "\n")+"\n")); err != nil {
+			return fmt.Errorf("dump %s error: %v", fileName, err)
+		}
+		c.dumpedUserFiles[fileName] = true
+	}
+
+	// delete files of Secrets which are no longer referenced
+	for fileName := range c.dumpedUserFiles {
+		if _, ok := c.userFiles[fileName]; !ok {
+			util.DeleteFile(fileName)
+			delete(c.dumpedUserFiles, fileName)
+		}
+	}
+	return nil
+}
+
+func (c *ModAuthBasicConfig) updateAuthBasicConf() error {
+	version := c.products.ConfVersion(c.authBasicRuleCache.Version)
+	if *c.authBasicConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.authBasicRuleCache.GetRules()
+	productRuleList := make(map[string]mod_auth_basic.RuleFileList)
+	userFiles := make(map[string][]string)
+	// conditions of rules without basic authentication
+	var noAuthConds cache.PriorityConds
+	for _, rule := range ruleList {
+		rule := rule.(*authBasicRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+		if len(cond) == 0 {
+			cond = "default_t()"
+		}
+
+		host := rule.GetHost()
+		if rule.authBasic == nil {
+			noAuthConds.Add(rule, cond)
+			continue
+		}
+
+		// mod_auth_basic stops at the first matched rule, but requests matched by rules without basic authentication
+		// with higher priority should not be checked
+		ruleCond := noAuthConds.Exclude(rule, cond)
+		if _, err := condition.Build(ruleCond); err != nil {
+			return fmt.Errorf("cond [%s] is illegal: %s", ruleCond, err)
+		}
+
+		fileName := userFileName(rule.secretName)
+		userFile, err := filepath.Abs(option.Opts.Ingress.ConfigPath + fileName)
+		if err != nil {
+			return err
+		}
+		userFiles[fileName] = c.authBasicRuleCache.users[rule.secretName]
+
+		for _, product := range c.products.Of(host) {
+			productRuleList[product] = append(productRuleList[product], mod_auth_basic.AuthBasicRuleFile{
+				Cond:     ruleCond,
+				UserFile: userFile,
+				Realm:    rule.authBasic.Realm,
+			})
+		}
+	}
+
+	// skip reloading BFE while no ingress uses mod_auth_basic, so it only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(*c.authBasicConfFile.Config) == 1 && len(*(*c.authBasicConfFile.Config)[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	authBasicConfFile := newAuthBasicConfFile(version)
+	for product, authBasicRuleList := range productRuleList {
+		authBasicRuleList := authBasicRuleList
+		(*authBasicConfFile.Config)[product] = &authBasicRuleList
+	}
+	if err := mod_auth_basic.AuthBasicConfCheck(*authBasicConfFile); err != nil {
+		return err
+	}
+
+	c.authBasicConfFile = authBasicConfFile
+	c.userFiles = userFiles
+	return nil
+}
+
+// userFileName returns the user file of the Secret, e.g. mod_auth_basic/users/default_dashboard-users
+func userFileName(secretName string) string {
+	namespace, name := util.SplitNamespacedName(secretName)
+	return fmt.Sprintf("%s%s_%s", UserFileDir, namespace, name)
+}
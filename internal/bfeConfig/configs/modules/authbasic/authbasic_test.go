@@ -0,0 +1,270 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package authbasic
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+const testUser = "admin:{SHA}0DPiKuNIrrVmD8IUCuw1hQxNqZc="
+
+func newSecret(name, users string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Data:       map[string][]byte{annotations.AuthBasicSecretKey: []byte(users)},
+	}
+}
+
+func TestUpdateAuthBasicConf(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	auth := map[string]string{
+		annotations.AuthBasicSecretAnnotation: "users",
+		annotations.AuthBasicRealmAnnotation:  "Dashboard",
+	}
+	secrets := []*corev1.Secret{newSecret("users", testUser)}
+
+	type request struct {
+		path string
+		want bool // whether the request is authenticated
+	}
+	tests := []struct {
+		name          string
+		ingresses     []*netv1.Ingress
+		secrets       []*corev1.Secret
+		wantErr       bool
+		wantRules     int
+		wantReload    bool
+		wantUserFiles map[string][]string
+		requests      []request
+	}{
+		{
+			name:          "no ingress uses basic authentication",
+			ingresses:     []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:     0,
+			wantReload:    false,
+			wantUserFiles: map[string][]string{},
+		},
+		{
+			name:       "basic authentication",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", auth, "foo.com", "/foo", "/bar")},
+			secrets:    secrets,
+			wantRules:  2,
+			wantReload: true,
+			wantUserFiles: map[string][]string{
+				UserFileDir + "default_users": {testUser},
+			},
+			requests: []request{
+				{path: "/foo", want: true},
+				{path: "/baz", want: false},
+			},
+		},
+		{
+			name:      "secret not found",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", auth, "foo.com", "/foo")},
+			wantErr:   true,
+		},
+		{
+			name:      "secret without users",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", auth, "foo.com", "/foo")},
+			secrets:   []*corev1.Secret{newSecret("users", "")},
+			wantErr:   true,
+		},
+		{
+			name: "requests of ingress without authentication with higher priority",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", auth, "foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo/public"),
+			},
+			secrets:    secrets,
+			wantRules:  1,
+			wantReload: true,
+			wantUserFiles: map[string][]string{
+				UserFileDir + "default_users": {testUser},
+			},
+			requests: []request{
+				{path: "/foo/private", want: true},
+				{path: "/foo/public", want: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewAuthBasicConfig("init", configs.NewProductTable())
+			var err error
+			for _, ingress := range tt.ingresses {
+				if err = c.UpdateIngressWithSecrets(ingress, tt.secrets); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateIngressWithSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := c.updateAuthBasicConf(); err != nil {
+				t.Fatalf("updateAuthBasicConf() error = %v", err)
+			}
+
+			rules := *(*c.authBasicConfFile.Config)[configs.DefaultProduct]
+			if got := len(rules); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := *c.authBasicConfFile.Version != c.version; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+			if !reflect.DeepEqual(c.userFiles, tt.wantUserFiles) {
+				t.Errorf("userFiles = %v, want %v", c.userFiles, tt.wantUserFiles)
+			}
+
+			for _, r := range tt.requests {
+				req := moduletest.NewRequest("foo.com", r.path, "10.0.0.1")
+				got := false
+				for _, rule := range rules {
+					if moduletest.Match(t, rule.Cond, req) {
+						got = rule.Realm == "Dashboard"
+						break
+					}
+				}
+				if got != r.want {
+					t.Errorf("request of %s: authenticated = %v, want %v", r.path, got, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateSecret(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	auth := map[string]string{annotations.AuthBasicSecretAnnotation: "users"}
+	tests := []struct {
+		name        string
+		secret      *corev1.Secret
+		wantErr     bool
+		wantUpdated bool
+	}{
+		{
+			name:        "secret not referenced",
+			secret:      newSecret("other", "user:{SHA}other"),
+			wantUpdated: false,
+		},
+		{
+			name:        "users not changed",
+			secret:      newSecret("users", testUser),
+			wantUpdated: false,
+		},
+		{
+			name:        "users changed",
+			secret:      newSecret("users", testUser+"\nguest:{SHA}guest"),
+			wantUpdated: true,
+		},
+		{
+			name:    "illegal users",
+			secret:  newSecret("users", "guest:plain"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewAuthBasicConfig("init", configs.NewProductTable())
+			ingress := moduletest.NewIngress("a", auth, "foo.com", "/foo")
+			if err := c.UpdateIngressWithSecrets(ingress, []*corev1.Secret{newSecret("users", testUser)}); err != nil {
+				t.Fatalf("UpdateIngressWithSecrets() error = %v", err)
+			}
+			version := c.authBasicRuleCache.Version
+
+			if err := c.UpdateSecret(tt.secret); (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := c.authBasicRuleCache.Version != version; got != tt.wantUpdated {
+				t.Errorf("updated = %v, want %v", got, tt.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestDeleteSecret(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	c := NewAuthBasicConfig("init", configs.NewProductTable())
+	auth := map[string]string{annotations.AuthBasicSecretAnnotation: "users"}
+	ingress := moduletest.NewIngress("a", auth, "foo.com", "/foo")
+	if err := c.UpdateIngressWithSecrets(ingress, []*corev1.Secret{newSecret("users", testUser)}); err != nil {
+		t.Fatalf("UpdateIngressWithSecrets() error = %v", err)
+	}
+	userFile := UserFileDir + "default_users"
+
+	steps := []struct {
+		name        string
+		action      func() error
+		wantUpdated bool
+		wantUsers   []string
+	}{
+		{
+			name:        "secret not referenced is deleted",
+			action:      func() error { c.DeleteSecret("default", "other"); return nil },
+			wantUpdated: false,
+			wantUsers:   []string{testUser},
+		},
+		{
+			name:        "referenced secret is deleted",
+			action:      func() error { c.DeleteSecret("default", "users"); return nil },
+			wantUpdated: true,
+			wantUsers:   []string{},
+		},
+		{
+			name:        "referenced secret is deleted again",
+			action:      func() error { c.DeleteSecret("default", "users"); return nil },
+			wantUpdated: false,
+			wantUsers:   []string{},
+		},
+		{
+			name:        "referenced secret is created again",
+			action:      func() error { return c.UpdateSecret(newSecret("users", testUser)) },
+			wantUpdated: true,
+			wantUsers:   []string{testUser},
+		},
+	}
+	for _, step := range steps {
+		version := c.authBasicRuleCache.Version
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		if got := c.authBasicRuleCache.Version != version; got != step.wantUpdated {
+			t.Errorf("%s: updated = %v, want %v", step.name, got, step.wantUpdated)
+		}
+
+		if err := c.updateAuthBasicConf(); err != nil {
+			t.Fatalf("%s: updateAuthBasicConf() error = %v", step.name, err)
+		}
+		// requests are still checked after the secret is deleted, and rejected since no user is left
+		if got := len(*(*c.authBasicConfFile.Config)[configs.DefaultProduct]); got != 1 {
+			t.Errorf("%s: rules = %d, want 1", step.name, got)
+		}
+		if got := c.userFiles[userFile]; !reflect.DeepEqual(got, step.wantUsers) {
+			t.Errorf("%s: users = %v, want %v", step.name, got, step.wantUsers)
+		}
+	}
+}
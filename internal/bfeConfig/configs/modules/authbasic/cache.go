@@ -0,0 +1,147 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authbasic is the module of basic authentication.
+// This file defines auth basic rule & cache's struct, also implements update ingress and secret methods.
+package authbasic
+
+import (
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+type authBasicRule struct {
+	*cache.BaseRule
+	// authBasic is nil if the ingress has no basic authentication, such rule stops rules with lower priority
+	authBasic *annotations.AuthBasic
+	// secretName is the namespaced name of the Secret which contains users
+	secretName string
+}
+
+type authBasicRuleCache struct {
+	*cache.BaseCache
+	// users of Secrets referenced by ingresses, namespaced name of Secret => lines in htpasswd format
+	users map[string][]string
+}
+
+func newAuthBasicRuleCache(version string) *authBasicRuleCache {
+	return &authBasicRuleCache{
+		BaseCache: cache.NewBaseCache(version),
+		users:     make(map[string][]string),
+	}
+}
+
+func (c *authBasicRuleCache) UpdateByIngress(ingress *netv1.Ingress, secrets []*corev1.Secret) error {
+	authBasic, err := annotations.GetAuthBasic(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+
+	var secretName string
+	if authBasic != nil {
+		secretName = util.NamespacedName(ingress.Namespace, authBasic.Secret)
+		secret := findSecret(secrets, ingress.Namespace, authBasic.Secret)
+		if secret == nil {
+			return fmt.Errorf("secret %s not found", secretName)
+		}
+		users, err := parseUsers(secret)
+		if err != nil {
+			return err
+		}
+		c.users[secretName] = users
+	}
+
+	// rules of ingresses without basic authentication are also cached, since mod_auth_basic stops at the first matched rule
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &authBasicRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				authBasic:  authBasic,
+				secretName: secretName,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
+
+// UpdateSecret updates users if the Secret is referenced by ingresses
+func (c *authBasicRuleCache) UpdateSecret(secret *corev1.Secret) error {
+	secretName := util.NamespacedName(secret.Namespace, secret.Name)
+	current, ok := c.users[secretName]
+	if !ok {
+		return nil
+	}
+
+	users, err := parseUsers(secret)
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(users, current) {
+		return nil
+	}
+
+	c.users[secretName] = users
+	c.Version = util.NewVersion()
+	return nil
+}
+
+// DeleteSecret removes all users if the Secret is referenced by ingresses, so no request is authenticated
+func (c *authBasicRuleCache) DeleteSecret(namespace, name string) {
+	secretName := util.NamespacedName(namespace, name)
+	current, ok := c.users[secretName]
+	if !ok || len(current) == 0 {
+		return
+	}
+
+	c.users[secretName] = []string{}
+	c.Version = util.NewVersion()
+}
+
+func parseUsers(secret *corev1.Secret) ([]string, error) {
+	secretName := util.NamespacedName(secret.Namespace, secret.Name)
+	data, ok := secret.Data[annotations.AuthBasicSecretKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s is illegal, key %s not found", secretName, annotations.AuthBasicSecretKey)
+	}
+
+	users, err := annotations.ParseHtpasswd(data)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s is illegal, %s", secretName, err)
+	}
+	return users, nil
+}
+
+func findSecret(secrets []*corev1.Secret, namespace, name string) *corev1.Secret {
+	for _, secret := range secrets {
+		if secret.Namespace == namespace && secret.Name == name {
+			return secret
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authjwt is the module of JWT authentication.
+// This file implements operate rule cache, generate and reload config file methods.
+package authjwt
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_auth_jwt"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+const (
+	ConfigNameAuthJWT = "mod_auth_jwt"
+	RuleData          = "mod_auth_jwt/auth_jwt_rule.data"
+	// KeyFileDir is the directory of key files, one file for each Secret or ConfigMap
+	KeyFileDir = "mod_auth_jwt/keys/"
+)
+
+type ModAuthJWTConfig struct {
+	version          string
+	products         *configs.ProductTable
+	authJWTRuleCache *authJWTRuleCache
+	authJWTConfFile  *mod_auth_jwt.AuthJWTConfFile
+	// keyFiles are the key files referenced by authJWTConfFile, file name => content
+	keyFiles map[string][]byte
+	// dumpedKeyFiles are the key files on the disk
+	dumpedKeyFiles map[string]bool
+}
+
+func NewAuthJWTConfig(version string, products *configs.ProductTable) *ModAuthJWTConfig {
+	return &ModAuthJWTConfig{
+		version:          version,
+		products:         products,
+		authJWTRuleCache: newAuthJWTRuleCache(version),
+		authJWTConfFile:  newAuthJWTConfFile(version),
+		keyFiles:         make(map[string][]byte),
+		dumpedKeyFiles:   make(map[string]bool),
+	}
+}
+
+func newAuthJWTConfFile(version string) *mod_auth_jwt.AuthJWTConfFile {
+	ruleList := make(mod_auth_jwt.RuleFileList, 0)
+	productRuleList := mod_auth_jwt.ProductRulesFile{
+		configs.DefaultProduct: &ruleList,
+	}
+	return &mod_auth_jwt.AuthJWTConfFile{
+		Version: &version,
+		Config:  &productRuleList,
+	}
+}
+
+func (c *ModAuthJWTConfig) Name() string {
+	return ConfigNameAuthJWT
+}
+
+func (c *ModAuthJWTConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	return c.UpdateIngressWithReferences(ingress, nil, nil)
+}
+
+// UpdateIngressWithSecrets implements modules.SecretReferrer
+func (c *ModAuthJWTConfig) UpdateIngressWithSecrets(ingress *netv1.Ingress, secrets []*corev1.Secret) error {
+	return c.UpdateIngressWithReferences(ingress, nil, secrets)
+}
+
+// UpdateIngressWithReferences implements modules.ConfigMapSecretReferrer
+func (c *ModAuthJWTConfig) UpdateIngressWithReferences(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap, secrets []*corev1.Secret) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.authJWTRuleCache.ContainsIngress(ingressName) {
+		c.authJWTRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.authJWTRuleCache.UpdateByIngress(ingress, configMaps, secrets)
+}
+
+func (c *ModAuthJWTConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.authJWTRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.authJWTRuleCache.DeleteByIngress(ingressName)
+}
+
+// UpdateSecret implements modules.SecretReferrer
+func (c *ModAuthJWTConfig) UpdateSecret(secret *corev1.Secret) error {
+	return c.authJWTRuleCache.UpdateSecret(secret)
+}
+
+// DeleteSecret implements modules.SecretReferrer
+func (c *ModAuthJWTConfig) DeleteSecret(namespace, name string) {
+	c.authJWTRuleCache.DeleteSecret(namespace, name)
+}
+
+func (c *ModAuthJWTConfig) Reload() error {
+	if err := c.updateAuthJWTConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.authJWTConfFile.Version != c.version {
+		// dump key files, which are loaded together with the config file
+		if err := c.dumpKeyFiles(); err != nil {
+			return err
+		}
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.authJWTConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameAuthJWT)
+		if err != nil {
+			return err
+		}
+		c.version = *c.authJWTConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModAuthJWTConfig) dumpKeyFiles() error {
+	for fileName, keySet := range c.keyFiles {
+		if err := util.DumpFile(fileName, keySet); err != nil {
+			return fmt.Errorf("dump %s error: %v", fileName, err)
+		}
+		c.dumpedKeyFiles[fileName] = true
+	}
+
+	// delete files of Secrets and ConfigMaps which are no longer referenced
+	for fileName := range c.dumpedKeyFiles {
+		if _, ok := c.keyFiles[fileName]; !ok {
+			util.DeleteFile(fileName)
+			delete(c.dumpedKeyFiles, fileName)
+		}
+	}
+	return nil
+}
+
+func (c *ModAuthJWTConfig) updateAuthJWTConf() error {
+	version := c.products.ConfVersion(c.authJWTRuleCache.Version)
+	if *c.authJWTConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.authJWTRuleCache.GetRules()
+	productRuleList := make(map[string]mod_auth_jwt.RuleFileList)
+	keyFiles := make(map[string][]byte)
+	// conditions of rules without JWT authentication
+	var noAuthConds cache.PriorityConds
+	for _, rule := range ruleList {
+		rule := rule.(*authJWTRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+		if len(cond) == 0 {
+			cond = "default_t()"
+		}
+
+		host := rule.GetHost()
+		if rule.authJWT == nil {
+			noAuthConds.Add(rule, cond)
+			continue
+		}
+
+		// mod_auth_jwt stops at the first matched rule, but requests matched by rules without JWT authentication
+		// with higher priority should not be checked
+		ruleCond := noAuthConds.Exclude(rule, cond)
+		if _, err := condition.Build(ruleCond); err != nil {
+			return fmt.Errorf("cond [%s] is illegal: %s", ruleCond, err)
+		}
+
+		fileName := KeyFileDir + rule.keySetName
+		keyFile, err := filepath.Abs(option.Opts.Ingress.ConfigPath + fileName)
+		if err != nil {
+			return err
+		}
+		keyFiles[fileName] = c.authJWTRuleCache.keySets[rule.keySetName]
+
+		for _, product := range c.products.Of(host) {
+			productRuleList[product] = append(productRuleList[product], mod_auth_jwt.AuthJWTRuleFile{
+				Cond:    ruleCond,
+				KeyFile: keyFile,
+				Realm:   rule.authJWT.Realm,
+			})
+		}
+	}
+
+	// skip reloading BFE while no ingress uses mod_auth_jwt, so it only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(*c.authJWTConfFile.Config) == 1 && len(*(*c.authJWTConfFile.Config)[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	authJWTConfFile := newAuthJWTConfFile(version)
+	for product, authJWTRuleList := range productRuleList {
+		authJWTRuleList := authJWTRuleList
+		(*authJWTConfFile.Config)[product] = &authJWTRuleList
+	}
+	if err := mod_auth_jwt.AuthJWTConfCheck(*authJWTConfFile); err != nil {
+		return err
+	}
+
+	c.authJWTConfFile = authJWTConfFile
+	c.keyFiles = keyFiles
+	return nil
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package authjwt
+
+import (
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+const testKeySet = `{"keys": [{"kty": "oct", "k": "c2VjcmV0"}]}`
+
+func TestUpdateAuthJWTConf(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	secretAuth := map[string]string{
+		annotations.AuthJWTSecretAnnotation: "jwks",
+		annotations.AuthJWTRealmAnnotation:  "API",
+	}
+	configMapAuth := map[string]string{
+		annotations.AuthJWTConfigMapAnnotation: "jwks",
+		annotations.AuthJWTRealmAnnotation:     "API",
+	}
+	secrets := []*corev1.Secret{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "jwks"},
+		Data:       map[string][]byte{annotations.AuthJWTKeySetKey: []byte(testKeySet)},
+	}}
+	configMaps := []*corev1.ConfigMap{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "jwks"},
+		Data:       map[string]string{annotations.AuthJWTKeySetKey: testKeySet},
+	}}
+
+	type request struct {
+		path string
+		want bool // whether the request is authenticated
+	}
+	tests := []struct {
+		name         string
+		ingresses    []*netv1.Ingress
+		configMaps   []*corev1.ConfigMap
+		secrets      []*corev1.Secret
+		wantErr      bool
+		wantRules    int
+		wantReload   bool
+		wantKeyFiles []string
+		requests     []request
+	}{
+		{
+			name:       "no ingress uses JWT authentication",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:         "key set in secret",
+			ingresses:    []*netv1.Ingress{moduletest.NewIngress("a", secretAuth, "foo.com", "/foo", "/bar")},
+			secrets:      secrets,
+			wantRules:    2,
+			wantReload:   true,
+			wantKeyFiles: []string{KeyFileDir + "secret_default_jwks"},
+			requests: []request{
+				{path: "/foo", want: true},
+				{path: "/baz", want: false},
+			},
+		},
+		{
+			name:         "key set in configmap",
+			ingresses:    []*netv1.Ingress{moduletest.NewIngress("a", configMapAuth, "foo.com", "/foo")},
+			configMaps:   configMaps,
+			wantRules:    1,
+			wantReload:   true,
+			wantKeyFiles: []string{KeyFileDir + "configmap_default_jwks"},
+			requests: []request{
+				{path: "/foo", want: true},
+			},
+		},
+		{
+			name:      "secret not found",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", secretAuth, "foo.com", "/foo")},
+			wantErr:   true,
+		},
+		{
+			name:      "configmap without key set",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", configMapAuth, "foo.com", "/foo")},
+			configMaps: []*corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "jwks"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "requests of ingress without authentication with higher priority",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", secretAuth, "foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo/public"),
+			},
+			secrets:      secrets,
+			wantRules:    1,
+			wantReload:   true,
+			wantKeyFiles: []string{KeyFileDir + "secret_default_jwks"},
+			requests: []request{
+				{path: "/foo/private", want: true},
+				{path: "/foo/public", want: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewAuthJWTConfig("init", configs.NewProductTable())
+			var err error
+			for _, ingress := range tt.ingresses {
+				if err = c.UpdateIngressWithReferences(ingress, tt.configMaps, tt.secrets); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateIngressWithReferences() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := c.updateAuthJWTConf(); err != nil {
+				t.Fatalf("updateAuthJWTConf() error = %v", err)
+			}
+
+			rules := *(*c.authJWTConfFile.Config)[configs.DefaultProduct]
+			if got := len(rules); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := *c.authJWTConfFile.Version != c.version; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+			if len(c.keyFiles) != len(tt.wantKeyFiles) {
+				t.Errorf("keyFiles = %v, want %v", c.keyFiles, tt.wantKeyFiles)
+			}
+			for _, fileName := range tt.wantKeyFiles {
+				if len(c.keyFiles[fileName]) == 0 {
+					t.Errorf("key file %s not found", fileName)
+				}
+			}
+
+			for _, r := range tt.requests {
+				req := moduletest.NewRequest("foo.com", r.path, "10.0.0.1")
+				got := false
+				for _, rule := range rules {
+					if moduletest.Match(t, rule.Cond, req) {
+						got = rule.Realm == "API"
+						break
+					}
+				}
+				if got != r.want {
+					t.Errorf("request of %s: authenticated = %v, want %v", r.path, got, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateSecret(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	newSecret := func(name, keySet string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Data:       map[string][]byte{annotations.AuthJWTKeySetKey: []byte(keySet)},
+		}
+	}
+	rotatedKeySet := `{"keys": [{"kty": "oct", "kid": "new", "k": "bmV3LXNlY3JldA"}, {"kty": "oct", "kid": "old", "k": "c2VjcmV0"}]}`
+
+	auth := map[string]string{annotations.AuthJWTSecretAnnotation: "jwks"}
+	tests := []struct {
+		name        string
+		secret      *corev1.Secret
+		wantErr     bool
+		wantUpdated bool
+		wantKeys    int
+	}{
+		{
+			name:        "secret not referenced",
+			secret:      newSecret("other", rotatedKeySet),
+			wantUpdated: false,
+			wantKeys:    1,
+		},
+		{
+			name:        "key set not changed",
+			secret:      newSecret("jwks", testKeySet),
+			wantUpdated: false,
+			wantKeys:    1,
+		},
+		{
+			name:        "keys rotated",
+			secret:      newSecret("jwks", rotatedKeySet),
+			wantUpdated: true,
+			wantKeys:    2,
+		},
+		{
+			name:     "illegal key set",
+			secret:   newSecret("jwks", `{"keys": []}`),
+			wantErr:  true,
+			wantKeys: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewAuthJWTConfig("init", configs.NewProductTable())
+			ingress := moduletest.NewIngress("a", auth, "foo.com", "/foo")
+			if err := c.UpdateIngressWithReferences(ingress, nil, []*corev1.Secret{newSecret("jwks", testKeySet)}); err != nil {
+				t.Fatalf("UpdateIngressWithReferences() error = %v", err)
+			}
+			version := c.authJWTRuleCache.Version
+
+			if err := c.UpdateSecret(tt.secret); (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := c.authJWTRuleCache.Version != version; got != tt.wantUpdated {
+				t.Errorf("updated = %v, want %v", got, tt.wantUpdated)
+			}
+
+			if err := c.updateAuthJWTConf(); err != nil {
+				t.Fatalf("updateAuthJWTConf() error = %v", err)
+			}
+			var keys []json.RawMessage
+			if err := json.Unmarshal(c.keyFiles[KeyFileDir+"secret_default_jwks"], &keys); err != nil {
+				t.Fatalf("key file is illegal: %v", err)
+			}
+			if len(keys) != tt.wantKeys {
+				t.Errorf("keys = %d, want %d", len(keys), tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestDeleteSecret(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	c := NewAuthJWTConfig("init", configs.NewProductTable())
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "jwks"},
+		Data:       map[string][]byte{annotations.AuthJWTKeySetKey: []byte(testKeySet)},
+	}
+	auth := map[string]string{annotations.AuthJWTSecretAnnotation: "jwks"}
+	ingress := moduletest.NewIngress("a", auth, "foo.com", "/foo")
+	if err := c.UpdateIngressWithReferences(ingress, nil, []*corev1.Secret{secret}); err != nil {
+		t.Fatalf("UpdateIngressWithReferences() error = %v", err)
+	}
+	keyFile := KeyFileDir + "secret_default_jwks"
+
+	steps := []struct {
+		name        string
+		action      func() error
+		wantUpdated bool
+		wantKeys    int
+	}{
+		{
+			name:        "secret not referenced is deleted",
+			action:      func() error { c.DeleteSecret("default", "other"); return nil },
+			wantUpdated: false,
+			wantKeys:    1,
+		},
+		{
+			name:        "referenced secret is deleted",
+			action:      func() error { c.DeleteSecret("default", "jwks"); return nil },
+			wantUpdated: true,
+			wantKeys:    0,
+		},
+		{
+			name:        "referenced secret is deleted again",
+			action:      func() error { c.DeleteSecret("default", "jwks"); return nil },
+			wantUpdated: false,
+			wantKeys:    0,
+		},
+		{
+			name:        "referenced secret is created again",
+			action:      func() error { return c.UpdateSecret(secret) },
+			wantUpdated: true,
+			wantKeys:    1,
+		},
+	}
+	for _, step := range steps {
+		version := c.authJWTRuleCache.Version
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		if got := c.authJWTRuleCache.Version != version; got != step.wantUpdated {
+			t.Errorf("%s: updated = %v, want %v", step.name, got, step.wantUpdated)
+		}
+
+		if err := c.updateAuthJWTConf(); err != nil {
+			t.Fatalf("%s: updateAuthJWTConf() error = %v", step.name, err)
+		}
+		// requests are still checked after the secret is deleted, and rejected since no key is left
+		if got := len(*(*c.authJWTConfFile.Config)[configs.DefaultProduct]); got != 1 {
+			t.Errorf("%s: rules = %d, want 1", step.name, got)
+		}
+		var keys []json.RawMessage
+		if err := json.Unmarshal(c.keyFiles[keyFile], &keys); err != nil {
+			t.Fatalf("%s: key file is illegal: %v", step.name, err)
+		}
+		if len(keys) != step.wantKeys {
+			t.Errorf("%s: keys = %d, want %d", step.name, len(keys), step.wantKeys)
+		}
+	}
+}
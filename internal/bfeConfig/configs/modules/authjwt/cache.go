@@ -0,0 +1,169 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authjwt is the module of JWT authentication.
+// This file defines auth jwt rule & cache's struct, also implements update ingress and secret methods.
+package authjwt
+
+import (
+	"bytes"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+// kinds of the object which contains the JWK set
+const (
+	kindSecret    = "secret"
+	kindConfigMap = "configmap"
+)
+
+// emptyKeySet is the content of the key file after the Secret is deleted, which rejects all tokens
+var emptyKeySet = []byte("[]")
+
+type authJWTRule struct {
+	*cache.BaseRule
+	// authJWT is nil if the ingress has no JWT authentication, such rule stops rules with lower priority
+	authJWT *annotations.AuthJWT
+	// keySetName identifies the object which contains the JWK set, e.g. secret_default_jwks
+	keySetName string
+}
+
+type authJWTRuleCache struct {
+	*cache.BaseCache
+	// key sets in objects referenced by ingresses, key set name => content of the key file
+	keySets map[string][]byte
+}
+
+func newAuthJWTRuleCache(version string) *authJWTRuleCache {
+	return &authJWTRuleCache{
+		BaseCache: cache.NewBaseCache(version),
+		keySets:   make(map[string][]byte),
+	}
+}
+
+func (c *authJWTRuleCache) UpdateByIngress(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap, secrets []*corev1.Secret) error {
+	authJWT, err := annotations.GetAuthJWT(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+
+	var keySetName string
+	if authJWT != nil {
+		var keySet []byte
+		if len(authJWT.Secret) > 0 {
+			keySetName = newKeySetName(kindSecret, ingress.Namespace, authJWT.Secret)
+			keySet, err = getSecretKeySet(secrets, ingress.Namespace, authJWT.Secret)
+		} else {
+			keySetName = newKeySetName(kindConfigMap, ingress.Namespace, authJWT.ConfigMap)
+			keySet, err = getConfigMapKeySet(configMaps, ingress.Namespace, authJWT.ConfigMap)
+		}
+		if err != nil {
+			return err
+		}
+		c.keySets[keySetName] = keySet
+	}
+
+	// rules of ingresses without JWT authentication are also cached, since mod_auth_jwt stops at the first matched rule
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &authJWTRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				authJWT:    authJWT,
+				keySetName: keySetName,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
+
+// UpdateSecret updates the key set if the Secret is referenced by ingresses
+func (c *authJWTRuleCache) UpdateSecret(secret *corev1.Secret) error {
+	keySetName := newKeySetName(kindSecret, secret.Namespace, secret.Name)
+	current, ok := c.keySets[keySetName]
+	if !ok {
+		return nil
+	}
+
+	keySet, err := parseKeySet(kindSecret, secret.Namespace, secret.Name, secret.Data[annotations.AuthJWTKeySetKey])
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(keySet, current) {
+		return nil
+	}
+
+	c.keySets[keySetName] = keySet
+	c.Version = util.NewVersion()
+	return nil
+}
+
+// DeleteSecret removes all keys if the Secret is referenced by ingresses, so no token is valid
+func (c *authJWTRuleCache) DeleteSecret(namespace, name string) {
+	keySetName := newKeySetName(kindSecret, namespace, name)
+	current, ok := c.keySets[keySetName]
+	if !ok || bytes.Equal(current, emptyKeySet) {
+		return
+	}
+
+	c.keySets[keySetName] = emptyKeySet
+	c.Version = util.NewVersion()
+}
+
+func getSecretKeySet(secrets []*corev1.Secret, namespace, name string) ([]byte, error) {
+	for _, secret := range secrets {
+		if secret.Namespace == namespace && secret.Name == name {
+			return parseKeySet(kindSecret, namespace, name, secret.Data[annotations.AuthJWTKeySetKey])
+		}
+	}
+	return nil, fmt.Errorf("secret %s not found", util.NamespacedName(namespace, name))
+}
+
+func getConfigMapKeySet(configMaps []*corev1.ConfigMap, namespace, name string) ([]byte, error) {
+	for _, configMap := range configMaps {
+		if configMap.Namespace == namespace && configMap.Name == name {
+			return parseKeySet(kindConfigMap, namespace, name, []byte(configMap.Data[annotations.AuthJWTKeySetKey]))
+		}
+	}
+	return nil, fmt.Errorf("configmap %s not found", util.NamespacedName(namespace, name))
+}
+
+func parseKeySet(kind, namespace, name string, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s %s is illegal, key %s not found", kind, util.NamespacedName(namespace, name), annotations.AuthJWTKeySetKey)
+	}
+
+	keySet, err := annotations.ParseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s is illegal, %s", kind, util.NamespacedName(namespace, name), err)
+	}
+	return keySet, nil
+}
+
+func newKeySetName(kind, namespace, name string) string {
+	return fmt.Sprintf("%s_%s_%s", kind, namespace, name)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authrequest is the module of external authorization.
+// This file implements operate rule cache, generate and reload config file methods.
+package authrequest
+
+import (
+	"fmt"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_auth_request"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+const (
+	ConfigNameAuthRequest = "mod_auth_request"
+	RuleData              = "mod_auth_request/auth_request_rule.data"
+)
+
+type ModAuthRequestConfig struct {
+	version              string
+	products             *configs.ProductTable
+	authRequestRuleCache *authRequestRuleCache
+	authRequestConfFile  *mod_auth_request.AuthRequestRuleFile
+}
+
+func NewAuthRequestConfig(version string, products *configs.ProductTable) *ModAuthRequestConfig {
+	return &ModAuthRequestConfig{
+		version:              version,
+		products:             products,
+		authRequestRuleCache: newAuthRequestRuleCache(version),
+		authRequestConfFile:  newAuthRequestConfFile(version),
+	}
+}
+
+func newAuthRequestConfFile(version string) *mod_auth_request.AuthRequestRuleFile {
+	return &mod_auth_request.AuthRequestRuleFile{
+		Version: version,
+		Config: mod_auth_request.ProductRuleRawList{
+			configs.DefaultProduct: make(mod_auth_request.RuleRawList, 0),
+		},
+	}
+}
+
+func (c *ModAuthRequestConfig) Name() string {
+	return ConfigNameAuthRequest
+}
+
+func (c *ModAuthRequestConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.authRequestRuleCache.ContainsIngress(ingressName) {
+		c.authRequestRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.authRequestRuleCache.UpdateByIngress(ingress)
+}
+
+func (c *ModAuthRequestConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.authRequestRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.authRequestRuleCache.DeleteByIngress(ingressName)
+}
+
+func (c *ModAuthRequestConfig) Reload() error {
+	if err := c.updateAuthRequestConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if c.authRequestConfFile.Version != c.version {
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.authRequestConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameAuthRequest)
+		if err != nil {
+			return err
+		}
+		c.version = c.authRequestConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModAuthRequestConfig) updateAuthRequestConf() error {
+	version := c.products.ConfVersion(c.authRequestRuleCache.Version)
+	if c.authRequestConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.authRequestRuleCache.GetRules()
+	productRuleList := make(mod_auth_request.ProductRuleRawList)
+	// conditions of rules with higher priority
+	var higherConds cache.PriorityConds
+	for _, rule := range ruleList {
+		rule := rule.(*authRequestRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+		if len(cond) == 0 {
+			cond = "default_t()"
+		}
+
+		// mod_auth_request checks all matched rules, so a request should only match the rule with the highest priority
+		host := rule.GetHost()
+		ruleCond := higherConds.Exclude(rule, cond)
+		higherConds.Add(rule, cond)
+		if !rule.enable {
+			continue
+		}
+
+		if _, err := condition.Build(ruleCond); err != nil {
+			return fmt.Errorf("cond [%s] is illegal: %s", ruleCond, err)
+		}
+		for _, product := range c.products.Of(host) {
+			productRuleList[product] = append(productRuleList[product], mod_auth_request.AuthRequestRuleRaw{
+				Cond:   ruleCond,
+				Enable: true,
+			})
+		}
+	}
+
+	// skip reloading BFE while no ingress uses mod_auth_request, so it only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(c.authRequestConfFile.Config) == 1 && len(c.authRequestConfFile.Config[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	authRequestConfFile := newAuthRequestConfFile(version)
+	for product, authRequestRuleList := range productRuleList {
+		authRequestConfFile.Config[product] = authRequestRuleList
+	}
+	if err := mod_auth_request.AuthRequestRuleCheck(authRequestConfFile); err != nil {
+		return err
+	}
+
+	c.authRequestConfFile = authRequestConfFile
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package authrequest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/baidu/go-lib/web-monitor/web_monitor"
+	"github.com/bfenetworks/bfe/bfe_module"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_auth_request"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+// authService allows requests with token "Bearer test", and records requests sent by mod_auth_request
+type authService struct {
+	lock     sync.Mutex
+	requests []*http.Request
+}
+
+func (s *authService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.requests = append(s.requests, r)
+	s.lock.Unlock()
+	if r.Header.Get("Authorization") != "Bearer test" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+// lastRequest returns the last request received since the last call, nil if no request is received
+func (s *authService) lastRequest() *http.Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.requests) == 0 {
+		return nil
+	}
+	r := s.requests[len(s.requests)-1]
+	s.requests = nil
+	return r
+}
+
+func TestUpdateAuthRequestConf(t *testing.T) {
+	opts := moduletest.SetOptions(t)
+
+	service := &authService{}
+	server := httptest.NewServer(service)
+	defer server.Close()
+	authURL := server.URL + "/check"
+	auth := map[string]string{annotations.AuthURLAnnotation: authURL}
+
+	type request struct {
+		host     string
+		uri      string
+		token    string
+		checked  bool // whether the request is sent to the auth service
+		wantCode int  // status code of the response of mod_auth_request, 0 if the request is allowed
+	}
+	tests := []struct {
+		name       string
+		authURL    string // set by --auth-url of the controller
+		ingresses  []*netv1.Ingress
+		wantErr    bool
+		wantRules  int
+		wantReload bool
+		requests   []request
+	}{
+		{
+			name:       "no ingress uses external authorization",
+			authURL:    authURL,
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "external authorization",
+			authURL:    authURL,
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", auth, "foo.com", "/foo", "/bar")},
+			wantRules:  2,
+			wantReload: true,
+			requests: []request{
+				{host: "foo.com", uri: "/foo?id=1", token: "Bearer test", checked: true, wantCode: 0},
+				{host: "foo.com", uri: "/bar/baz", token: "Bearer other", checked: true, wantCode: http.StatusUnauthorized},
+				{host: "foo.com", uri: "/baz", checked: false, wantCode: 0},
+			},
+		},
+		{
+			name:      "external authorization disabled",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", auth, "foo.com", "/foo")},
+			wantErr:   true,
+		},
+		{
+			name:      "auth service not set by the controller",
+			authURL:   "http://other.example.com/check",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", auth, "foo.com", "/foo")},
+			wantErr:   true,
+		},
+		{
+			name:    "requests of ingress without external authorization with higher priority",
+			authURL: authURL,
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", auth, "foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo/bar"),
+			},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{host: "foo.com", uri: "/foo/bar", checked: false, wantCode: 0},
+				{host: "foo.com", uri: "/foo/baz", checked: true, wantCode: http.StatusUnauthorized},
+			},
+		},
+		{
+			name:    "requests of ingress without external authorization with higher priority on overlapping host",
+			authURL: authURL,
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", auth, "*.foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "a.foo.com", "/foo"),
+			},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{host: "a.foo.com", uri: "/foo", checked: false, wantCode: 0},
+				{host: "b.foo.com", uri: "/foo", checked: true, wantCode: http.StatusUnauthorized},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bfe := moduletest.NewBfe(t, opts)
+			opts.Ingress.AuthURL = tt.authURL
+
+			c := NewAuthRequestConfig("init", configs.NewProductTable())
+			var err error
+			for _, ingress := range tt.ingresses {
+				if err = c.UpdateIngress(ingress); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateIngress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := c.Reload(); err != nil {
+				t.Fatalf("Reload() error = %v", err)
+			}
+
+			if got := len(c.authRequestConfFile.Config[configs.DefaultProduct]); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := len(bfe.Reloads()) > 0; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+			if len(tt.requests) == 0 {
+				return
+			}
+
+			// requests are checked by mod_auth_request with the dumped config file
+			handlers := loadModAuthRequest(t, opts.Ingress.ConfigPath)
+			for _, r := range tt.requests {
+				req := moduletest.NewRequest(r.host, "", "10.0.0.1")
+				req.HttpRequest.Method = http.MethodPost
+				req.HttpRequest.URL, _ = url.Parse(r.uri)
+				req.HttpRequest.Header.Set("Authorization", r.token)
+				req.HttpRequest.Header.Set("Connection", "keep-alive")
+				req.Route.Product = configs.DefaultProduct
+
+				gotCode := 0
+				if ret, resp := handlers.FilterRequest(req); ret == bfe_module.BfeHandlerResponse {
+					gotCode = resp.StatusCode
+				}
+				if gotCode != r.wantCode {
+					t.Errorf("request of %s%s: code = %d, want %d", r.host, r.uri, gotCode, r.wantCode)
+				}
+
+				authReq := service.lastRequest()
+				if got := authReq != nil; got != r.checked {
+					t.Errorf("request of %s%s: checked = %v, want %v", r.host, r.uri, got, r.checked)
+				}
+				if authReq == nil {
+					continue
+				}
+				// the request is sent to the URL set by --auth-url, with headers of the original request
+				headers := map[string]string{
+					mod_auth_request.XForwardedMethod: http.MethodPost,
+					mod_auth_request.XForwardedURI:    r.uri,
+					"Authorization":                   r.token,
+					"Connection":                      "",
+				}
+				if authReq.Method != http.MethodGet || authReq.URL.Path != "/check" {
+					t.Errorf("request of %s%s: auth request = %s %s, want GET /check", r.host, r.uri, authReq.Method, authReq.URL.Path)
+				}
+				for key, want := range headers {
+					if got := authReq.Header.Get(key); got != want {
+						t.Errorf("request of %s%s: header %s of auth request = %q, want %q", r.host, r.uri, key, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// loadModAuthRequest initializes mod_auth_request with the config files in confRoot, and returns its handlers.
+// The auth service is set by the controller when bfe starts, same as enableAuthRequest does.
+func loadModAuthRequest(t *testing.T, confRoot string) *bfe_module.HandlerList {
+	t.Helper()
+	conf := "[Basic]\nDataPath = " + RuleData + "\nAuthAddress = " + option.Opts.Ingress.AuthURL + "\nAuthTimeout = 1000\n"
+	if err := ioutil.WriteFile(confRoot+"mod_auth_request/mod_auth_request.conf", []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := mod_auth_request.NewModuleAuthRequest()
+	cbs := bfe_module.NewBfeCallbacks()
+	if err := m.Init(cbs, web_monitor.NewWebHandlers(), confRoot); err != nil {
+		t.Fatalf("mod_auth_request Init() error = %v", err)
+	}
+	return cbs.GetHandlerList(bfe_module.HandleFoundProduct)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authrequest is the module of external authorization.
+// This file defines auth request rule & cache's struct, also implements update ingress method.
+package authrequest
+
+import (
+	"fmt"
+
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+type authRequestRule struct {
+	*cache.BaseRule
+	// enable is false if the ingress has no external authorization, such rule stops rules with lower priority
+	enable bool
+}
+
+type authRequestRuleCache struct {
+	*cache.BaseCache
+}
+
+func newAuthRequestRuleCache(version string) *authRequestRuleCache {
+	return &authRequestRuleCache{
+		BaseCache: cache.NewBaseCache(version),
+	}
+}
+
+func (c authRequestRuleCache) UpdateByIngress(ingress *netv1.Ingress) error {
+	authURL, err := annotations.GetAuthURL(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+
+	// the auth service is set in mod_auth_request.conf when bfe starts
+	if len(authURL) > 0 && authURL != option.Opts.Ingress.AuthURL {
+		if len(option.Opts.Ingress.AuthURL) == 0 {
+			return fmt.Errorf("annotation %s is illegal, external authorization is disabled, start the controller with --auth-url=%s to enable it", annotations.AuthURLAnnotation, authURL)
+		}
+		return fmt.Errorf("annotation %s is illegal, only %s set by --auth-url of the controller is supported", annotations.AuthURLAnnotation, option.Opts.Ingress.AuthURL)
+	}
+
+	// rules of ingresses without external authorization are also cached, since requests matched by them should not be checked
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &authRequestRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				enable: len(authURL) > 0,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
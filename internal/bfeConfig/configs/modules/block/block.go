@@ -0,0 +1,319 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package block is the module of IP whitelist and blacklist.
+// This file implements operate rule cache, generate and reload config file methods.
+package block
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_block"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+const (
+	ConfigNameBlock = "mod_block"
+	RuleData        = "mod_block/block_rules.data"
+	IPBlocklistData = "mod_block/ip_blocklist.data"
+
+	// names of the reload handlers of mod_block
+	reloadNameRule        = ConfigNameBlock + ".product_rule_table"
+	reloadNameIPBlocklist = ConfigNameBlock + ".global_ip_table"
+)
+
+const (
+	actionClose = "CLOSE"
+	actionAllow = "ALLOW"
+)
+
+// blockRuleFile is the rule of mod_block, which is not exported by mod_block
+type blockRuleFile struct {
+	Cond   *string
+	Name   *string
+	Action *mod_block.ActionFile
+}
+
+type blockRuleFileList []blockRuleFile
+
+type blockConfFile struct {
+	Version *string
+	Config  *map[string]*blockRuleFileList
+}
+
+type ModBlockConfig struct {
+	version            string
+	products           *configs.ProductTable
+	ipBlocklistVersion string
+	blockRuleCache     *blockRuleCache
+	blockConfFile      *blockConfFile
+}
+
+func NewBlockConfig(version string, products *configs.ProductTable) *ModBlockConfig {
+	return &ModBlockConfig{
+		version:            version,
+		products:           products,
+		ipBlocklistVersion: version,
+		blockRuleCache:     newBlockRuleCache(version),
+		blockConfFile:      newBlockConfFile(version),
+	}
+}
+
+func newBlockConfFile(version string) *blockConfFile {
+	ruleList := make(blockRuleFileList, 0)
+	productRuleList := map[string]*blockRuleFileList{
+		configs.DefaultProduct: &ruleList,
+	}
+	return &blockConfFile{
+		Version: &version,
+		Config:  &productRuleList,
+	}
+}
+
+func (c *ModBlockConfig) Name() string {
+	return ConfigNameBlock
+}
+
+func (c *ModBlockConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	return c.UpdateIngressWithConfigMaps(ingress, nil)
+}
+
+// UpdateIngressWithConfigMaps implements modules.ConfigMapReferrer
+func (c *ModBlockConfig) UpdateIngressWithConfigMaps(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.blockRuleCache.ContainsIngress(ingressName) {
+		c.blockRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.blockRuleCache.UpdateByIngress(ingress, configMaps)
+}
+
+func (c *ModBlockConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.blockRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.blockRuleCache.DeleteByIngress(ingressName)
+}
+
+// UpdateGlobalConfig implements modules.GlobalConfigHandler
+func (c *ModBlockConfig) UpdateGlobalConfig(data map[string]string) error {
+	var ipBlocklist []annotations.IPRange
+	if value, ok := data[annotations.BlockIPBlocklistKey]; ok {
+		var err error
+		ipBlocklist, err = annotations.ParseIPRanges(map[string]string{annotations.BlockIPBlocklistKey: value})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.blockRuleCache.UpdateIPBlocklist(ipBlocklist)
+	return nil
+}
+
+func (c *ModBlockConfig) Reload() error {
+	if err := c.reloadIPBlocklist(); err != nil {
+		return err
+	}
+
+	if err := c.updateBlockConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.blockConfFile.Version != c.version {
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.blockConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(reloadNameRule)
+		if err != nil {
+			return err
+		}
+		c.version = *c.blockConfFile.Version
+	}
+
+	return nil
+}
+
+// reloadIPBlocklist dumps the global blocklist in format of IP dictionary, one IP or IP range per line
+func (c *ModBlockConfig) reloadIPBlocklist() error {
+	if c.ipBlocklistVersion == c.blockRuleCache.ipBlocklistVersion {
+		return nil
+	}
+
+	version := c.blockRuleCache.ipBlocklistVersion
+	singleIPNum, pairIPNum := 0, 0
+	var lines []string
+	for _, ipRange := range c.blockRuleCache.ipBlocklist {
+		if ipRange.Start.Equal(ipRange.End) {
+			singleIPNum++
+			lines = append(lines, ipRange.Start.String())
+		} else {
+			pairIPNum++
+			lines = append(lines, ipRange.Start.String()+" "+ipRange.End.String())
+		}
+	}
+
+	// the first line is the meta info of the dictionary
+	meta, err := json.Marshal(map[string]interface{}{
+		"Version":     version,
+		"SingleIPNum": singleIPNum,
+		"PairIPNum":   pairIPNum,
+	})
+	if err != nil {
+		return fmt.Errorf("dump %s error: %v", IPBlocklistData, err)
+	}
+	lines = append([]string{"#" + string(meta)}, lines...)
+
+	if err := util.DumpFile(IPBlocklistData, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		return fmt.Errorf("dump %s error: %v", IPBlocklistData, err)
+	}
+	if err := util.ReloadBfe(reloadNameIPBlocklist); err != nil {
+		return err
+	}
+	c.ipBlocklistVersion = version
+	return nil
+}
+
+func (c *ModBlockConfig) updateBlockConf() error {
+	version := c.products.ConfVersion(c.blockRuleCache.Version)
+	if *c.blockConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.blockRuleCache.GetRules()
+	productRuleList := make(map[string]blockRuleFileList)
+	// number of rules ending with a CLOSE rule in each product, the ALLOW rules after them are useless
+	closeRuleNum := make(map[string]int)
+	for _, rule := range ruleList {
+		rule := rule.(*blockRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%s:%s%s", rule.GetIngress(), rule.GetHost(), rule.GetPath())
+		closeCond := buildCloseCond(cond, rule.blockList)
+		for _, product := range c.products.Of(rule.GetHost()) {
+			blockRuleList := productRuleList[product]
+			if len(closeCond) > 0 {
+				blockRuleList = append(blockRuleList, newBlockRuleFile(name, closeCond, actionClose))
+				closeRuleNum[product] = len(blockRuleList)
+			}
+			// mod_block stops at the first matched rule, so requests allowed by the ingress aren't checked by rules with lower priority
+			productRuleList[product] = append(blockRuleList, newBlockRuleFile(name+":allow", cond, actionAllow))
+		}
+	}
+
+	blockConfFile := newBlockConfFile(version)
+	for product, blockRuleList := range productRuleList {
+		blockRuleList := blockRuleList[:closeRuleNum[product]]
+		if len(blockRuleList) == 0 {
+			continue
+		}
+		if err := blockRuleListCheck(blockRuleList); err != nil {
+			return err
+		}
+		(*blockConfFile.Config)[product] = &blockRuleList
+	}
+
+	// skip reloading BFE while no request is blocked, so mod_block only needs to be enabled in bfe.conf when used
+	if blockConfEmpty(blockConfFile) && blockConfEmpty(c.blockConfFile) {
+		c.version = version
+	}
+
+	c.blockConfFile = blockConfFile
+	return nil
+}
+
+// blockConfEmpty returns true if the config has no rule
+func blockConfEmpty(conf *blockConfFile) bool {
+	return len(*conf.Config) == 1 && len(*(*conf.Config)[configs.DefaultProduct]) == 0
+}
+
+// buildCloseCond returns the condition of requests which should be denied,
+// that is requests from IPs in the blacklist or not in the whitelist. Empty string is returned if no request is denied.
+func buildCloseCond(cond string, blockList *annotations.BlockList) string {
+	if blockList == nil {
+		return ""
+	}
+
+	var denyConds []string
+	if len(blockList.Blacklist) > 0 {
+		denyConds = append(denyConds, buildIPRangeCond(blockList.Blacklist))
+	}
+	if len(blockList.Whitelist) > 0 {
+		denyConds = append(denyConds, fmt.Sprintf("!(%s)", buildIPRangeCond(blockList.Whitelist)))
+	}
+	if len(denyConds) == 0 {
+		// e.g. the blacklist ConfigMap is empty
+		return ""
+	}
+
+	denyCond := strings.Join(denyConds, "||")
+	if len(cond) == 0 {
+		return denyCond
+	}
+	return fmt.Sprintf("%s&&(%s)", cond, denyCond)
+}
+
+func buildIPRangeCond(ranges []annotations.IPRange) string {
+	conds := make([]string, 0, len(ranges))
+	for _, ipRange := range ranges {
+		conds = append(conds, fmt.Sprintf("req_cip_range(\"%s\", \"%s\")", ipRange.Start, ipRange.End))
+	}
+	return strings.Join(conds, "||")
+}
+
+func newBlockRuleFile(name, cond, cmd string) blockRuleFile {
+	return blockRuleFile{
+		Cond:   &cond,
+		Name:   &name,
+		Action: &mod_block.ActionFile{Cmd: &cmd, Params: []string{}},
+	}
+}
+
+// blockRuleListCheck checks rules in the same way as mod_block does
+func blockRuleListCheck(ruleList blockRuleFileList) error {
+	names := make(map[string]bool)
+	for i, rule := range ruleList {
+		if _, err := condition.Build(*rule.Cond); err != nil {
+			return fmt.Errorf("blockRule:%d, cond [%s] is illegal: %s", i, *rule.Cond, err)
+		}
+		if err := mod_block.ActionFileCheck(rule.Action); err != nil {
+			return fmt.Errorf("blockRule:%d, Action:%s", i, err)
+		}
+		if names[*rule.Name] {
+			return fmt.Errorf("blockRule:%d, two rules have same name[%s]", i, *rule.Name)
+		}
+		names[*rule.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package block
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+func TestUpdateBlockConf(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	whitelist := map[string]string{annotations.BlockWhitelistAnnotation: "10.0.0.0/8"}
+	blacklist := map[string]string{annotations.BlockBlacklistConfigMapAnnotation: "blacklist"}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "blacklist"},
+		Data:       map[string]string{"ips": "1.2.3.4\n5.6.7.0/24"},
+	}
+
+	type request struct {
+		path string
+		ip   string
+		want string // action of the first matched rule, empty if no rule is matched
+	}
+	tests := []struct {
+		name       string
+		ingresses  []*netv1.Ingress
+		configMaps []*corev1.ConfigMap
+		wantRules  int
+		wantReload bool
+		requests   []request
+	}{
+		{
+			name:       "no ingress uses whitelist or blacklist",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "whitelist",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", whitelist, "foo.com", "/foo")},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo", ip: "10.1.1.1", want: ""},
+				{path: "/foo", ip: "192.168.1.1", want: actionClose},
+				{path: "/bar", ip: "192.168.1.1", want: ""},
+			},
+		},
+		{
+			name:       "blacklist in configmap",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", blacklist, "foo.com", "/foo")},
+			configMaps: []*corev1.ConfigMap{configMap},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo", ip: "1.2.3.4", want: actionClose},
+				{path: "/foo", ip: "5.6.7.8", want: actionClose},
+				{path: "/foo", ip: "1.2.3.5", want: ""},
+			},
+		},
+		{
+			name: "requests allowed by ingress with higher priority",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", whitelist, "foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo/bar"),
+			},
+			wantRules:  2,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo/bar", ip: "192.168.1.1", want: actionAllow},
+				{path: "/foo/baz", ip: "192.168.1.1", want: actionClose},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewBlockConfig("init", configs.NewProductTable())
+			for _, ingress := range tt.ingresses {
+				if err := c.UpdateIngressWithConfigMaps(ingress, tt.configMaps); err != nil {
+					t.Fatalf("UpdateIngressWithConfigMaps() error = %v", err)
+				}
+			}
+			if err := c.updateBlockConf(); err != nil {
+				t.Fatalf("updateBlockConf() error = %v", err)
+			}
+
+			rules := *(*c.blockConfFile.Config)[configs.DefaultProduct]
+			if got := len(rules); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := *c.blockConfFile.Version != c.version; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+
+			for _, r := range tt.requests {
+				req := moduletest.NewRequest("foo.com", r.path, r.ip)
+				got := ""
+				for _, rule := range rules {
+					if moduletest.Match(t, *rule.Cond, req) {
+						got = *rule.Action.Cmd
+						break
+					}
+				}
+				if got != r.want {
+					t.Errorf("request of %s from %s: action = %q, want %q", r.path, r.ip, got, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateBlockConfWithConfigMaps(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	ingress := moduletest.NewIngress("a", map[string]string{annotations.BlockWhitelistConfigMapAnnotation: "whitelist"}, "foo.com", "/foo")
+	tests := []struct {
+		name       string
+		configMaps []*corev1.ConfigMap
+		wantErr    bool
+	}{
+		{
+			name:    "configmap not found",
+			wantErr: true,
+		},
+		{
+			name: "empty whitelist",
+			configMaps: []*corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "whitelist"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "configmap of other namespace",
+			configMaps: []*corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "whitelist"},
+				Data:       map[string]string{"ips": "10.0.0.0/8"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "valid whitelist",
+			configMaps: []*corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "whitelist"},
+				Data:       map[string]string{"ips": "10.0.0.0/8"},
+			}},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewBlockConfig("init", configs.NewProductTable())
+			if err := c.UpdateIngressWithConfigMaps(ingress, tt.configMaps); (err != nil) != tt.wantErr {
+				t.Errorf("UpdateIngressWithConfigMaps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
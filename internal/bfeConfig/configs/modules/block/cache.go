@@ -0,0 +1,102 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package block is the module of IP whitelist and blacklist.
+// This file defines block rule & cache's struct, also implements update ingress method.
+package block
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+type blockRule struct {
+	*cache.BaseRule
+	// blockList is nil if the ingress has no whitelist or blacklist, such rule only stops rules with lower priority
+	blockList *annotations.BlockList
+}
+
+type blockRuleCache struct {
+	*cache.BaseCache
+	// ipBlocklist is the global blocklist, connections from these IPs are closed for all ingresses
+	ipBlocklist []annotations.IPRange
+	// ipBlocklistVersion is changed when ipBlocklist is updated
+	ipBlocklistVersion string
+}
+
+func newBlockRuleCache(version string) *blockRuleCache {
+	return &blockRuleCache{
+		BaseCache:          cache.NewBaseCache(version),
+		ipBlocklistVersion: version,
+	}
+}
+
+func (c *blockRuleCache) UpdateByIngress(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap) error {
+	blockList, err := annotations.GetBlockList(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+
+	if blockList != nil {
+		for _, name := range annotations.GetConfigMapReferences(ingress.Annotations) {
+			configMap := findConfigMap(configMaps, ingress.Namespace, name)
+			if configMap == nil {
+				return fmt.Errorf("configmap %s not found", util.NamespacedName(ingress.Namespace, name))
+			}
+			if err := blockList.AddConfigMap(name, configMap.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	// rules of ingresses without whitelist or blacklist are also cached, since mod_block stops at the first matched rule
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &blockRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				blockList: blockList,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
+
+// UpdateIPBlocklist replaces the global blocklist
+func (c *blockRuleCache) UpdateIPBlocklist(ipBlocklist []annotations.IPRange) {
+	c.ipBlocklist = ipBlocklist
+	c.ipBlocklistVersion = util.NewVersion()
+}
+
+func findConfigMap(configMaps []*corev1.ConfigMap, namespace, name string) *corev1.ConfigMap {
+	for _, configMap := range configMaps {
+		if configMap.Namespace == namespace && configMap.Name == name {
+			return configMap
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compress is the module of response compression.
+// This file defines compress rule & cache's struct, also implements update ingress and defaults methods.
+package compress
+
+import (
+	"fmt"
+
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+type compressRule struct {
+	*cache.BaseRule
+	// compress is set by annotations "compress.*" of the ingress, and merged with the global defaults when generating rules
+	compress *annotations.Compress
+}
+
+type compressRuleCache struct {
+	*cache.BaseCache
+	// defaults are the compression set by the global ConfigMap
+	defaults *annotations.Compress
+}
+
+func newCompressRuleCache(version string) *compressRuleCache {
+	return &compressRuleCache{
+		BaseCache: cache.NewBaseCache(version),
+		defaults:  &annotations.Compress{},
+	}
+}
+
+func (c *compressRuleCache) UpdateByIngress(ingress *netv1.Ingress) error {
+	compress, err := annotations.GetCompress(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+	if err := compress.Merge(c.defaults).Check(); err != nil {
+		return err
+	}
+
+	// rules of ingresses without compression are also cached, since mod_compress stops at the first matched rule
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &compressRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				compress: compress,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
+
+// UpdateDefaults updates the default compression, which should be valid with the compression of all ingresses
+func (c *compressRuleCache) UpdateDefaults(defaults *annotations.Compress) error {
+	if err := defaults.Check(); err != nil {
+		return err
+	}
+	for _, rule := range c.GetRules() {
+		rule := rule.(*compressRule)
+		if err := rule.compress.Merge(defaults).Check(); err != nil {
+			return fmt.Errorf("conflict with ingress %s: %s", rule.GetIngress(), err)
+		}
+	}
+
+	c.defaults = defaults
+	c.Version = util.NewVersion()
+	return nil
+}
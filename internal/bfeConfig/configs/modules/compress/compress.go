@@ -0,0 +1,262 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compress is the module of response compression.
+// This file implements operate rule cache, generate and reload config file methods.
+package compress
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_compress"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+const (
+	ConfigNameCompress = "mod_compress"
+	RuleData           = "mod_compress/compress_rule.data"
+
+	// defaultFlushSize is the size of data buffered before flushed to the client, same as the example of mod_compress
+	defaultFlushSize = 512
+)
+
+// commands of mod_compress
+var compressCmds = map[string]string{
+	annotations.CompressGzip:   mod_compress.ActionGzip,
+	annotations.CompressBrotli: mod_compress.ActionBrotli,
+}
+
+// compressRuleFile is the rule of mod_compress, which is not exported by mod_compress
+type compressRuleFile struct {
+	Cond   *string
+	Action *mod_compress.ActionFile
+}
+
+type compressRuleFileList []compressRuleFile
+
+type compressConfFile struct {
+	Version *string
+	Config  *map[string]*compressRuleFileList
+}
+
+type ModCompressConfig struct {
+	version           string
+	products          *configs.ProductTable
+	compressRuleCache *compressRuleCache
+	compressConfFile  *compressConfFile
+}
+
+func NewCompressConfig(version string, products *configs.ProductTable) *ModCompressConfig {
+	return &ModCompressConfig{
+		version:           version,
+		products:          products,
+		compressRuleCache: newCompressRuleCache(version),
+		compressConfFile:  newCompressConfFile(version),
+	}
+}
+
+func newCompressConfFile(version string) *compressConfFile {
+	ruleList := make(compressRuleFileList, 0)
+	productRuleList := map[string]*compressRuleFileList{
+		configs.DefaultProduct: &ruleList,
+	}
+	return &compressConfFile{
+		Version: &version,
+		Config:  &productRuleList,
+	}
+}
+
+func (c *ModCompressConfig) Name() string {
+	return ConfigNameCompress
+}
+
+func (c *ModCompressConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.compressRuleCache.ContainsIngress(ingressName) {
+		c.compressRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.compressRuleCache.UpdateByIngress(ingress)
+}
+
+func (c *ModCompressConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.compressRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.compressRuleCache.DeleteByIngress(ingressName)
+}
+
+// UpdateGlobalConfig implements modules.GlobalConfigHandler
+func (c *ModCompressConfig) UpdateGlobalConfig(data map[string]string) error {
+	defaults, err := annotations.ParseCompress(data)
+	if err != nil {
+		return err
+	}
+	return c.compressRuleCache.UpdateDefaults(defaults)
+}
+
+func (c *ModCompressConfig) Reload() error {
+	if err := c.updateCompressConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.compressConfFile.Version != c.version {
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.compressConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameCompress)
+		if err != nil {
+			return err
+		}
+		c.version = *c.compressConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModCompressConfig) updateCompressConf() error {
+	version := c.products.ConfVersion(c.compressRuleCache.Version)
+	if *c.compressConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.compressRuleCache.GetRules()
+	productRuleList := make(map[string]compressRuleFileList)
+	// conditions of rules with higher priority
+	var higherConds cache.PriorityConds
+	for _, rule := range ruleList {
+		rule := rule.(*compressRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+		if len(cond) == 0 {
+			cond = "default_t()"
+		}
+
+		// responses of an ingress are only compressed by its own rule, even if they don't match the content types or size
+		host := rule.GetHost()
+		ruleCond := higherConds.Exclude(rule, cond)
+		higherConds.Add(rule, cond)
+
+		compress := rule.compress.Merge(c.compressRuleCache.defaults)
+		if !compress.Enabled() {
+			continue
+		}
+		for _, product := range c.products.Of(host) {
+			productRuleList[product] = append(productRuleList[product], newCompressRuleFile(ruleCond, compress))
+		}
+	}
+
+	// skip reloading BFE while no response is compressed, so mod_compress only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(*c.compressConfFile.Config) == 1 && len(*(*c.compressConfFile.Config)[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	compressConfFile := newCompressConfFile(version)
+	for product, compressRuleList := range productRuleList {
+		compressRuleList := compressRuleList
+		if err := compressRuleListCheck(compressRuleList); err != nil {
+			return err
+		}
+		(*compressConfFile.Config)[product] = &compressRuleList
+	}
+
+	c.compressConfFile = compressConfFile
+	return nil
+}
+
+func newCompressRuleFile(cond string, compress *annotations.Compress) compressRuleFile {
+	if typeCond := buildContentTypeCond(compress.ContentTypes()); len(typeCond) > 0 {
+		cond = fmt.Sprintf("%s&&%s", cond, typeCond)
+	}
+	if sizeCond := buildMinSizeCond(compress.MinSizeValue()); len(sizeCond) > 0 {
+		cond = fmt.Sprintf("%s&&%s", cond, sizeCond)
+	}
+
+	cmd := compressCmds[*compress.Type]
+	quality := compress.QualityValue()
+	flushSize := defaultFlushSize
+	return compressRuleFile{
+		Cond: &cond,
+		Action: &mod_compress.ActionFile{
+			Cmd:       &cmd,
+			Quality:   &quality,
+			FlushSize: &flushSize,
+		},
+	}
+}
+
+// buildContentTypeCond returns the condition of responses with the content types.
+// Content-Type is matched exactly, so the common form with charset utf-8 is also matched.
+func buildContentTypeCond(types []string) string {
+	if len(types) == 0 {
+		return ""
+	}
+
+	var values []string
+	for _, contentType := range types {
+		values = append(values, contentType)
+		if !strings.Contains(contentType, ";") {
+			values = append(values, contentType+";charset=utf-8", contentType+"; charset=utf-8")
+		}
+	}
+	return fmt.Sprintf("res_header_value_in(\"Content-Type\", \"%s\", true)", strings.Join(values, "|"))
+}
+
+// buildMinSizeCond returns the condition of responses not smaller than minSize.
+// mod_compress can't compare sizes, so Content-Length of smaller responses are listed,
+// responses without Content-Length (e.g. chunked) are compressed.
+func buildMinSizeCond(minSize int) string {
+	if minSize == 0 {
+		return ""
+	}
+
+	values := make([]string, 0, minSize)
+	for size := 0; size < minSize; size++ {
+		values = append(values, strconv.Itoa(size))
+	}
+	return fmt.Sprintf("!res_header_value_in(\"Content-Length\", \"%s\", false)", strings.Join(values, "|"))
+}
+
+// compressRuleListCheck checks rules in the same way as mod_compress does
+func compressRuleListCheck(ruleList compressRuleFileList) error {
+	for i, rule := range ruleList {
+		if _, err := condition.Build(*rule.Cond); err != nil {
+			return fmt.Errorf("compressRule:%d, cond [%s] is illegal: %s", i, *rule.Cond, err)
+		}
+		if err := mod_compress.ActionFileCheck(rule.Action); err != nil {
+			return fmt.Errorf("compressRule:%d, Action:%s", i, err)
+		}
+	}
+	return nil
+}
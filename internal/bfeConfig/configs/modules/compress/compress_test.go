@@ -0,0 +1,169 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package compress
+
+import (
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_compress"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+func TestUpdateCompressConf(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	gzip := map[string]string{annotations.BfeAnnotationPrefix + annotations.CompressTypeKey: annotations.CompressGzip}
+	brotli := map[string]string{
+		annotations.BfeAnnotationPrefix + annotations.CompressTypeKey:    annotations.CompressBrotli,
+		annotations.BfeAnnotationPrefix + annotations.CompressTypesKey:   annotations.CompressAnyType,
+		annotations.BfeAnnotationPrefix + annotations.CompressMinSizeKey: "1024",
+	}
+	off := map[string]string{annotations.BfeAnnotationPrefix + annotations.CompressTypeKey: annotations.CompressOff}
+
+	type request struct {
+		path          string
+		contentType   string
+		contentLength string
+		want          string // command of the first matched rule, empty if no rule is matched
+	}
+	tests := []struct {
+		name       string
+		ingresses  []*netv1.Ingress
+		global     map[string]string
+		wantErr    bool
+		wantRules  int
+		wantReload bool
+		requests   []request
+	}{
+		{
+			name:       "no compression is set",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "gzip with default content types",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", gzip, "foo.com", "/foo")},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo", contentType: "text/html", want: mod_compress.ActionGzip},
+				{path: "/foo", contentType: "application/json; charset=utf-8", want: mod_compress.ActionGzip},
+				{path: "/foo", contentType: "image/png", want: ""},
+				{path: "/bar", contentType: "text/html", want: ""},
+			},
+		},
+		{
+			name:       "brotli with any content type and min size",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", brotli, "foo.com", "/foo")},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo", contentType: "image/png", contentLength: "2048", want: mod_compress.ActionBrotli},
+				{path: "/foo", contentType: "image/png", want: mod_compress.ActionBrotli},
+				{path: "/foo", contentType: "image/png", contentLength: "100", want: ""},
+			},
+		},
+		{
+			name: "ingress without compression with higher priority",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", gzip, "foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo/bar"),
+			},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo/baz", contentType: "text/html", want: mod_compress.ActionGzip},
+				{path: "/foo/bar", contentType: "text/html", want: ""},
+			},
+		},
+		{
+			name: "global compression",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", nil, "foo.com", "/foo"),
+				moduletest.NewIngress("b", off, "foo.com", "/foo/bar"),
+			},
+			global:     map[string]string{annotations.CompressTypeKey: annotations.CompressGzip},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo/baz", contentType: "text/html", want: mod_compress.ActionGzip},
+				{path: "/foo/bar", contentType: "text/html", want: ""},
+			},
+		},
+		{
+			name: "illegal quality",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", map[string]string{
+				annotations.BfeAnnotationPrefix + annotations.CompressTypeKey:    annotations.CompressGzip,
+				annotations.BfeAnnotationPrefix + annotations.CompressQualityKey: "20",
+			}, "foo.com", "/foo")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCompressConfig("init", configs.NewProductTable())
+			if err := c.UpdateGlobalConfig(tt.global); err != nil {
+				t.Fatalf("UpdateGlobalConfig() error = %v", err)
+			}
+			var err error
+			for _, ingress := range tt.ingresses {
+				if err = c.UpdateIngress(ingress); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateIngress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := c.updateCompressConf(); err != nil {
+				t.Fatalf("updateCompressConf() error = %v", err)
+			}
+
+			rules := *(*c.compressConfFile.Config)[configs.DefaultProduct]
+			if got := len(rules); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := *c.compressConfFile.Version != c.version; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+
+			for _, r := range tt.requests {
+				req := moduletest.NewRequest("foo.com", r.path, "10.0.0.1")
+				req.HttpResponse = &bfe_http.Response{StatusCode: 200, Header: make(bfe_http.Header)}
+				req.HttpResponse.Header.Set("Content-Type", r.contentType)
+				if len(r.contentLength) > 0 {
+					req.HttpResponse.Header.Set("Content-Length", r.contentLength)
+				}
+				got := ""
+				for _, rule := range rules {
+					if moduletest.Match(t, *rule.Cond, req) {
+						got = *rule.Action.Cmd
+						break
+					}
+				}
+				if got != r.want {
+					t.Errorf("response %s of %s: command = %q, want %q", r.contentType, r.path, got, r.want)
+				}
+			}
+		})
+	}
+}
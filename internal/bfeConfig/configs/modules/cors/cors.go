@@ -0,0 +1,150 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cors is the module of Cross-Origin Resource Sharing.
+// This file implements operate rule cache, generate and reload config file methods.
+package cors
+
+import (
+	"fmt"
+
+	"github.com/bfenetworks/bfe/bfe_modules/mod_cors"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+const (
+	ConfigNameCors = "mod_cors"
+	RuleData       = "mod_cors/cors_rule.data"
+)
+
+type ModCorsConfig struct {
+	version       string
+	products      *configs.ProductTable
+	corsRuleCache *corsRuleCache
+	corsConfFile  *mod_cors.CorsRuleFile
+}
+
+func NewCorsConfig(version string, products *configs.ProductTable) *ModCorsConfig {
+	return &ModCorsConfig{
+		version:       version,
+		products:      products,
+		corsRuleCache: newCorsRuleCache(version),
+		corsConfFile:  newCorsConfFile(version),
+	}
+}
+
+func newCorsConfFile(version string) *mod_cors.CorsRuleFile {
+	productRuleList := make(mod_cors.ProductRuleRawList)
+	productRuleList[configs.DefaultProduct] = make(mod_cors.RuleRawList, 0)
+	return &mod_cors.CorsRuleFile{
+		Version: version,
+		Config:  productRuleList,
+	}
+}
+
+func (c *ModCorsConfig) Name() string {
+	return ConfigNameCors
+}
+
+func (c *ModCorsConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.corsRuleCache.ContainsIngress(ingressName) {
+		c.corsRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.corsRuleCache.UpdateByIngress(ingress)
+}
+
+func (c *ModCorsConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.corsRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.corsRuleCache.DeleteByIngress(ingressName)
+}
+
+func (c *ModCorsConfig) Reload() error {
+	if err := c.updateCorsConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if c.corsConfFile.Version != c.version {
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.corsConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameCors)
+		if err != nil {
+			return err
+		}
+		c.version = c.corsConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModCorsConfig) updateCorsConf() error {
+	version := c.products.ConfVersion(c.corsRuleCache.Version)
+	if c.corsConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.corsRuleCache.GetRules()
+	productRuleList := make(mod_cors.ProductRuleRawList)
+	for _, rule := range ruleList {
+		rule := rule.(*corsRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+		for _, product := range c.products.Of(rule.GetHost()) {
+			productRuleList[product] = append(productRuleList[product], mod_cors.CorsRuleRaw{
+				Cond:                          cond,
+				AccessControlAllowOrigins:     rule.cors.AllowOrigins,
+				AccessControlAllowCredentials: rule.cors.AllowCredentials,
+				AccessControlExposeHeaders:    rule.cors.ExposeHeaders,
+				AccessControlAllowMethods:     rule.cors.AllowMethods,
+				AccessControlAllowHeaders:     rule.cors.AllowHeaders,
+				AccessControlMaxAge:           rule.cors.MaxAge,
+			})
+		}
+	}
+
+	// skip reloading BFE while no ingress uses CORS, so mod_cors only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(c.corsConfFile.Config) == 1 && len(c.corsConfFile.Config[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	corsConfFile := newCorsConfFile(version)
+	for product, corsRuleList := range productRuleList {
+		corsConfFile.Config[product] = corsRuleList
+	}
+	if err := mod_cors.CorsRuleCheck(corsConfFile); err != nil {
+		return err
+	}
+
+	c.corsConfFile = corsConfFile
+	return nil
+}
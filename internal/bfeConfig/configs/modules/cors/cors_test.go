@@ -0,0 +1,88 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cors
+
+import (
+	"testing"
+
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+func TestUpdateCorsConf(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	cors := map[string]string{annotations.CorsAllowOriginsAnnotation: `["https://example.com"]`}
+	tests := []struct {
+		name       string
+		ingresses  []*netv1.Ingress
+		deleted    []string
+		wantRules  int
+		wantReload bool
+	}{
+		{
+			name:       "no ingress uses cors",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "ingress with cors annotations",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", cors, "foo.com", "/foo", "/bar")},
+			wantRules:  2,
+			wantReload: true,
+		},
+		{
+			name:       "ingress with cors annotations deleted",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", cors, "foo.com", "/foo")},
+			deleted:    []string{"a"},
+			wantRules:  0,
+			wantReload: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCorsConfig("init", configs.NewProductTable())
+			for _, ingress := range tt.ingresses {
+				if err := c.UpdateIngress(ingress); err != nil {
+					t.Fatalf("UpdateIngress() error = %v", err)
+				}
+			}
+			if len(tt.deleted) > 0 {
+				// rules are dumped before deleted
+				if err := c.updateCorsConf(); err != nil {
+					t.Fatalf("updateCorsConf() error = %v", err)
+				}
+				c.version = c.corsConfFile.Version
+				for _, name := range tt.deleted {
+					c.DeleteIngress("default", name)
+				}
+			}
+			if err := c.updateCorsConf(); err != nil {
+				t.Fatalf("updateCorsConf() error = %v", err)
+			}
+
+			if got := len(c.corsConfFile.Config[configs.DefaultProduct]); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := c.corsConfFile.Version != c.version; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+		})
+	}
+}
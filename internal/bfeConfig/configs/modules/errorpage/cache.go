@@ -0,0 +1,107 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errorpage is the module of error pages.
+// This file defines error rule & cache's struct, also implements update ingress and defaults methods.
+package errorpage
+
+import (
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+type errorRule struct {
+	*cache.BaseRule
+	// actions is nil if the ingress has no error pages, such rule stops rules with lower priority,
+	// and the global defaults are used for it
+	actions annotations.ErrorActions
+}
+
+type errorRuleCache struct {
+	*cache.BaseCache
+	// defaults are the error pages from the global ConfigMap, which are used by all ingresses
+	defaults annotations.ErrorActions
+}
+
+func newErrorRuleCache(version string) *errorRuleCache {
+	return &errorRuleCache{
+		BaseCache: cache.NewBaseCache(version),
+		defaults:  make(annotations.ErrorActions),
+	}
+}
+
+func (c *errorRuleCache) UpdateByIngress(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap) error {
+	name, ok, err := annotations.GetErrorsPagesConfigMap(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+	var pages map[string]string
+	if ok {
+		configMap := findConfigMap(configMaps, ingress.Namespace, name)
+		if configMap == nil {
+			return fmt.Errorf("configmap %s not found", util.NamespacedName(ingress.Namespace, name))
+		}
+		pages = configMap.Data
+	}
+
+	actions, err := annotations.GetErrorActions(ingress.Annotations, pages)
+	if err != nil {
+		return err
+	}
+
+	// rules of ingresses without error pages are also cached, since mod_errors stops at the first matched rule
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &errorRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				actions: actions,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
+
+// UpdateDefaults updates the error pages from the global ConfigMap
+func (c *errorRuleCache) UpdateDefaults(defaults annotations.ErrorActions) {
+	if reflect.DeepEqual(c.defaults, defaults) {
+		return
+	}
+
+	c.defaults = defaults
+	c.Version = util.NewVersion()
+}
+
+func findConfigMap(configMaps []*corev1.ConfigMap, namespace, name string) *corev1.ConfigMap {
+	for _, configMap := range configMaps {
+		if configMap.Namespace == namespace && configMap.Name == name {
+			return configMap
+		}
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errorpage is the module of error pages.
+// This file implements operate rule cache, generate and reload config file methods.
+package errorpage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_errors"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+const (
+	ConfigNameErrors = "mod_errors"
+	RuleData         = "mod_errors/errors_rule.data"
+	// PageFileDir is the directory of error pages, e.g. mod_errors/pages/default_app_502
+	PageFileDir = "mod_errors/pages/"
+
+	// globalPagePrefix is the prefix of files of error pages from the global ConfigMap
+	globalPagePrefix = "global"
+)
+
+type ModErrorsConfig struct {
+	version        string
+	products       *configs.ProductTable
+	errorRuleCache *errorRuleCache
+	errorsConfFile *mod_errors.ErrorsConfFile
+	// pageFiles are the error pages referenced by errorsConfFile, file name => content
+	pageFiles map[string]string
+	// dumpedPageFiles are the error pages on the disk
+	dumpedPageFiles map[string]bool
+}
+
+func NewErrorsConfig(version string, products *configs.ProductTable) *ModErrorsConfig {
+	return &ModErrorsConfig{
+		version:         version,
+		products:        products,
+		errorRuleCache:  newErrorRuleCache(version),
+		errorsConfFile:  newErrorsConfFile(version),
+		pageFiles:       make(map[string]string),
+		dumpedPageFiles: make(map[string]bool),
+	}
+}
+
+func newErrorsConfFile(version string) *mod_errors.ErrorsConfFile {
+	ruleList := make(mod_errors.RuleFileList, 0)
+	productRuleList := mod_errors.ProductRulesFile{
+		configs.DefaultProduct: &ruleList,
+	}
+	return &mod_errors.ErrorsConfFile{
+		Version: &version,
+		Config:  &productRuleList,
+	}
+}
+
+func (c *ModErrorsConfig) Name() string {
+	return ConfigNameErrors
+}
+
+func (c *ModErrorsConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	return c.UpdateIngressWithConfigMaps(ingress, nil)
+}
+
+// UpdateIngressWithConfigMaps implements modules.ConfigMapReferrer
+func (c *ModErrorsConfig) UpdateIngressWithConfigMaps(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.errorRuleCache.ContainsIngress(ingressName) {
+		c.errorRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.errorRuleCache.UpdateByIngress(ingress, configMaps)
+}
+
+func (c *ModErrorsConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.errorRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.errorRuleCache.DeleteByIngress(ingressName)
+}
+
+// UpdateGlobalConfig implements modules.GlobalConfigHandler
+func (c *ModErrorsConfig) UpdateGlobalConfig(data map[string]string) error {
+	defaults, err := annotations.ParseErrorActions(data)
+	if err != nil {
+		return err
+	}
+
+	c.errorRuleCache.UpdateDefaults(defaults)
+	return nil
+}
+
+func (c *ModErrorsConfig) Reload() error {
+	if err := c.updateErrorsConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.errorsConfFile.Version != c.version {
+		// dump error pages, which are read when the config file is loaded
+		if err := c.dumpPageFiles(); err != nil {
+			return err
+		}
+		// error pages are checked by mod_errors, so they must be on the disk
+		if err := mod_errors.ErrorsConfCheck(*c.errorsConfFile); err != nil {
+			return fmt.Errorf("check %s error: %v", RuleData, err)
+		}
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.errorsConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameErrors)
+		if err != nil {
+			return err
+		}
+		c.version = *c.errorsConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModErrorsConfig) dumpPageFiles() error {
+	for fileName, page := range c.pageFiles {
+		if err := util.DumpFile(fileName, []byte(page)); err != nil {
+			return fmt.Errorf("dump %s error: %v", fileName, err)
+		}
+		c.dumpedPageFiles[fileName] = true
+	}
+
+	// delete error pages which are no longer used
+	for fileName := range c.dumpedPageFiles {
+		if _, ok := c.pageFiles[fileName]; !ok {
+			util.DeleteFile(fileName)
+			delete(c.dumpedPageFiles, fileName)
+		}
+	}
+	return nil
+}
+
+func (c *ModErrorsConfig) updateErrorsConf() error {
+	version := c.products.ConfVersion(c.errorRuleCache.Version)
+	if *c.errorsConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.errorRuleCache.GetRules()
+	productRuleList := make(map[string]mod_errors.RuleFileList)
+	pageFiles := make(map[string]string)
+	// conditions of rules with higher priority
+	var higherConds cache.PriorityConds
+	for _, rule := range ruleList {
+		rule := rule.(*errorRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+		if len(cond) == 0 {
+			cond = "default_t()"
+		}
+
+		// a request should only match rules of the ingress with the highest priority,
+		// so that error pages not set by it are still replaced by the global defaults
+		host := rule.GetHost()
+		ruleCond := higherConds.Exclude(rule, cond)
+		higherConds.Add(rule, cond)
+		if rule.actions == nil {
+			continue
+		}
+
+		pagePrefix := strings.Replace(rule.GetIngress(), "/", "_", 1)
+		rules, err := newErrorsRules(ruleCond, rule.actions, pagePrefix, pageFiles)
+		if err != nil {
+			return err
+		}
+		for _, product := range c.products.Of(host) {
+			productRuleList[product] = append(productRuleList[product], rules...)
+		}
+	}
+
+	// the global defaults are used by requests not matched by rules of ingresses
+	rules, err := newErrorsRules("", c.errorRuleCache.defaults, globalPagePrefix, pageFiles)
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		for _, product := range c.products.All() {
+			productRuleList[product] = append(productRuleList[product], rules...)
+		}
+	}
+
+	// skip reloading BFE while no error page is set, so mod_errors only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(*c.errorsConfFile.Config) == 1 && len(*(*c.errorsConfFile.Config)[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	errorsConfFile := newErrorsConfFile(version)
+	for product, errorsRuleList := range productRuleList {
+		errorsRuleList := errorsRuleList
+		(*errorsConfFile.Config)[product] = &errorsRuleList
+	}
+
+	c.errorsConfFile = errorsConfFile
+	c.pageFiles = pageFiles
+	return nil
+}
+
+// newErrorsRules returns a rule for each status code of actions, pages are added to pageFiles
+func newErrorsRules(cond string, actions annotations.ErrorActions, pagePrefix string, pageFiles map[string]string) ([]mod_errors.ErrorsRuleFile, error) {
+	var rules []mod_errors.ErrorsRuleFile
+	for _, code := range actions.StatusCodes() {
+		action := actions[code]
+		ruleCond := fmt.Sprintf("res_code_in(\"%d\")", code)
+		if len(cond) > 0 {
+			ruleCond = fmt.Sprintf("%s&&%s", cond, ruleCond)
+		}
+		if _, err := condition.Build(ruleCond); err != nil {
+			return nil, fmt.Errorf("cond [%s] is illegal: %s", ruleCond, err)
+		}
+
+		var cmd string
+		var params []string
+		if len(action.RedirectURL) > 0 {
+			cmd = mod_errors.REDIRECT
+			params = []string{action.RedirectURL}
+		} else {
+			fileName := fmt.Sprintf("%s%s_%d", PageFileDir, pagePrefix, code)
+			pageFile, err := filepath.Abs(option.Opts.Ingress.ConfigPath + fileName)
+			if err != nil {
+				return nil, err
+			}
+			pageFiles[fileName] = action.Page
+			cmd = mod_errors.RETURN
+			params = []string{strconv.Itoa(code), action.ContentType, pageFile}
+		}
+
+		rules = append(rules, mod_errors.ErrorsRuleFile{
+			Cond:    &ruleCond,
+			Actions: &mod_errors.ActionFileList{{Cmd: &cmd, Params: params}},
+		})
+	}
+	return rules, nil
+}
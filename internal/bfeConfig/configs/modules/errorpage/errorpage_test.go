@@ -0,0 +1,251 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package errorpage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_http"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_errors"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+func TestUpdateErrorsConf(t *testing.T) {
+	opts := moduletest.SetOptions(t)
+
+	pages := map[string]string{
+		annotations.ErrorsPagesConfigMapAnnotation: "pages",
+		annotations.ErrorsRedirectAnnotation:       `{"503": "https://status.example.com/"}`,
+	}
+	configMaps := []*corev1.ConfigMap{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pages"},
+		Data:       map[string]string{"500.html": "<h1>error</h1>", "404.json": `{"error": "not found"}`},
+	}}
+	defaults := map[string]string{annotations.ErrorsPageKeyPrefix + "503.html": "<h1>unavailable</h1>"}
+
+	type request struct {
+		path     string
+		code     int
+		want     string // action of the first matched rule, empty if no rule is matched
+		wantPage string // content of the page returned by the action
+	}
+	tests := []struct {
+		name       string
+		ingresses  []*netv1.Ingress
+		configMaps []*corev1.ConfigMap
+		global     map[string]string
+		wantErr    bool
+		wantRules  int
+		wantReload bool
+		// wantPages are the page files written to the disk, file name => content
+		wantPages map[string]string
+		requests  []request
+	}{
+		{
+			name:       "no error page is set",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "error pages of ingress",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", pages, "foo.com", "/foo")},
+			configMaps: configMaps,
+			wantRules:  3,
+			wantReload: true,
+			wantPages: map[string]string{
+				"default_a_404": `{"error": "not found"}`,
+				"default_a_500": "<h1>error</h1>",
+			},
+			requests: []request{
+				{path: "/foo", code: 404, want: mod_errors.RETURN, wantPage: `{"error": "not found"}`},
+				{path: "/foo", code: 500, want: mod_errors.RETURN, wantPage: "<h1>error</h1>"},
+				{path: "/foo", code: 503, want: mod_errors.REDIRECT},
+				{path: "/foo", code: 502, want: ""},
+				{path: "/bar", code: 404, want: ""},
+			},
+		},
+		{
+			name:       "global error pages",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			global:     defaults,
+			wantRules:  1,
+			wantReload: true,
+			wantPages: map[string]string{
+				globalPagePrefix + "_503": "<h1>unavailable</h1>",
+			},
+			requests: []request{
+				{path: "/foo", code: 503, want: mod_errors.RETURN, wantPage: "<h1>unavailable</h1>"},
+				{path: "/bar", code: 503, want: mod_errors.RETURN, wantPage: "<h1>unavailable</h1>"},
+			},
+		},
+		{
+			name: "global error pages for ingress without error pages with higher priority",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", pages, "foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo/bar"),
+			},
+			configMaps: configMaps,
+			global:     defaults,
+			wantRules:  4,
+			wantReload: true,
+			wantPages: map[string]string{
+				"default_a_404":           `{"error": "not found"}`,
+				"default_a_500":           "<h1>error</h1>",
+				globalPagePrefix + "_503": "<h1>unavailable</h1>",
+			},
+			requests: []request{
+				{path: "/foo/baz", code: 503, want: mod_errors.REDIRECT},
+				{path: "/foo/bar", code: 503, want: mod_errors.RETURN, wantPage: "<h1>unavailable</h1>"},
+				{path: "/foo/bar", code: 404, want: ""},
+			},
+		},
+		{
+			name:      "configmap not found",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", pages, "foo.com", "/foo")},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bfe := moduletest.NewBfe(t, opts)
+			c := NewErrorsConfig("init", configs.NewProductTable())
+			if err := c.UpdateGlobalConfig(tt.global); err != nil {
+				t.Fatalf("UpdateGlobalConfig() error = %v", err)
+			}
+			var err error
+			for _, ingress := range tt.ingresses {
+				if err = c.UpdateIngressWithConfigMaps(ingress, tt.configMaps); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateIngressWithConfigMaps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := c.Reload(); err != nil {
+				t.Fatalf("Reload() error = %v", err)
+			}
+
+			rules := *(*c.errorsConfFile.Config)[configs.DefaultProduct]
+			if got := len(rules); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := len(bfe.Reloads()) > 0; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+			if got := readPages(t, opts.Ingress.ConfigPath+PageFileDir); !reflect.DeepEqual(got, tt.wantPages) {
+				t.Errorf("pages = %v, want %v", got, tt.wantPages)
+			}
+
+			for _, r := range tt.requests {
+				req := moduletest.NewRequest("foo.com", r.path, "10.0.0.1")
+				req.HttpResponse = &bfe_http.Response{StatusCode: r.code}
+				got, gotPage := "", ""
+				for _, rule := range rules {
+					if moduletest.Match(t, *rule.Cond, req) {
+						action := (*rule.Actions)[0]
+						got = *action.Cmd
+						if got == mod_errors.RETURN {
+							gotPage = readFile(t, action.Params[2])
+						}
+						break
+					}
+				}
+				if got != r.want || gotPage != r.wantPage {
+					t.Errorf("response %d of %s: action = %q, page = %q, want %q, %q", r.code, r.path, got, gotPage, r.want, r.wantPage)
+				}
+			}
+		})
+	}
+}
+
+func TestDumpPageFiles(t *testing.T) {
+	opts := moduletest.SetOptions(t)
+	moduletest.NewBfe(t, opts)
+
+	annots := map[string]string{annotations.ErrorsPagesConfigMapAnnotation: "pages"}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pages"},
+		Data:       map[string]string{"500.html": "<h1>error</h1>"},
+	}
+	c := NewErrorsConfig("init", configs.NewProductTable())
+
+	// pages are updated with the ConfigMap
+	for _, page := range []string{"<h1>error</h1>", "<h1>internal error</h1>"} {
+		configMap.Data["500.html"] = page
+		ingress := moduletest.NewIngress("a", annots, "foo.com", "/foo")
+		if err := c.UpdateIngressWithConfigMaps(ingress, []*corev1.ConfigMap{configMap}); err != nil {
+			t.Fatalf("UpdateIngressWithConfigMaps() error = %v", err)
+		}
+		if err := c.Reload(); err != nil {
+			t.Fatalf("Reload() error = %v", err)
+		}
+		want := map[string]string{"default_a_500": page}
+		if got := readPages(t, opts.Ingress.ConfigPath+PageFileDir); !reflect.DeepEqual(got, want) {
+			t.Errorf("pages = %v, want %v", got, want)
+		}
+	}
+
+	// pages of deleted ingresses are removed
+	c.DeleteIngress("default", "a")
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := readPages(t, opts.Ingress.ConfigPath+PageFileDir); len(got) != 0 {
+		t.Errorf("pages = %v, want none", got)
+	}
+}
+
+// readPages returns files in dir, file name => content
+func readPages(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pages map[string]string
+	for _, file := range files {
+		if pages == nil {
+			pages = make(map[string]string)
+		}
+		pages[file.Name()] = readFile(t, filepath.Join(dir, file.Name()))
+	}
+	return pages
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
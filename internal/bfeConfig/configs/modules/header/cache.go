@@ -0,0 +1,218 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package header is the module of modifying request and response headers.
+// This file defines header rule & cache's struct, also implements update ingress method.
+package header
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bfenetworks/bfe/bfe_modules/mod_header"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+// variableCharset is the charset of variable names in header values
+const variableCharset = "abcdefghijklmnopqrstuvwxyz0123456789_"
+
+type headerRule struct {
+	*cache.BaseRule
+	// actions are set by annotations "header.*" and "static.expires" of the ingress
+	actions mod_header.ActionFileList
+	// securityHeaders are security response headers set by annotations of the ingress
+	securityHeaders *annotations.SecurityHeaders
+	// sslRedirectHSTS means HTTP requests are redirected to HTTPS with HSTS enabled
+	sslRedirectHSTS bool
+	// tlsHost means the host of the rule is listed in spec.tls
+	tlsHost bool
+}
+
+// buildActions returns the actions for requests over HTTP and HTTPS respectively.
+// Security headers go first, so that they can be overwritten by annotations "header.*".
+func (rule *headerRule) buildActions(defaults *annotations.SecurityHeaders) (mod_header.ActionFileList, mod_header.ActionFileList) {
+	var actions mod_header.ActionFileList
+
+	headers := rule.securityHeaders.Merge(defaults)
+	for _, header := range headers.ResponseHeaders() {
+		actions = append(actions, newAction("RSP_HEADER_SET", header[0], escapeValue(header[1])))
+	}
+	secureActions := append(mod_header.ActionFileList{}, actions...)
+
+	hsts := headers.HSTSEnabled()
+	if rule.securityHeaders.HSTS == nil && rule.sslRedirectHSTS {
+		hsts = true
+	}
+	// browsers ignore Strict-Transport-Security received over HTTP, and the header only makes sense for hosts with certificates
+	if hsts && rule.tlsHost {
+		secureActions = append(secureActions, newAction("RSP_HEADER_SET", annotations.HSTSHeader, headers.HSTSValue()))
+	}
+
+	actions = append(actions, rule.actions...)
+	secureActions = append(secureActions, rule.actions...)
+	return actions, secureActions
+}
+
+type headerRuleCache struct {
+	*cache.BaseCache
+	// defaults are the security headers set by the global ConfigMap
+	defaults *annotations.SecurityHeaders
+}
+
+func newHeaderRuleCache(version string) *headerRuleCache {
+	return &headerRuleCache{
+		BaseCache: cache.NewBaseCache(version),
+		defaults:  &annotations.SecurityHeaders{},
+	}
+}
+
+func (c *headerRuleCache) UpdateByIngress(ingress *netv1.Ingress) error {
+	securityHeaders, err := annotations.GetSecurityHeaders(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+	if err := securityHeaders.Merge(c.defaults).Check(); err != nil {
+		return err
+	}
+
+	sslRedirectHSTS, err := isSSLRedirectHSTS(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+
+	actions, err := getHeaderActions(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+
+	// header Cache-Control of static files goes first, so that it can be overwritten by annotations "header.*"
+	static, err := annotations.GetStatic(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+	if static != nil && len(static.CacheControl()) > 0 {
+		actions = append(mod_header.ActionFileList{newAction("RSP_HEADER_SET", "Cache-Control", static.CacheControl())}, actions...)
+	}
+
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &headerRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				actions:         actions,
+				securityHeaders: securityHeaders,
+				sslRedirectHSTS: sslRedirectHSTS,
+				tlsHost:         util.IsTLSHost(host, ingress.Spec.TLS),
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
+
+// UpdateDefaults updates the default security headers, which should be valid with the security headers of all ingresses
+func (c *headerRuleCache) UpdateDefaults(defaults *annotations.SecurityHeaders) error {
+	if err := defaults.Check(); err != nil {
+		return err
+	}
+	for _, rule := range c.GetRules() {
+		rule := rule.(*headerRule)
+		if err := rule.securityHeaders.Merge(defaults).Check(); err != nil {
+			return fmt.Errorf("conflict with ingress %s: %s", rule.GetIngress(), err)
+		}
+	}
+
+	c.defaults = defaults
+	c.Version = util.NewVersion()
+	return nil
+}
+
+// isSSLRedirectHSTS returns true if the Strict-Transport-Security header should be sent for hosts in spec.tls,
+// which only takes effect when HTTP requests of these hosts are redirected to HTTPS
+func isSSLRedirectHSTS(annots map[string]string) (bool, error) {
+	sslRedirect, err := annotations.GetSSLRedirect(annots, option.Opts.Ingress.SSLRedirect)
+	if err != nil || !sslRedirect {
+		return false, err
+	}
+	return annotations.GetSSLRedirectHSTS(annots, option.Opts.Ingress.SSLRedirectHSTS)
+}
+
+// getHeaderActions parse annotations "header.*" into actions of mod_header
+func getHeaderActions(annots map[string]string) (mod_header.ActionFileList, error) {
+	headerActions, err := annotations.GetHeaderActions(annots)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make(mod_header.ActionFileList, 0, len(headerActions))
+	for _, action := range headerActions {
+		if len(action.Params) == 2 {
+			if err := checkVariables(action.Params[1]); err != nil {
+				return nil, err
+			}
+		}
+		actions = append(actions, newAction(action.Cmd, action.Params...))
+	}
+	if err := mod_header.ActionFileListCheck(&actions); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// checkVariables checks variables in header value, e.g. "%bfe_client_ip".
+// "%%" is used for a literal "%".
+func checkVariables(value string) error {
+	for i := 0; i < len(value); i++ {
+		if value[i] != '%' {
+			continue
+		}
+		if i+1 < len(value) && value[i+1] == '%' {
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j < len(value) && strings.IndexByte(variableCharset, value[j]) >= 0 {
+			j++
+		}
+		if _, ok := mod_header.VariableHandlers[value[i+1:j]]; !ok {
+			return fmt.Errorf("unknown variable [%s] in header value [%s]", value[i:j], value)
+		}
+		i = j - 1
+	}
+	return nil
+}
+
+// escapeValue escapes "%" in header value, which is used as the prefix of variables
+func escapeValue(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+func newAction(cmd string, params ...string) mod_header.ActionFile {
+	return mod_header.ActionFile{
+		Cmd:    &cmd,
+		Params: params,
+	}
+}
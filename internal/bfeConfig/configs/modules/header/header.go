@@ -0,0 +1,199 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package header is the module of modifying request and response headers.
+// This file implements operate rule cache, generate and reload config file methods.
+package header
+
+import (
+	"fmt"
+
+	"github.com/bfenetworks/bfe/bfe_modules/mod_header"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+const (
+	ConfigNameHeader = "mod_header"
+	RuleData         = "mod_header/header_rule.data"
+
+	// placeholderHeader is deleted from requests by placeholder rules, which have no other actions
+	placeholderHeader = "X-Bfe-Ingress-Placeholder"
+)
+
+type ModHeaderConfig struct {
+	version         string
+	products        *configs.ProductTable
+	headerRuleCache *headerRuleCache
+	headerConfFile  *mod_header.HeaderConfFile
+}
+
+func NewHeaderConfig(version string, products *configs.ProductTable) *ModHeaderConfig {
+	return &ModHeaderConfig{
+		version:         version,
+		products:        products,
+		headerRuleCache: newHeaderRuleCache(version),
+		headerConfFile:  newHeaderConfFile(version),
+	}
+}
+
+func newHeaderConfFile(version string) *mod_header.HeaderConfFile {
+	ruleFileList := make(mod_header.RuleFileList, 0)
+	productRulesFile := make(mod_header.ProductRulesFile)
+	productRulesFile[configs.DefaultProduct] = &ruleFileList
+	return &mod_header.HeaderConfFile{
+		Version: &version,
+		Config:  &productRulesFile,
+	}
+}
+
+func (c *ModHeaderConfig) Name() string {
+	return ConfigNameHeader
+}
+
+func (c *ModHeaderConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.headerRuleCache.ContainsIngress(ingressName) {
+		c.headerRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.headerRuleCache.UpdateByIngress(ingress)
+}
+
+func (c *ModHeaderConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.headerRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.headerRuleCache.DeleteByIngress(ingressName)
+}
+
+// UpdateGlobalConfig implements modules.GlobalConfigHandler
+func (c *ModHeaderConfig) UpdateGlobalConfig(data map[string]string) error {
+	defaults, err := annotations.ParseSecurityHeaders(data)
+	if err != nil {
+		return err
+	}
+	return c.headerRuleCache.UpdateDefaults(defaults)
+}
+
+func (c *ModHeaderConfig) Reload() error {
+	if err := c.updateHeaderConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.headerConfFile.Version != c.version {
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.headerConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameHeader)
+		if err != nil {
+			return err
+		}
+		c.version = *c.headerConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModHeaderConfig) updateHeaderConf() error {
+	version := c.products.ConfVersion(c.headerRuleCache.Version)
+	if *c.headerConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.headerRuleCache.GetRules()
+	productRuleList := make(map[string]mod_header.RuleFileList)
+	// product -> length of the rule list without placeholders at the end
+	productRuleLen := make(map[string]int)
+	for _, rule := range ruleList {
+		rule := rule.(*headerRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+
+		actions, secureActions := rule.buildActions(c.headerRuleCache.defaults)
+		last := true
+		var headerRuleList mod_header.RuleFileList
+		// rule for requests over HTTPS goes first, since the rule list stops at the first matched rule
+		if len(secureActions) > len(actions) {
+			secureCond := cond + "&&req_proto_secure()"
+			if len(cond) == 0 {
+				secureCond = "req_proto_secure()"
+			}
+			headerRuleList = append(headerRuleList, mod_header.HeaderRuleFile{
+				Cond:    &secureCond,
+				Actions: &secureActions,
+				Last:    &last,
+			})
+		}
+		// rule without actions is kept as a placeholder, so that requests routed to the ingress
+		// don't match rules of ingresses with lower priority
+		placeholder := len(actions) == 0
+		if placeholder {
+			actions = mod_header.ActionFileList{newAction("REQ_HEADER_DEL", placeholderHeader)}
+		}
+		headerRuleList = append(headerRuleList, mod_header.HeaderRuleFile{
+			Cond:    &cond,
+			Actions: &actions,
+			Last:    &last,
+		})
+
+		for _, product := range c.products.Of(rule.GetHost()) {
+			productRuleList[product] = append(productRuleList[product], headerRuleList...)
+			if !placeholder || len(headerRuleList) > 1 {
+				productRuleLen[product] = len(productRuleList[product])
+			}
+		}
+	}
+
+	headerConfFile := newHeaderConfFile(version)
+	for product, headerRuleList := range productRuleList {
+		// placeholders at the end of the list are useless
+		headerRuleList := headerRuleList[:productRuleLen[product]]
+		if len(headerRuleList) == 0 {
+			continue
+		}
+		(*headerConfFile.Config)[product] = &headerRuleList
+	}
+	if err := mod_header.HeaderConfCheck(*headerConfFile); err != nil {
+		return err
+	}
+
+	// skip reloading BFE while no header is set, so mod_header only needs to be enabled in bfe.conf when used
+	if headerConfEmpty(headerConfFile) && headerConfEmpty(c.headerConfFile) {
+		c.version = version
+	}
+
+	c.headerConfFile = headerConfFile
+	return nil
+}
+
+// headerConfEmpty returns true if the config has no rule
+func headerConfEmpty(conf *mod_header.HeaderConfFile) bool {
+	return len(*conf.Config) == 1 && len(*(*conf.Config)[configs.DefaultProduct]) == 0
+}
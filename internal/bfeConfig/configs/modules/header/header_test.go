@@ -0,0 +1,100 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package header
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+// dumpRules returns rules of the default product as "<path of cond> <first action>"
+func dumpRules(c *ModHeaderConfig, paths ...string) []string {
+	var rules []string
+	for _, rule := range *(*c.headerConfFile.Config)[configs.DefaultProduct] {
+		path := "?"
+		for _, p := range paths {
+			if strings.Contains(*rule.Cond, `"`+p+`"`) {
+				path = p
+			}
+		}
+		action := (*rule.Actions)[0]
+		rules = append(rules, fmt.Sprintf("%s %s %v", path, *action.Cmd, action.Params))
+	}
+	return rules
+}
+
+func TestUpdateHeaderConf(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	headers := map[string]string{annotations.HeaderRequestSetAnnotation: `{"X-Tenant-Id": "a"}`}
+	placeholder := fmt.Sprintf("REQ_HEADER_DEL [%s]", placeholderHeader)
+	tests := []struct {
+		name      string
+		ingresses []*netv1.Ingress
+		want      []string
+	}{
+		{
+			name:      "no annotations",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			want:      nil,
+		},
+		{
+			name:      "header annotations",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", headers, "foo.com", "/foo")},
+			want:      []string{"/foo REQ_HEADER_SET [X-Tenant-Id a]"},
+		},
+		{
+			name: "higher priority ingress without annotations",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", nil, "foo.com", "/foo/bar"),
+				moduletest.NewIngress("b", headers, "foo.com", "/foo"),
+			},
+			want: []string{"/foo/bar " + placeholder, "/foo REQ_HEADER_SET [X-Tenant-Id a]"},
+		},
+		{
+			name: "lower priority ingress without annotations",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", headers, "foo.com", "/foo/bar"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo"),
+			},
+			want: []string{"/foo/bar REQ_HEADER_SET [X-Tenant-Id a]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewHeaderConfig("init", configs.NewProductTable())
+			for _, ingress := range tt.ingresses {
+				if err := c.UpdateIngress(ingress); err != nil {
+					t.Fatalf("UpdateIngress() error = %v", err)
+				}
+			}
+			if err := c.updateHeaderConf(); err != nil {
+				t.Fatalf("updateHeaderConf() error = %v", err)
+			}
+
+			got := dumpRules(c, "/foo", "/foo/bar")
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,10 +15,24 @@
 package modules
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/authbasic"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/authjwt"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/authrequest"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/block"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/compress"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/cors"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/errorpage"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/header"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/prison"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/redirect"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/rewrite"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/static"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/trustclientip"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/waf"
 )
 
 // BFEModuleConfig is an abstraction of the BFE module configuration.
@@ -37,10 +51,58 @@ type BFEModuleConfig interface {
 	Name() string
 }
 
-func InitBFEModules(version string) []BFEModuleConfig {
+// GlobalConfigHandler is implemented by the BFEModuleConfig which reads default values from the global ConfigMap.
+// The ConfigBuilder will call UpdateGlobalConfig when the global ConfigMap is updated or deleted.
+type GlobalConfigHandler interface {
+	// UpdateGlobalConfig uses data of the global ConfigMap to update the default values, data is nil if the ConfigMap is deleted.
+	// If an error is returned, the BFEModuleConfig should keep using the previous default values.
+	UpdateGlobalConfig(data map[string]string) error
+}
+
+// ConfigMapReferrer is implemented by the BFEModuleConfig which reads data from ConfigMaps referenced by annotations of ingresses.
+// The ConfigBuilder will call UpdateIngressWithConfigMaps instead of UpdateIngress.
+type ConfigMapReferrer interface {
+	// UpdateIngressWithConfigMaps uses the ingress and the ConfigMaps referenced by it to update the BFEModuleConfig
+	UpdateIngressWithConfigMaps(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap) error
+}
+
+// SecretReferrer is implemented by the BFEModuleConfig which reads data from Secrets referenced by annotations of ingresses.
+// The ConfigBuilder will call UpdateIngressWithSecrets instead of UpdateIngress, and call UpdateSecret or DeleteSecret
+// when a Secret is updated or deleted.
+type SecretReferrer interface {
+	// UpdateIngressWithSecrets uses the ingress and the Secrets referenced by it to update the BFEModuleConfig
+	UpdateIngressWithSecrets(ingress *netv1.Ingress, secrets []*corev1.Secret) error
+
+	// UpdateSecret updates the data of the Secret if it is referenced by ingresses, otherwise the Secret is ignored
+	UpdateSecret(secret *corev1.Secret) error
+
+	// DeleteSecret clears the data of the Secret if it is referenced by ingresses, so requests relying on it are rejected
+	DeleteSecret(namespace, name string)
+}
+
+// ConfigMapSecretReferrer is implemented by the BFEModuleConfig which reads data from both ConfigMaps and Secrets referenced by annotations of ingresses.
+// The ConfigBuilder will call UpdateIngressWithReferences instead of UpdateIngressWithConfigMaps, UpdateIngressWithSecrets and UpdateIngress.
+type ConfigMapSecretReferrer interface {
+	// UpdateIngressWithReferences uses the ingress and the ConfigMaps and Secrets referenced by it to update the BFEModuleConfig
+	UpdateIngressWithReferences(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap, secrets []*corev1.Secret) error
+}
+
+func InitBFEModules(version string, products *configs.ProductTable) []BFEModuleConfig {
 	var modules []BFEModuleConfig
 	// mod_redirect
-	modules = append(modules, redirect.NewRedirectConfig(version))
-	modules = append(modules, rewrite.NewRewriteConfig(version))
+	modules = append(modules, redirect.NewRedirectConfig(version, products))
+	modules = append(modules, rewrite.NewRewriteConfig(version, products))
+	modules = append(modules, header.NewHeaderConfig(version, products))
+	modules = append(modules, cors.NewCorsConfig(version, products))
+	modules = append(modules, prison.NewPrisonConfig(version, products))
+	modules = append(modules, block.NewBlockConfig(version, products))
+	modules = append(modules, trustclientip.NewTrustClientIPConfig(version))
+	modules = append(modules, authbasic.NewAuthBasicConfig(version, products))
+	modules = append(modules, authjwt.NewAuthJWTConfig(version, products))
+	modules = append(modules, authrequest.NewAuthRequestConfig(version, products))
+	modules = append(modules, errorpage.NewErrorsConfig(version, products))
+	modules = append(modules, compress.NewCompressConfig(version, products))
+	modules = append(modules, waf.NewWafConfig(version, products))
+	modules = append(modules, static.NewStaticConfig(version, products))
 	return modules
 }
@@ -0,0 +1,112 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package moduletest provides helpers for tests of modules.
+package moduletest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_http"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+// NewIngress returns an ingress in namespace "default", routing prefix paths of host to service "svc"
+func NewIngress(name string, annots map[string]string, host string, paths ...string) *netv1.Ingress {
+	pathType := netv1.PathTypePrefix
+	rule := netv1.IngressRule{
+		Host:             host,
+		IngressRuleValue: netv1.IngressRuleValue{HTTP: &netv1.HTTPIngressRuleValue{}},
+	}
+	for _, path := range paths {
+		rule.HTTP.Paths = append(rule.HTTP.Paths, netv1.HTTPIngressPath{
+			Path:     path,
+			PathType: &pathType,
+			Backend:  netv1.IngressBackend{Service: &netv1.IngressServiceBackend{Name: "svc"}},
+		})
+	}
+
+	return &netv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Annotations: annots},
+		Spec:       netv1.IngressSpec{Rules: []netv1.IngressRule{rule}},
+	}
+}
+
+// SetOptions sets default options of the controller during the test
+func SetOptions(t *testing.T) *option.Options {
+	opts := option.NewOptions()
+	option.Opts = opts
+	t.Cleanup(func() { option.Opts = nil })
+	return opts
+}
+
+// NewRequest returns a request of host and path from clientIP, which is used to check conditions of rules
+func NewRequest(host, path, clientIP string) *bfe_basic.Request {
+	return &bfe_basic.Request{
+		Session:     &bfe_basic.Session{},
+		HttpRequest: &bfe_http.Request{Host: host, URL: &url.URL{Path: path}, Header: make(bfe_http.Header)},
+		ClientAddr:  &net.TCPAddr{IP: net.ParseIP(clientIP)},
+	}
+}
+
+// Match returns true if the condition matches the request, the test fails if the condition is illegal
+func Match(t *testing.T, cond string, req *bfe_basic.Request) bool {
+	t.Helper()
+	c, err := condition.Build(cond)
+	if err != nil {
+		t.Fatalf("condition.Build(%s) error = %v", cond, err)
+	}
+	return c.Match(req)
+}
+
+// Bfe is a fake BFE process, config files are dumped to a temporary directory and all reloads succeed
+type Bfe struct {
+	lock    sync.Mutex
+	reloads []string
+}
+
+// NewBfe sets the config path and the reload URL of opts to a fake BFE process during the test
+func NewBfe(t *testing.T, opts *option.Options) *Bfe {
+	b := &Bfe{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		b.reloads = append(b.reloads, strings.TrimPrefix(r.URL.Path, "/reload/"))
+	}))
+	t.Cleanup(server.Close)
+
+	opts.Ingress.ConfigPath = t.TempDir() + "/"
+	opts.Ingress.ReloadUrl = server.URL + "/reload/"
+	return b
+}
+
+// Reloads returns names of configs reloaded since the last call
+func (b *Bfe) Reloads() []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	reloads := b.reloads
+	b.reloads = nil
+	return reloads
+}
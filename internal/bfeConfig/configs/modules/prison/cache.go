@@ -0,0 +1,66 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prison is the module of rate limiting.
+// This file defines prison rule & cache's struct, also implements update ingress method.
+package prison
+
+import (
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+type prisonRule struct {
+	*cache.BaseRule
+	// rateLimit is the rate limit of the ingress. Refer to https://www.bfe-networks.net/en_us/modules/mod_prison/mod_prison/.
+	rateLimit *annotations.RateLimit
+}
+
+type prisonRuleCache struct {
+	*cache.BaseCache
+}
+
+func newPrisonRuleCache(version string) *prisonRuleCache {
+	return &prisonRuleCache{
+		BaseCache: cache.NewBaseCache(version),
+	}
+}
+
+func (c prisonRuleCache) UpdateByIngress(ingress *netv1.Ingress) error {
+	rateLimit, err := annotations.GetRateLimit(ingress.Annotations)
+	if err != nil || rateLimit == nil {
+		return err
+	}
+
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &prisonRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				rateLimit: rateLimit,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prison is the module of rate limiting.
+// This file implements operate rule cache, generate and reload config file methods.
+package prison
+
+import (
+	"fmt"
+
+	"github.com/bfenetworks/bfe/bfe_basic/action"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_prison"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+const (
+	ConfigNamePrison = "mod_prison"
+	RuleData         = "mod_prison/prison.data"
+)
+
+const (
+	// requests are counted in windows of one second
+	checkPeriod int64 = 1
+	// requests are allowed again in the next window
+	stayPeriod int64 = 0
+	// dictSize is the max number of keys (e.g. client IPs) tracked by a rule
+	dictSize = 100000
+)
+
+// prisonActions maps actions of the rate limit annotation to actions of mod_prison.
+// mod_prison can't reply with a custom status code, so only closing the connection is supported.
+var prisonActions = map[string]string{
+	annotations.RateLimitActionClose: action.ActionClose,
+}
+
+type ModPrisonConfig struct {
+	version         string
+	products        *configs.ProductTable
+	prisonRuleCache *prisonRuleCache
+	prisonConfFile  *mod_prison.ProductRuleConf
+}
+
+func NewPrisonConfig(version string, products *configs.ProductTable) *ModPrisonConfig {
+	return &ModPrisonConfig{
+		version:         version,
+		products:        products,
+		prisonRuleCache: newPrisonRuleCache(version),
+		prisonConfFile:  newPrisonConfFile(version),
+	}
+}
+
+func newPrisonConfFile(version string) *mod_prison.ProductRuleConf {
+	ruleList := make(mod_prison.PrisonRuleConfList, 0)
+	productRuleList := map[string]*mod_prison.PrisonRuleConfList{
+		configs.DefaultProduct: &ruleList,
+	}
+	return &mod_prison.ProductRuleConf{
+		Version: &version,
+		Config:  &productRuleList,
+	}
+}
+
+func (c *ModPrisonConfig) Name() string {
+	return ConfigNamePrison
+}
+
+func (c *ModPrisonConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.prisonRuleCache.ContainsIngress(ingressName) {
+		c.prisonRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.prisonRuleCache.UpdateByIngress(ingress)
+}
+
+func (c *ModPrisonConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.prisonRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.prisonRuleCache.DeleteByIngress(ingressName)
+}
+
+func (c *ModPrisonConfig) Reload() error {
+	if err := c.updatePrisonConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.prisonConfFile.Version != c.version {
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.prisonConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNamePrison)
+		if err != nil {
+			return err
+		}
+		c.version = *c.prisonConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModPrisonConfig) updatePrisonConf() error {
+	version := c.products.ConfVersion(c.prisonRuleCache.Version)
+	if *c.prisonConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.prisonRuleCache.GetRules()
+	productRuleList := make(map[string]mod_prison.PrisonRuleConfList)
+	// conditions of rules with higher priority
+	var higherConds cache.PriorityConds
+	for _, rule := range ruleList {
+		rule := rule.(*prisonRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+
+		// mod_prison checks every matched rule, so a request is only counted by the rule with the highest priority
+		host := rule.GetHost()
+		ruleCond := higherConds.Exclude(rule, cond)
+		higherConds.Add(rule, cond)
+
+		for _, product := range c.products.Of(host) {
+			productRuleList[product] = append(productRuleList[product], newPrisonRuleConf(
+				fmt.Sprintf("%s:%s%s", rule.GetIngress(), host, rule.GetPath()),
+				ruleCond,
+				rule.rateLimit,
+			))
+		}
+	}
+
+	// skip reloading BFE while no ingress uses rate limit, so mod_prison only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(*c.prisonConfFile.Config) == 1 && len(*(*c.prisonConfFile.Config)[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	prisonConfFile := newPrisonConfFile(version)
+	for product, prisonRuleList := range productRuleList {
+		prisonRuleList := prisonRuleList
+		(*prisonConfFile.Config)[product] = &prisonRuleList
+	}
+	if err := mod_prison.ProductRulesCheck(*prisonConfFile.Config); err != nil {
+		return err
+	}
+
+	c.prisonConfFile = prisonConfFile
+	return nil
+}
+
+func newPrisonRuleConf(name, cond string, rateLimit *annotations.RateLimit) *mod_prison.PrisonRuleConf {
+	// requests of the same host and key are counted together
+	signConf := &mod_prison.AccessSignConf{
+		UseHost: true,
+	}
+	switch rateLimit.KeyType {
+	case annotations.RateLimitKeyClientIP:
+		signConf.UseClientIP = true
+	case annotations.RateLimitKeyPath:
+		signConf.UsePath = true
+	case annotations.RateLimitKeyHeader:
+		signConf.Header = []string{rateLimit.KeyName}
+	case annotations.RateLimitKeyCookie:
+		signConf.Cookie = []string{rateLimit.KeyName}
+	}
+
+	checkPeriod, stayPeriod := checkPeriod, stayPeriod
+	threshold := rateLimit.Threshold()
+	accessDictSize, prisonDictSize := dictSize, dictSize
+	return &mod_prison.PrisonRuleConf{
+		Cond:           &cond,
+		Action:         &action.Action{Cmd: prisonActions[rateLimit.Action], Params: []string{}},
+		AccessSignConf: signConf,
+		Name:           &name,
+		CheckPeriod:    &checkPeriod,
+		StayPeriod:     &stayPeriod,
+		Threshold:      &threshold,
+		AccessDictSize: &accessDictSize,
+		PrisonDictSize: &prisonDictSize,
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package prison
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_basic"
+	"github.com/bfenetworks/bfe/bfe_basic/action"
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_http"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+func TestUpdatePrisonConf(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	rateLimit := map[string]string{annotations.RateLimitRPSAnnotation: "10"}
+	headerKey := map[string]string{
+		annotations.RateLimitRPSAnnotation:   "10",
+		annotations.RateLimitBurstAnnotation: "5",
+		annotations.RateLimitKeyAnnotation:   "header:X-Api-Key",
+	}
+	tests := []struct {
+		name       string
+		ingresses  []*netv1.Ingress
+		wantRules  int
+		wantReload bool
+		// host and path of a request, and the name of the only rule which should count it
+		host, path string
+		wantMatch  string
+	}{
+		{
+			name:       "no ingress uses rate limit",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "ingress with rate limit",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", rateLimit, "foo.com", "/foo", "/bar")},
+			wantRules:  2,
+			wantReload: true,
+			host:       "foo.com",
+			path:       "/bar",
+			wantMatch:  "default/a:foo.com/bar*",
+		},
+		{
+			name: "request counted by the rule of exact host",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", rateLimit, "*.foo.com", "/foo"),
+				moduletest.NewIngress("b", headerKey, "a.foo.com", "/foo"),
+			},
+			wantRules:  2,
+			wantReload: true,
+			host:       "a.foo.com",
+			path:       "/foo",
+			wantMatch:  "default/b:a.foo.com/foo*",
+		},
+		{
+			name: "request counted by the rule of longer path",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", rateLimit, "", "/"),
+				moduletest.NewIngress("b", rateLimit, "", "/foo"),
+			},
+			wantRules:  2,
+			wantReload: true,
+			host:       "b.foo.com",
+			path:       "/foo/bar",
+			wantMatch:  "default/b:*/foo*",
+		},
+		{
+			name: "request of other hosts",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", rateLimit, "*.foo.com", "/foo"),
+				moduletest.NewIngress("b", headerKey, "a.foo.com", "/foo"),
+			},
+			wantRules:  2,
+			wantReload: true,
+			host:       "b.foo.com",
+			path:       "/foo",
+			wantMatch:  "default/a:*.foo.com/foo*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewPrisonConfig("init", configs.NewProductTable())
+			for _, ingress := range tt.ingresses {
+				if err := c.UpdateIngress(ingress); err != nil {
+					t.Fatalf("UpdateIngress() error = %v", err)
+				}
+			}
+			if err := c.updatePrisonConf(); err != nil {
+				t.Fatalf("updatePrisonConf() error = %v", err)
+			}
+
+			rules := *(*c.prisonConfFile.Config)[configs.DefaultProduct]
+			if got := len(rules); got != tt.wantRules {
+				t.Fatalf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := *c.prisonConfFile.Version != c.version; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+			if len(tt.wantMatch) == 0 {
+				return
+			}
+
+			req := &bfe_basic.Request{
+				Session:     &bfe_basic.Session{},
+				HttpRequest: &bfe_http.Request{Host: tt.host, URL: &url.URL{Path: tt.path}},
+			}
+			for _, rule := range rules {
+				if rule.Action.Cmd != action.ActionClose {
+					t.Errorf("rule %s action = %s, want %s", *rule.Name, rule.Action.Cmd, action.ActionClose)
+				}
+				cond, err := condition.Build(*rule.Cond)
+				if err != nil {
+					t.Fatalf("condition.Build(%s) error = %v", *rule.Cond, err)
+				}
+				if got, want := cond.Match(req), *rule.Name == tt.wantMatch; got != want {
+					t.Errorf("rule %s matched = %v, want %v", *rule.Name, got, want)
+				}
+			}
+		})
+	}
+}
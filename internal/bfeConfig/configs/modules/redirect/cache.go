@@ -15,10 +15,13 @@
 package redirect
 
 import (
+	"fmt"
+
 	"github.com/bfenetworks/bfe/bfe_modules/mod_redirect"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
 	netv1 "k8s.io/api/networking/v1"
 
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
 )
@@ -29,6 +32,23 @@ type redirectRule struct {
 	statusCode int
 	// action is the redirect action. Refer to https://www.bfe-networks.net/en_us/modules/mod_redirect/mod_redirect/.
 	action *mod_redirect.ActionFileList
+	// insecureOnly means the rule only matches requests over HTTP, it's used to redirect HTTP requests to HTTPS
+	insecureOnly bool
+}
+
+// GetCond returns the condition of the rule.
+// Rules redirecting HTTP requests to HTTPS skip requests over HTTPS and ACME HTTP-01 challenges.
+func (rule redirectRule) GetCond() (string, error) {
+	cond, err := rule.BaseRule.GetCond()
+	if err != nil || !rule.insecureOnly {
+		return cond, err
+	}
+
+	insecure := fmt.Sprintf("!req_proto_secure()&&!req_path_prefix_in(\"%s\", false)", configs.AcmeChallengePath)
+	if len(cond) == 0 {
+		return insecure, nil
+	}
+	return fmt.Sprintf("%s&&%s", cond, insecure), nil
 }
 
 type redirectRuleCache struct {
@@ -50,6 +70,10 @@ func (c redirectRuleCache) UpdateByIngress(ingress *netv1.Ingress) error {
 	if err != nil {
 		return err
 	}
+	if cmd == "" {
+		return c.updateSSLRedirectByIngress(ingress)
+	}
+
 	statusCode, err := annotations.GetRedirectStatusCode(ingress.Annotations)
 	if err != nil {
 		return err
@@ -90,3 +114,47 @@ func (c redirectRuleCache) UpdateByIngress(ingress *netv1.Ingress) error {
 		nil,
 	)
 }
+
+// updateSSLRedirectByIngress generates rules redirecting HTTP requests of hosts in spec.tls to HTTPS
+func (c redirectRuleCache) updateSSLRedirectByIngress(ingress *netv1.Ingress) error {
+	enabled, err := isSSLRedirect(ingress.Annotations)
+	if err != nil || !enabled || len(ingress.Spec.TLS) == 0 {
+		return err
+	}
+
+	statusCode, err := annotations.GetSSLRedirectStatusCode(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+	if err := checkStatusCode(statusCode); err != nil {
+		return err
+	}
+
+	cmd, param := "SCHEME_SET", "https"
+	return c.BaseCache.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			if !util.IsTLSHost(host, ingress.Spec.TLS) {
+				return nil, nil
+			}
+
+			return &redirectRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				statusCode: statusCode,
+				action: &mod_redirect.ActionFileList{mod_redirect.ActionFile{
+					Cmd:    &cmd,
+					Params: []string{param},
+				}},
+				insecureOnly: true,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
 )
 
 const (
@@ -34,13 +35,15 @@ const (
 
 type ModRedirectConfig struct {
 	version           string
+	products          *configs.ProductTable
 	redirectRuleCache *redirectRuleCache
 	redirectConfFile  *mod_redirect.RedirectConfFile
 }
 
-func NewRedirectConfig(version string) *ModRedirectConfig {
+func NewRedirectConfig(version string, products *configs.ProductTable) *ModRedirectConfig {
 	return &ModRedirectConfig{
 		version:           version,
+		products:          products,
 		redirectRuleCache: newRedirectRuleCache(version),
 		redirectConfFile:  newRedirectConfFile(version),
 	}
@@ -69,28 +72,34 @@ func (r *ModRedirectConfig) UpdateIngress(ingress *netv1.Ingress) error {
 }
 
 func (r *ModRedirectConfig) updateRedirectConfFile() error {
-	if *r.redirectConfFile.Version == r.redirectRuleCache.Version {
+	version := r.products.ConfVersion(r.redirectRuleCache.Version)
+	if *r.redirectConfFile.Version == version {
 		// if the version is the same, no need to update
 		return nil
 	}
 
 	ruleList := r.redirectRuleCache.GetRules()
-	redirectRuleList := make(mod_redirect.RuleFileList, 0, len(ruleList))
+	productRuleList := make(map[string]mod_redirect.RuleFileList)
 	for _, rule := range ruleList {
 		rule := rule.(*redirectRule)
 		cond, err := rule.GetCond()
 		if err != nil {
 			return err
 		}
-		redirectRuleList = append(redirectRuleList, mod_redirect.RedirectRuleFile{
-			Cond:    &cond,
-			Actions: rule.action,
-			Status:  &(rule.statusCode),
-		})
+		for _, product := range r.products.Of(rule.GetHost()) {
+			productRuleList[product] = append(productRuleList[product], mod_redirect.RedirectRuleFile{
+				Cond:    &cond,
+				Actions: rule.action,
+				Status:  &(rule.statusCode),
+			})
+		}
 	}
 
-	redirectConfFile := newRedirectConfFile(r.redirectRuleCache.Version)
-	(*redirectConfFile.Config)[configs.DefaultProduct] = &redirectRuleList
+	redirectConfFile := newRedirectConfFile(version)
+	for product, redirectRuleList := range productRuleList {
+		redirectRuleList := redirectRuleList
+		(*redirectConfFile.Config)[product] = &redirectRuleList
+	}
 	if err := mod_redirect.RedirectConfCheck(*redirectConfFile); err != nil {
 		return err
 	}
@@ -154,12 +163,18 @@ func parseRedirectActionFromAnnotations(annots map[string]string) (string, strin
 
 	switch {
 	case cnt == 0:
+		if sslRedirect, _ := isSSLRedirect(annots); sslRedirect {
+			return "", "", nil
+		}
 		if annots[annotations.RedirectResponseStatusAnnotation] != "" {
 			return "", "", fmt.Errorf("unexpected annotation: {%s:%s}", annotations.RedirectResponseStatusAnnotation, annots[annotations.RedirectResponseStatusAnnotation])
 		}
 		return "", "", nil
 
 	case cnt == 1:
+		if sslRedirect, _ := annotations.GetSSLRedirect(annots, false); sslRedirect {
+			return "", "", fmt.Errorf("setting %s with other redirection-related annotations at the same time is not supported", annotations.RedirectSSLAnnotation)
+		}
 		cmd, param, err := annotations.GetRedirectAction(annots)
 		if err != nil {
 			return "", "", err
@@ -171,6 +186,11 @@ func parseRedirectActionFromAnnotations(annots map[string]string) (string, strin
 	}
 }
 
+// isSSLRedirect returns true if HTTP requests of hosts in spec.tls should be redirected to HTTPS
+func isSSLRedirect(annots map[string]string) (bool, error) {
+	return annotations.GetSSLRedirect(annots, option.Opts.Ingress.SSLRedirect)
+}
+
 // checkAction checks the redirect action and returns the error if the action is invalid
 func checkAction(cmd, param string) error {
 	switch cmd {
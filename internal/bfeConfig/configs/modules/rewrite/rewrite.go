@@ -12,8 +12,8 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-//Package rewrite is the module of rewrite url.
-//This file implements operate rule cache, generate and reload config file methods.
+// Package rewrite is the module of rewrite url.
+// This file implements operate rule cache, generate and reload config file methods.
 package rewrite
 
 import (
@@ -34,13 +34,15 @@ const (
 
 type ModRewriteConfig struct {
 	version          string
+	products         *configs.ProductTable
 	rewriteRuleCache *rewriteRuleCache
 	rewriteConfFile  *mod_rewrite.ReWriteConfFile
 }
 
-func NewRewriteConfig(version string) *ModRewriteConfig {
+func NewRewriteConfig(version string, products *configs.ProductTable) *ModRewriteConfig {
 	return &ModRewriteConfig{
 		version:          version,
+		products:         products,
 		rewriteRuleCache: newRewriteRuleCache(version),
 		rewriteConfFile:  newRewriteConfFile(version),
 	}
@@ -106,12 +108,14 @@ func (c *ModRewriteConfig) Reload() error {
 }
 
 func (c *ModRewriteConfig) updateRewriteConf() error {
-	if *c.rewriteConfFile.Version == c.rewriteRuleCache.Version {
+	version := c.products.ConfVersion(c.rewriteRuleCache.Version)
+	if *c.rewriteConfFile.Version == version {
 		return nil
 	}
 
 	ruleList := c.rewriteRuleCache.GetRules()
-	segmentRules := make(map[string]mod_rewrite.RuleFileList)
+	// callback point -> product -> rules
+	segmentRules := make(map[string]map[string]mod_rewrite.RuleFileList)
 	for _, rule := range ruleList {
 		rule := rule.(*rewriteRule)
 		cond, err := rule.GetCond()
@@ -119,16 +123,18 @@ func (c *ModRewriteConfig) updateRewriteConf() error {
 			return err
 		}
 		if _, ok := segmentRules[rule.when]; !ok {
-			segmentRules[rule.when] = make(mod_rewrite.RuleFileList, 0, len(ruleList))
+			segmentRules[rule.when] = make(map[string]mod_rewrite.RuleFileList)
+		}
+		for _, product := range c.products.Of(rule.GetHost()) {
+			segmentRules[rule.when][product] = append(segmentRules[rule.when][product], mod_rewrite.ReWriteRuleFile{
+				Cond:    &cond,
+				Actions: rule.actions,
+				Last:    rule.last,
+			})
 		}
-		segmentRules[rule.when] = append(segmentRules[rule.when], mod_rewrite.ReWriteRuleFile{
-			Cond:    &cond,
-			Actions: rule.actions,
-			Last:    rule.last,
-		})
 	}
 
-	rewriteConfFile := newRewriteConfFile(c.rewriteRuleCache.Version)
+	rewriteConfFile := newRewriteConfFile(version)
 	// map rule to config segment through callback point
 	for cb := range segmentRules {
 		err := annotations.CheckAllowedCallBack(cb)
@@ -136,8 +142,10 @@ func (c *ModRewriteConfig) updateRewriteConf() error {
 			return err
 		}
 	}
-	afterLocationRules := segmentRules[annotations.DefaultCallBackPoint]
-	(*rewriteConfFile.Config)[configs.DefaultProduct] = &afterLocationRules
+	for product, afterLocationRules := range segmentRules[annotations.DefaultCallBackPoint] {
+		afterLocationRules := afterLocationRules
+		(*rewriteConfFile.Config)[product] = &afterLocationRules
+	}
 
 	if err := mod_rewrite.ReWriteConfCheck(*rewriteConfFile); err != nil {
 		return err
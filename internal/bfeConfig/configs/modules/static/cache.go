@@ -0,0 +1,130 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package static is the module of serving static files.
+// This file defines static rule & cache's struct, also implements update ingress and mime types methods.
+package static
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+type staticRule struct {
+	*cache.BaseRule
+	// static is nil if the ingress serves no static file, such rule stops rules with lower priority
+	static *annotations.Static
+	// files are the data of the ConfigMap referenced by annotation "static.configmap", file name => content
+	files map[string][]byte
+}
+
+type staticRuleCache struct {
+	*cache.BaseCache
+	// mimeTypes are the content types of file extensions set by the global ConfigMap
+	mimeTypes map[string]string
+	// mimeTypesVersion is changed when mimeTypes is updated
+	mimeTypesVersion string
+}
+
+func newStaticRuleCache(version string) *staticRuleCache {
+	return &staticRuleCache{
+		BaseCache:        cache.NewBaseCache(version),
+		mimeTypes:        make(map[string]string),
+		mimeTypesVersion: version,
+	}
+}
+
+func (c *staticRuleCache) UpdateByIngress(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap) error {
+	static, err := annotations.GetStatic(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+
+	var files map[string][]byte
+	if static != nil && len(static.ConfigMap) > 0 {
+		configMap := findConfigMap(configMaps, ingress.Namespace, static.ConfigMap)
+		if configMap == nil {
+			return fmt.Errorf("configmap %s not found", util.NamespacedName(ingress.Namespace, static.ConfigMap))
+		}
+		files = getConfigMapFiles(configMap)
+		if len(files) == 0 {
+			return fmt.Errorf("annotation %s is illegal, configmap %s has no file", annotations.StaticConfigMapAnnotation, static.ConfigMap)
+		}
+		if _, ok := files[static.DefaultFile]; len(static.DefaultFile) > 0 && !ok {
+			return fmt.Errorf("annotation %s is illegal, file %s not found in configmap %s", annotations.StaticDefaultFileAnnotation, static.DefaultFile, static.ConfigMap)
+		}
+	}
+
+	// rules of ingresses without static files are also cached, since mod_static stops at the first matched rule
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			if len(files) > 0 && cache.IsRegexPath(path) {
+				return nil, fmt.Errorf("annotation %s is illegal, files of configmap can't be served on regex path %s", annotations.StaticConfigMapAnnotation, strings.TrimPrefix(path, cache.RegexPathPrefix))
+			}
+			return &staticRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				static: static,
+				files:  files,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
+
+// UpdateMimeTypes updates the content types of file extensions from the global ConfigMap
+func (c *staticRuleCache) UpdateMimeTypes(mimeTypes map[string]string) {
+	if reflect.DeepEqual(c.mimeTypes, mimeTypes) {
+		return
+	}
+
+	c.mimeTypes = mimeTypes
+	c.mimeTypesVersion = util.NewVersion()
+}
+
+// getConfigMapFiles returns files in both data and binaryData of the ConfigMap
+func getConfigMapFiles(configMap *corev1.ConfigMap) map[string][]byte {
+	files := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+	for name, content := range configMap.Data {
+		files[name] = []byte(content)
+	}
+	for name, content := range configMap.BinaryData {
+		files[name] = content
+	}
+	return files
+}
+
+func findConfigMap(configMaps []*corev1.ConfigMap, namespace, name string) *corev1.ConfigMap {
+	for _, configMap := range configMaps {
+		if configMap.Namespace == namespace && configMap.Name == name {
+			return configMap
+		}
+	}
+	return nil
+}
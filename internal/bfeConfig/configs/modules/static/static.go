@@ -0,0 +1,312 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package static is the module of serving static files.
+// This file implements operate rule cache, generate and reload config file methods.
+package static
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_static"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+const (
+	ConfigNameStatic = "mod_static"
+	RuleData         = "mod_static/static_rule.data"
+	MimeTypeData     = "mod_static/mime_type.data"
+	// RootDir is the directory of files from ConfigMaps, e.g. mod_static/roots/default_app/index.html
+	RootDir = "mod_static/roots/"
+
+	// names of the reload handlers of mod_static
+	reloadNameRule     = ConfigNameStatic
+	reloadNameMimeType = ConfigNameStatic + ".mime_type"
+)
+
+type ModStaticConfig struct {
+	version          string
+	products         *configs.ProductTable
+	mimeTypesVersion string
+	staticRuleCache  *staticRuleCache
+	staticConfFile   *mod_static.StaticConfFile
+	// files are the static files from ConfigMaps referenced by staticConfFile, file name => content
+	files map[string][]byte
+	// dumpedFiles are the static files on the disk
+	dumpedFiles map[string][]byte
+	// dumpedMimeTypes are the content types on the disk
+	dumpedMimeTypes map[string]string
+}
+
+func NewStaticConfig(version string, products *configs.ProductTable) *ModStaticConfig {
+	return &ModStaticConfig{
+		version:          version,
+		products:         products,
+		mimeTypesVersion: version,
+		staticRuleCache:  newStaticRuleCache(version),
+		staticConfFile:   newStaticConfFile(version),
+		files:            make(map[string][]byte),
+		dumpedFiles:      make(map[string][]byte),
+		dumpedMimeTypes:  make(map[string]string),
+	}
+}
+
+func newStaticConfFile(version string) *mod_static.StaticConfFile {
+	ruleList := make(mod_static.RuleFileList, 0)
+	productRuleList := mod_static.ProductRulesFile{
+		configs.DefaultProduct: &ruleList,
+	}
+	return &mod_static.StaticConfFile{
+		Version: &version,
+		Config:  &productRuleList,
+	}
+}
+
+func (c *ModStaticConfig) Name() string {
+	return ConfigNameStatic
+}
+
+func (c *ModStaticConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	return c.UpdateIngressWithConfigMaps(ingress, nil)
+}
+
+// UpdateIngressWithConfigMaps implements modules.ConfigMapReferrer
+func (c *ModStaticConfig) UpdateIngressWithConfigMaps(ingress *netv1.Ingress, configMaps []*corev1.ConfigMap) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.staticRuleCache.ContainsIngress(ingressName) {
+		c.staticRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.staticRuleCache.UpdateByIngress(ingress, configMaps)
+}
+
+func (c *ModStaticConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.staticRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.staticRuleCache.DeleteByIngress(ingressName)
+}
+
+// UpdateGlobalConfig implements modules.GlobalConfigHandler
+func (c *ModStaticConfig) UpdateGlobalConfig(data map[string]string) error {
+	mimeTypes, err := annotations.ParseStaticMimeTypes(data)
+	if err != nil {
+		return err
+	}
+
+	c.staticRuleCache.UpdateMimeTypes(mimeTypes)
+	return nil
+}
+
+func (c *ModStaticConfig) Reload() error {
+	if err := c.reloadMimeTypes(); err != nil {
+		return err
+	}
+
+	if err := c.updateStaticConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.staticConfFile.Version != c.version {
+		// dump static files, which are checked when the config file is loaded
+		if err := c.dumpFiles(); err != nil {
+			return err
+		}
+		// directories and default files are checked by mod_static, so they must be on the disk
+		if err := mod_static.StaticConfCheck(*c.staticConfFile); err != nil {
+			return fmt.Errorf("check %s error: %v", RuleData, err)
+		}
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.staticConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(reloadNameRule)
+		if err != nil {
+			return err
+		}
+		c.version = *c.staticConfFile.Version
+	}
+
+	return nil
+}
+
+// reloadMimeTypes dumps the content types of file extensions, which are used by all static files
+func (c *ModStaticConfig) reloadMimeTypes() error {
+	if c.mimeTypesVersion == c.staticRuleCache.mimeTypesVersion {
+		return nil
+	}
+
+	version := c.staticRuleCache.mimeTypesVersion
+	mimeTypes := c.staticRuleCache.mimeTypes
+	// skip reloading BFE while no content type is set, so mod_static only needs to be enabled in bfe.conf when used
+	if len(mimeTypes) == 0 && len(c.dumpedMimeTypes) == 0 {
+		c.mimeTypesVersion = version
+		return nil
+	}
+
+	mimeTypeConf := mod_static.MimeTypeConf{
+		Version: version,
+		Config:  mimeTypes,
+	}
+	if err := util.DumpBfeConf(MimeTypeData, mimeTypeConf); err != nil {
+		return fmt.Errorf("dump %s error: %v", MimeTypeData, err)
+	}
+	if err := util.ReloadBfe(reloadNameMimeType); err != nil {
+		return err
+	}
+	c.mimeTypesVersion = version
+	c.dumpedMimeTypes = mimeTypes
+	return nil
+}
+
+func (c *ModStaticConfig) dumpFiles() error {
+	for fileName, content := range c.files {
+		if dumped, ok := c.dumpedFiles[fileName]; ok && bytes.Equal(dumped, content) {
+			continue
+		}
+		if err := util.DumpFile(fileName, content); err != nil {
+			return fmt.Errorf("dump %s error: %v", fileName, err)
+		}
+		c.dumpedFiles[fileName] = content
+	}
+
+	// delete static files which are no longer used
+	for fileName := range c.dumpedFiles {
+		if _, ok := c.files[fileName]; !ok {
+			util.DeleteFile(fileName)
+			delete(c.dumpedFiles, fileName)
+		}
+	}
+	return nil
+}
+
+func (c *ModStaticConfig) updateStaticConf() error {
+	version := c.products.ConfVersion(c.staticRuleCache.Version)
+	if *c.staticConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.staticRuleCache.GetRules()
+	productRuleList := make(map[string]mod_static.RuleFileList)
+	files := make(map[string][]byte)
+	// conditions of rules with higher priority
+	var higherConds cache.PriorityConds
+	for _, rule := range ruleList {
+		rule := rule.(*staticRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+		if len(cond) == 0 {
+			cond = "default_t()"
+		}
+
+		// requests matched by an ingress without static files should be forwarded to its backend
+		host := rule.GetHost()
+		ruleCond := higherConds.Exclude(rule, cond)
+		higherConds.Add(rule, cond)
+		if rule.static == nil {
+			continue
+		}
+		if _, err := condition.Build(ruleCond); err != nil {
+			return fmt.Errorf("cond [%s] is illegal: %s", ruleCond, err)
+		}
+
+		root, defaultFile, err := buildRoot(rule, files)
+		if err != nil {
+			return err
+		}
+		cmd := mod_static.ActionBrowse
+		for _, product := range c.products.Of(host) {
+			productRuleList[product] = append(productRuleList[product], mod_static.StaticRuleFile{
+				Cond: ruleCond,
+				Action: &mod_static.ActionFile{
+					Cmd:    &cmd,
+					Params: []string{root, defaultFile},
+				},
+			})
+		}
+	}
+
+	// skip reloading BFE while no static file is served, so mod_static only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(*c.staticConfFile.Config) == 1 && len(*(*c.staticConfFile.Config)[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	staticConfFile := newStaticConfFile(version)
+	for product, staticRuleList := range productRuleList {
+		staticRuleList := staticRuleList
+		(*staticConfFile.Config)[product] = &staticRuleList
+	}
+
+	c.staticConfFile = staticConfFile
+	c.files = files
+	return nil
+}
+
+// buildRoot returns the root directory and the default file of the rule, files from the ConfigMap are added to files.
+// mod_static looks up the whole path of the request in the root directory,
+// so files from the ConfigMap are placed in the directory of the path of the rule.
+func buildRoot(rule *staticRule, files map[string][]byte) (string, string, error) {
+	static := rule.static
+	if len(static.Root) > 0 {
+		return static.Root, static.DefaultFile, nil
+	}
+
+	rootDir := RootDir + strings.Replace(rule.GetIngress(), "/", "_", 1)
+	root, err := filepath.Abs(option.Opts.Ingress.ConfigPath + rootDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	// e.g. files of prefix path /assets are served as /assets/<file>, files of exact path /robots.txt are served as /<file>
+	dir := rule.GetPath()
+	if strings.HasSuffix(dir, "*") {
+		dir = strings.TrimSuffix(dir, "*")
+	} else {
+		dir = path.Dir(dir)
+	}
+	dir = path.Join("/", dir)
+
+	for name, content := range rule.files {
+		files[rootDir+path.Join(dir, name)] = content
+	}
+
+	var defaultFile string
+	if len(static.DefaultFile) > 0 {
+		defaultFile = path.Join(dir, static.DefaultFile)
+	}
+	return root, defaultFile, nil
+}
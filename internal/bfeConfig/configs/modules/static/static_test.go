@@ -0,0 +1,272 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package static
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/baidu/go-lib/web-monitor/web_monitor"
+	"github.com/bfenetworks/bfe/bfe_module"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_static"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+func TestUpdateStaticConf(t *testing.T) {
+	opts := moduletest.SetOptions(t)
+
+	// files of the directory are served by annotation "static.root"
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index.html":    "<h1>dir</h1>",
+		"foo/app.js":    "app()",
+		"foo/README.md": "# readme",
+	})
+	root := map[string]string{
+		annotations.StaticRootAnnotation:        dir,
+		annotations.StaticDefaultFileAnnotation: "index.html",
+	}
+	files := map[string]string{
+		annotations.StaticConfigMapAnnotation:   "files",
+		annotations.StaticDefaultFileAnnotation: "index.html",
+	}
+	configMaps := []*corev1.ConfigMap{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "files"},
+		Data:       map[string]string{"index.html": "<h1>index</h1>"},
+		BinaryData: map[string][]byte{"logo.png": {0x89, 0x50}},
+	}}
+
+	type request struct {
+		path     string
+		want     string // content of the served file, empty if the request is not served
+		wantType string // Content-Type of the response, not checked if empty
+	}
+	tests := []struct {
+		name       string
+		ingresses  []*netv1.Ingress
+		configMaps []*corev1.ConfigMap
+		global     map[string]string
+		wantErr    bool
+		wantRules  int
+		wantReload bool
+		// wantFiles are the files of ConfigMaps written to the disk, file name in RootDir => content
+		wantFiles map[string]string
+		requests  []request
+	}{
+		{
+			name:       "no static file is served",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "files in root directory",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", root, "foo.com", "/foo")},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo/app.js", want: "app()"},
+				{path: "/foo/missing.js", want: "<h1>dir</h1>"},
+				{path: "/bar/app.js", want: ""},
+			},
+		},
+		{
+			name:       "files of configmap",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", files, "foo.com", "/foo")},
+			configMaps: configMaps,
+			wantRules:  1,
+			wantReload: true,
+			wantFiles: map[string]string{
+				"default_a/foo/index.html": "<h1>index</h1>",
+				"default_a/foo/logo.png":   "\x89\x50",
+			},
+			requests: []request{
+				{path: "/foo/logo.png", want: "\x89\x50", wantType: "image/png"},
+				{path: "/foo/", want: "<h1>index</h1>"},
+				{path: "/foo/missing.png", want: "<h1>index</h1>"},
+			},
+		},
+		{
+			name: "ingress without static files with higher priority",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", root, "foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo/api"),
+			},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo/app.js", want: "app()"},
+				{path: "/foo/api", want: ""},
+			},
+		},
+		{
+			name:       "content types of the global ConfigMap",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", root, "foo.com", "/foo")},
+			global:     map[string]string{annotations.StaticMimeTypesKey: `{".md": "text/markdown"}`},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo/README.md", want: "# readme", wantType: "text/markdown"},
+			},
+		},
+		{
+			name:      "configmap not found",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", files, "foo.com", "/foo")},
+			wantErr:   true,
+		},
+		{
+			name: "default file not found in configmap",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", map[string]string{
+				annotations.StaticConfigMapAnnotation:   "files",
+				annotations.StaticDefaultFileAnnotation: "home.html",
+			}, "foo.com", "/foo")},
+			configMaps: configMaps,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bfe := moduletest.NewBfe(t, opts)
+			c := NewStaticConfig("init", configs.NewProductTable())
+			if err := c.UpdateGlobalConfig(tt.global); err != nil {
+				t.Fatalf("UpdateGlobalConfig() error = %v", err)
+			}
+			var err error
+			for _, ingress := range tt.ingresses {
+				if err = c.UpdateIngressWithConfigMaps(ingress, tt.configMaps); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateIngressWithConfigMaps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := c.Reload(); err != nil {
+				t.Fatalf("Reload() error = %v", err)
+			}
+
+			rules := *(*c.staticConfFile.Config)[configs.DefaultProduct]
+			if got := len(rules); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := len(bfe.Reloads()) > 0; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+			if got := readFiles(t, opts.Ingress.ConfigPath+RootDir); !reflect.DeepEqual(got, tt.wantFiles) {
+				t.Errorf("files = %v, want %v", got, tt.wantFiles)
+			}
+			if len(tt.requests) == 0 {
+				return
+			}
+
+			// requests are served by mod_static with the dumped config files
+			handlers := loadModStatic(t, opts.Ingress.ConfigPath)
+			for _, r := range tt.requests {
+				req := moduletest.NewRequest("foo.com", r.path, "10.0.0.1")
+				req.HttpRequest.Method = "GET"
+				req.Route.Product = configs.DefaultProduct
+				got, gotType := "", ""
+				if ret, resp := handlers.FilterRequest(req); ret == bfe_module.BfeHandlerResponse {
+					data, err := ioutil.ReadAll(resp.Body)
+					if err != nil {
+						t.Fatal(err)
+					}
+					resp.Body.Close()
+					got, gotType = string(data), resp.Header.Get("Content-Type")
+				}
+				if got != r.want {
+					t.Errorf("request %s: file = %q, want %q", r.path, got, r.want)
+				}
+				if len(r.wantType) > 0 && gotType != r.wantType {
+					t.Errorf("request %s: Content-Type = %q, want %q", r.path, gotType, r.wantType)
+				}
+			}
+		})
+	}
+}
+
+// loadModStatic initializes mod_static with the config files in confRoot, and returns its handlers
+func loadModStatic(t *testing.T, confRoot string) *bfe_module.HandlerList {
+	t.Helper()
+	files := map[string]string{
+		"mod_static/mod_static.conf": "[Basic]\nDataPath = " + RuleData + "\nMimeTypePath = " + MimeTypeData + "\n",
+	}
+	// the content types are only dumped if set by the global ConfigMap
+	if _, err := os.Stat(confRoot + MimeTypeData); os.IsNotExist(err) {
+		files[MimeTypeData] = `{"Version": "init", "Config": {}}`
+	}
+	writeFiles(t, confRoot, files)
+
+	m := mod_static.NewModuleStatic()
+	cbs := bfe_module.NewBfeCallbacks()
+	if err := m.Init(cbs, web_monitor.NewWebHandlers(), confRoot); err != nil {
+		t.Fatalf("mod_static Init() error = %v", err)
+	}
+	return cbs.GetHandlerList(bfe_module.HandleFoundProduct)
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readFiles returns files in dir, relative file name => content
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	var files map[string]string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if files == nil {
+			files = make(map[string]string)
+		}
+		files[name] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
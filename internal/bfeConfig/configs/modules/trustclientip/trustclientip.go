@@ -0,0 +1,143 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trustclientip is the module of trusted sources, e.g. load balancers in front of BFE.
+// The trusted sources are read from the global ConfigMap, and not related to ingresses.
+package trustclientip
+
+import (
+	"fmt"
+
+	"github.com/bfenetworks/bfe/bfe_modules/mod_trust_clientip"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+const (
+	ConfigNameTrustClientIP = "mod_trust_clientip"
+	ConfData                = "mod_trust_clientip/trust_client_ip.data"
+
+	// trustedSource is the source name of trusted IPs in the config file
+	trustedSource = "ingress-trusted"
+)
+
+type ModTrustClientIPConfig struct {
+	version string
+	// trustedIPs is nil if the global ConfigMap never sets trusted IPs, then the config file of BFE is kept
+	trustedIPs        []annotations.IPRange
+	trustedIPsVersion string
+	trustIPConfFile   *mod_trust_clientip.TrustIPConfFile
+}
+
+func NewTrustClientIPConfig(version string) *ModTrustClientIPConfig {
+	return &ModTrustClientIPConfig{
+		version:           version,
+		trustedIPsVersion: version,
+		trustIPConfFile:   newTrustIPConfFile(version),
+	}
+}
+
+func newTrustIPConfFile(version string) *mod_trust_clientip.TrustIPConfFile {
+	scopeList := make(mod_trust_clientip.AddrScopeFileList, 0)
+	srcScopeMap := mod_trust_clientip.SrcScopeMapFile{
+		trustedSource: &scopeList,
+	}
+	return &mod_trust_clientip.TrustIPConfFile{
+		Version: &version,
+		Config:  &srcScopeMap,
+	}
+}
+
+func (c *ModTrustClientIPConfig) Name() string {
+	return ConfigNameTrustClientIP
+}
+
+// UpdateIngress does nothing, since trusted sources are not related to ingresses
+func (c *ModTrustClientIPConfig) UpdateIngress(_ *netv1.Ingress) error {
+	return nil
+}
+
+func (c *ModTrustClientIPConfig) DeleteIngress(_, _ string) {
+}
+
+// UpdateGlobalConfig implements modules.GlobalConfigHandler
+func (c *ModTrustClientIPConfig) UpdateGlobalConfig(data map[string]string) error {
+	value, ok := data[annotations.TrustClientIPKey]
+	if !ok && c.trustedIPs == nil {
+		return nil
+	}
+
+	trustedIPs, err := annotations.ParseIPRanges(map[string]string{annotations.TrustClientIPKey: value})
+	if err != nil {
+		return err
+	}
+	// keep the config file of BFE while no IP is ever trusted, so mod_trust_clientip only needs to be enabled in bfe.conf when used
+	if len(trustedIPs) == 0 && c.trustedIPs == nil {
+		return nil
+	}
+
+	// no IP is trusted if the key is removed or empty
+	c.trustedIPs = make([]annotations.IPRange, 0, len(trustedIPs))
+	c.trustedIPs = append(c.trustedIPs, trustedIPs...)
+	c.trustedIPsVersion = util.NewVersion()
+	return nil
+}
+
+func (c *ModTrustClientIPConfig) Reload() error {
+	if err := c.updateTrustIPConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", ConfData, err)
+	}
+
+	if *c.trustIPConfFile.Version != c.version {
+		// dump config file
+		err := util.DumpBfeConf(ConfData, c.trustIPConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", ConfData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameTrustClientIP)
+		if err != nil {
+			return err
+		}
+		c.version = *c.trustIPConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModTrustClientIPConfig) updateTrustIPConf() error {
+	if *c.trustIPConfFile.Version == c.trustedIPsVersion {
+		return nil
+	}
+
+	scopeList := make(mod_trust_clientip.AddrScopeFileList, 0, len(c.trustedIPs))
+	for _, ipRange := range c.trustedIPs {
+		begin, end := ipRange.Start.String(), ipRange.End.String()
+		scopeList = append(scopeList, mod_trust_clientip.AddrScopeFile{
+			Begin: &begin,
+			End:   &end,
+		})
+	}
+
+	trustIPConfFile := newTrustIPConfFile(c.trustedIPsVersion)
+	(*trustIPConfFile.Config)[trustedSource] = &scopeList
+	if err := mod_trust_clientip.TrustIPConfCheck(trustIPConfFile); err != nil {
+		return err
+	}
+
+	c.trustIPConfFile = trustIPConfFile
+	return nil
+}
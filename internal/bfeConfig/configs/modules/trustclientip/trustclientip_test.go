@@ -0,0 +1,99 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package trustclientip
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+)
+
+func TestUpdateTrustIPConf(t *testing.T) {
+	// data of the global ConfigMap, nil if the ConfigMap is deleted
+	type step struct {
+		data       map[string]string
+		wantErr    bool
+		wantScopes []string // begin-end of the trusted address scopes
+		wantReload bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "no IP is trusted",
+			steps: []step{
+				{data: nil, wantScopes: nil, wantReload: false},
+				{data: map[string]string{"other": "value"}, wantScopes: nil, wantReload: false},
+				{data: map[string]string{annotations.TrustClientIPKey: ""}, wantScopes: nil, wantReload: false},
+			},
+		},
+		{
+			name: "trusted IPs",
+			steps: []step{
+				{
+					data: map[string]string{annotations.TrustClientIPKey: "10.0.0.0/8, 192.168.1.1\n172.16.0.0/12, 2001:db8::/126"},
+					wantScopes: []string{
+						"10.0.0.0-10.255.255.255",
+						"192.168.1.1-192.168.1.1",
+						"172.16.0.0-172.31.255.255",
+						"2001:db8::-2001:db8::3",
+					},
+					wantReload: true,
+				},
+			},
+		},
+		{
+			name: "illegal IPs keep the trusted IPs",
+			steps: []step{
+				{data: map[string]string{annotations.TrustClientIPKey: "10.0.0.0/8"}, wantScopes: []string{"10.0.0.0-10.255.255.255"}, wantReload: true},
+				{data: map[string]string{annotations.TrustClientIPKey: "10.0.0.0/33"}, wantErr: true, wantScopes: []string{"10.0.0.0-10.255.255.255"}, wantReload: false},
+			},
+		},
+		{
+			name: "trusted IPs removed",
+			steps: []step{
+				{data: map[string]string{annotations.TrustClientIPKey: "10.0.0.0/8"}, wantScopes: []string{"10.0.0.0-10.255.255.255"}, wantReload: true},
+				{data: nil, wantScopes: nil, wantReload: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewTrustClientIPConfig("init")
+			for i, s := range tt.steps {
+				if err := c.UpdateGlobalConfig(s.data); (err != nil) != s.wantErr {
+					t.Fatalf("step %d: UpdateGlobalConfig() error = %v, wantErr %v", i, err, s.wantErr)
+				}
+				if err := c.updateTrustIPConf(); err != nil {
+					t.Fatalf("step %d: updateTrustIPConf() error = %v", i, err)
+				}
+
+				var scopes []string
+				for _, scope := range *(*c.trustIPConfFile.Config)[trustedSource] {
+					scopes = append(scopes, *scope.Begin+"-"+*scope.End)
+				}
+				if !reflect.DeepEqual(scopes, s.wantScopes) {
+					t.Errorf("step %d: scopes = %v, want %v", i, scopes, s.wantScopes)
+				}
+				if got := *c.trustIPConfFile.Version != c.version; got != s.wantReload {
+					t.Errorf("step %d: reload = %v, want %v", i, got, s.wantReload)
+				}
+				// the config is dumped
+				c.version = *c.trustIPConfFile.Version
+			}
+		})
+	}
+}
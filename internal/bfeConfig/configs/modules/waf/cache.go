@@ -0,0 +1,67 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package waf is the module of web application firewall.
+// This file defines waf rule & cache's struct, also implements update ingress method.
+package waf
+
+import (
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+type wafRule struct {
+	*cache.BaseRule
+	// waf is nil if the ingress has no WAF, such rule stops rules with lower priority
+	waf *annotations.Waf
+}
+
+type wafRuleCache struct {
+	*cache.BaseCache
+}
+
+func newWafRuleCache(version string) *wafRuleCache {
+	return &wafRuleCache{
+		BaseCache: cache.NewBaseCache(version),
+	}
+}
+
+func (c *wafRuleCache) UpdateByIngress(ingress *netv1.Ingress) error {
+	waf, err := annotations.GetWaf(ingress.Annotations)
+	if err != nil {
+		return err
+	}
+
+	// rules of ingresses without WAF are also cached, since mod_waf stops at the first matched rule
+	return c.UpdateByIngressFramework(
+		ingress,
+		func(ingress *netv1.Ingress, host, path string, _ netv1.HTTPIngressPath) (cache.Rule, error) {
+			return &wafRule{
+				BaseRule: cache.NewBaseRule(
+					util.NamespacedName(ingress.Namespace, ingress.Name),
+					host,
+					path,
+					ingress.Annotations,
+					ingress.CreationTimestamp.Time,
+				),
+				waf: waf,
+			}, nil
+		},
+		nil,
+		nil,
+	)
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package waf is the module of web application firewall.
+// This file implements operate rule cache, generate and reload config file methods.
+package waf
+
+import (
+	"fmt"
+
+	"github.com/bfenetworks/bfe/bfe_basic/condition"
+	"github.com/bfenetworks/bfe/bfe_modules/mod_waf/waf_rule"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+)
+
+const (
+	ConfigNameWaf = "mod_waf"
+	RuleData      = "mod_waf/waf_rule.data"
+)
+
+// wafRuleFile is the rule of mod_waf, which is not exported by mod_waf
+type wafRuleFile struct {
+	Cond       string
+	BlockRules []string
+	CheckRules []string
+}
+
+type wafRuleFileList []*wafRuleFile
+
+type wafConfFile struct {
+	Version *string
+	Config  *map[string]*wafRuleFileList
+}
+
+type ModWafConfig struct {
+	version      string
+	products     *configs.ProductTable
+	wafRuleCache *wafRuleCache
+	wafConfFile  *wafConfFile
+}
+
+func NewWafConfig(version string, products *configs.ProductTable) *ModWafConfig {
+	return &ModWafConfig{
+		version:      version,
+		products:     products,
+		wafRuleCache: newWafRuleCache(version),
+		wafConfFile:  newWafConfFile(version),
+	}
+}
+
+func newWafConfFile(version string) *wafConfFile {
+	ruleList := make(wafRuleFileList, 0)
+	productRuleList := map[string]*wafRuleFileList{
+		configs.DefaultProduct: &ruleList,
+	}
+	return &wafConfFile{
+		Version: &version,
+		Config:  &productRuleList,
+	}
+}
+
+func (c *ModWafConfig) Name() string {
+	return ConfigNameWaf
+}
+
+func (c *ModWafConfig) UpdateIngress(ingress *netv1.Ingress) error {
+	// clear cache
+	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	if c.wafRuleCache.ContainsIngress(ingressName) {
+		c.wafRuleCache.DeleteByIngress(ingressName)
+	}
+	// nothing to update
+	if len(ingress.Spec.Rules) == 0 {
+		return nil
+	}
+
+	return c.wafRuleCache.UpdateByIngress(ingress)
+}
+
+func (c *ModWafConfig) DeleteIngress(namespace, name string) {
+	ingressName := util.NamespacedName(namespace, name)
+	if !c.wafRuleCache.ContainsIngress(ingressName) {
+		return
+	}
+
+	c.wafRuleCache.DeleteByIngress(ingressName)
+}
+
+func (c *ModWafConfig) Reload() error {
+	if err := c.updateWafConf(); err != nil {
+		return fmt.Errorf("update %s config error: %v", RuleData, err)
+	}
+
+	if *c.wafConfFile.Version != c.version {
+		// dump config file
+		err := util.DumpBfeConf(RuleData, c.wafConfFile)
+		if err != nil {
+			return fmt.Errorf("dump %s error: %v", RuleData, err)
+		}
+		// reload bfe engine
+		err = util.ReloadBfe(ConfigNameWaf)
+		if err != nil {
+			return err
+		}
+		c.version = *c.wafConfFile.Version
+	}
+
+	return nil
+}
+
+func (c *ModWafConfig) updateWafConf() error {
+	version := c.products.ConfVersion(c.wafRuleCache.Version)
+	if *c.wafConfFile.Version == version {
+		return nil
+	}
+
+	ruleList := c.wafRuleCache.GetRules()
+	productRuleList := make(map[string]wafRuleFileList)
+	// conditions of rules with higher priority
+	var higherConds cache.PriorityConds
+	for _, rule := range ruleList {
+		rule := rule.(*wafRule)
+		cond, err := rule.GetCond()
+		if err != nil {
+			return err
+		}
+		if len(cond) == 0 {
+			cond = "default_t()"
+		}
+
+		// requests matched by an ingress without WAF should not be checked by rules of ingresses with lower priority
+		host := rule.GetHost()
+		ruleCond := higherConds.Exclude(rule, cond)
+		higherConds.Add(rule, cond)
+		if rule.waf == nil {
+			continue
+		}
+
+		wafRuleFile := &wafRuleFile{Cond: ruleCond}
+		if rule.waf.Mode == annotations.WafModeBlock {
+			wafRuleFile.BlockRules = rule.waf.Rules
+		} else {
+			wafRuleFile.CheckRules = rule.waf.Rules
+		}
+		for _, product := range c.products.Of(host) {
+			productRuleList[product] = append(productRuleList[product], wafRuleFile)
+		}
+	}
+
+	// skip reloading BFE while WAF is not used, so mod_waf only needs to be enabled in bfe.conf when used
+	if len(productRuleList) == 0 && len(*c.wafConfFile.Config) == 1 && len(*(*c.wafConfFile.Config)[configs.DefaultProduct]) == 0 {
+		c.version = version
+	}
+
+	wafConfFile := newWafConfFile(version)
+	for product, wafRuleList := range productRuleList {
+		wafRuleList := wafRuleList
+		if err := wafRuleListCheck(wafRuleList); err != nil {
+			return err
+		}
+		(*wafConfFile.Config)[product] = &wafRuleList
+	}
+
+	c.wafConfFile = wafConfFile
+	return nil
+}
+
+// wafRuleListCheck checks rules in the same way as mod_waf does
+func wafRuleListCheck(ruleList wafRuleFileList) error {
+	for i, rule := range ruleList {
+		if _, err := condition.Build(rule.Cond); err != nil {
+			return fmt.Errorf("wafRule:%d, cond [%s] is illegal: %s", i, rule.Cond, err)
+		}
+		if len(rule.BlockRules) == 0 && len(rule.CheckRules) == 0 {
+			return fmt.Errorf("wafRule:%d, block rules and check rules both empty", i)
+		}
+		for _, name := range append(append([]string{}, rule.BlockRules...), rule.CheckRules...) {
+			if !waf_rule.IsValidRule(name) {
+				return fmt.Errorf("wafRule:%d, unknown rule %s", i, name)
+			}
+		}
+	}
+	return nil
+}
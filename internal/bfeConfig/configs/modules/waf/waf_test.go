@@ -0,0 +1,153 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package waf
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_modules/mod_waf/waf_rule"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/modules/moduletest"
+)
+
+func TestUpdateWafConf(t *testing.T) {
+	moduletest.SetOptions(t)
+
+	block := map[string]string{annotations.WafAnnotation: annotations.WafModeBlock}
+	detect := map[string]string{
+		annotations.WafAnnotation:      annotations.WafModeDetect,
+		annotations.WafRulesAnnotation: waf_rule.RuleBashCmd,
+	}
+	off := map[string]string{annotations.WafAnnotation: annotations.WafModeOff}
+
+	type request struct {
+		path      string
+		want      string   // mode of the first matched rule, empty if no rule is matched
+		wantRules []string // WAF rules of the first matched rule
+	}
+	tests := []struct {
+		name       string
+		ingresses  []*netv1.Ingress
+		wantErr    bool
+		wantRules  int
+		wantReload bool
+		requests   []request
+	}{
+		{
+			name:       "no WAF is set",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", nil, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "WAF is off",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", off, "foo.com", "/foo")},
+			wantRules:  0,
+			wantReload: false,
+		},
+		{
+			name:       "block mode with default rules",
+			ingresses:  []*netv1.Ingress{moduletest.NewIngress("a", block, "foo.com", "/foo")},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo", want: annotations.WafModeBlock, wantRules: annotations.DefaultWafRules},
+				{path: "/bar", want: ""},
+			},
+		},
+		{
+			name: "ingresses with different modes",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", block, "foo.com", "/foo"),
+				moduletest.NewIngress("b", detect, "foo.com", "/foo/bar"),
+			},
+			wantRules:  2,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo/baz", want: annotations.WafModeBlock, wantRules: annotations.DefaultWafRules},
+				{path: "/foo/bar", want: annotations.WafModeDetect, wantRules: []string{waf_rule.RuleBashCmd}},
+			},
+		},
+		{
+			name: "ingress without WAF with higher priority",
+			ingresses: []*netv1.Ingress{
+				moduletest.NewIngress("a", block, "foo.com", "/foo"),
+				moduletest.NewIngress("b", nil, "foo.com", "/foo/bar"),
+			},
+			wantRules:  1,
+			wantReload: true,
+			requests: []request{
+				{path: "/foo/baz", want: annotations.WafModeBlock, wantRules: annotations.DefaultWafRules},
+				{path: "/foo/bar", want: ""},
+			},
+		},
+		{
+			name: "unknown rule",
+			ingresses: []*netv1.Ingress{moduletest.NewIngress("a", map[string]string{
+				annotations.WafAnnotation:      annotations.WafModeBlock,
+				annotations.WafRulesAnnotation: "RuleUnknown",
+			}, "foo.com", "/foo")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewWafConfig("init", configs.NewProductTable())
+			var err error
+			for _, ingress := range tt.ingresses {
+				if err = c.UpdateIngress(ingress); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateIngress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := c.updateWafConf(); err != nil {
+				t.Fatalf("updateWafConf() error = %v", err)
+			}
+
+			rules := *(*c.wafConfFile.Config)[configs.DefaultProduct]
+			if got := len(rules); got != tt.wantRules {
+				t.Errorf("rules = %d, want %d", got, tt.wantRules)
+			}
+			if got := *c.wafConfFile.Version != c.version; got != tt.wantReload {
+				t.Errorf("reload = %v, want %v", got, tt.wantReload)
+			}
+
+			for _, r := range tt.requests {
+				req := moduletest.NewRequest("foo.com", r.path, "10.0.0.1")
+				got, gotRules := "", []string(nil)
+				for _, rule := range rules {
+					if moduletest.Match(t, rule.Cond, req) {
+						got, gotRules = annotations.WafModeDetect, rule.CheckRules
+						if len(rule.BlockRules) > 0 {
+							got, gotRules = annotations.WafModeBlock, rule.BlockRules
+						}
+						break
+					}
+				}
+				if got != r.want || !reflect.DeepEqual(gotRules, r.wantRules) {
+					t.Errorf("request %s: mode = %q, rules = %v, want %q, %v", r.path, got, gotRules, r.want, r.wantRules)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2021 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configs
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/log"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option/ingress"
+)
+
+// ProductTable maps hosts in the host table of BFE to products.
+// A request is handled by the rules of the product of its host, or the default product if its host isn't in the table,
+// so rules are put into all products whose requests may match them.
+type ProductTable struct {
+	// host -> product, wildcard hosts are in format of "*.foo.com", which matches one or more labels
+	hostProduct map[string]string
+
+	// changed when the table is changed, so that configs of modules are regenerated
+	version string
+}
+
+// NewProductTable creates an empty table, which puts all rules into the default product.
+// The table is shared by the server data config and configs of modules, and is updated with route rules of all ingresses
+// by the server data config.
+func NewProductTable() *ProductTable {
+	return &ProductTable{
+		hostProduct: make(map[string]string),
+	}
+}
+
+// newProductTable builds the table with rules sorted by priority, the product of a host is decided by its first rule.
+// In namespace mode, a host used by ingresses of more than one namespace belongs to the namespace of its first rule,
+// and rules of other namespaces are put into the product of that namespace, too.
+func newProductTable(mode string, rules []cache.Rule) *ProductTable {
+	hostProduct := make(map[string]string)
+	if mode == ingress.ProductModeHost || mode == ingress.ProductModeNamespace {
+		for _, rule := range rules {
+			host := tableHost(rule.GetHost())
+			if len(host) == 0 {
+				continue
+			}
+
+			product := host
+			if mode == ingress.ProductModeNamespace {
+				product = strings.SplitN(rule.GetIngress(), "/", 2)[0]
+			}
+			if p, ok := hostProduct[host]; ok {
+				if p != product {
+					log.Log.V(0).Info("host is used by ingresses of more than one namespace, rules are put into the product of the first namespace",
+						"host", rule.GetHost(), "product", p, "ingress", rule.GetIngress())
+				}
+				continue
+			}
+			hostProduct[host] = product
+		}
+	}
+
+	return &ProductTable{
+		hostProduct: hostProduct,
+	}
+}
+
+// update replaces hosts of the table with hosts of the given table, returns true if the table is changed
+func (t *ProductTable) update(table *ProductTable) bool {
+	if reflect.DeepEqual(table.hostProduct, t.hostProduct) {
+		return false
+	}
+
+	t.hostProduct = table.hostProduct
+	t.version = util.NewVersion()
+	return true
+}
+
+// ConfVersion returns the version of a config generated from rules of the version,
+// the config should be regenerated if the version or the table is changed
+func (t *ProductTable) ConfVersion(version string) string {
+	if len(t.version) == 0 {
+		return version
+	}
+	return version + "_" + t.version
+}
+
+// All returns all products in order, including the default product
+func (t *ProductTable) All() []string {
+	products := []string{DefaultProduct}
+	for _, product := range t.hostProduct {
+		if !containsString(products, product) {
+			products = append(products, product)
+		}
+	}
+	sort.Strings(products[1:])
+	return products
+}
+
+// Of returns products whose requests may match rules of the host, host of rules matching all hosts is "*"
+func (t *ProductTable) Of(host string) []string {
+	if len(t.hostProduct) == 0 {
+		return []string{DefaultProduct}
+	}
+	if len(host) == 0 || host == "*" {
+		return t.All()
+	}
+
+	host = tableHost(host)
+	products := []string{t.lookup(host)}
+	if strings.HasPrefix(host, "*.") {
+		// requests of hosts under the wildcard host are handled by products of these hosts
+		suffix := host[1:]
+		for h, product := range t.hostProduct {
+			if strings.HasSuffix(h, suffix) && !containsString(products, product) {
+				products = append(products, product)
+			}
+		}
+	}
+	sort.Strings(products)
+	return products
+}
+
+// hosts returns hosts of products
+func (t *ProductTable) hosts() map[string][]string {
+	hosts := make(map[string][]string)
+	for host, product := range t.hostProduct {
+		hosts[product] = append(hosts[product], host)
+	}
+	for _, list := range hosts {
+		sort.Strings(list)
+	}
+	return hosts
+}
+
+// hasHost returns true if the host is in the table
+func (t *ProductTable) hasHost(host string) bool {
+	_, ok := t.hostProduct[host]
+	return ok
+}
+
+// lookup returns product of the host in the same way as BFE,
+// exact host is matched first, then the wildcard host with the longest suffix
+func (t *ProductTable) lookup(host string) string {
+	if product, ok := t.hostProduct[host]; ok {
+		return product
+	}
+
+	product, suffixLen := DefaultProduct, 0
+	for h, p := range t.hostProduct {
+		if strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:]) && len(h) > suffixLen {
+			product, suffixLen = p, len(h)
+		}
+	}
+	return product
+}
+
+// tableHost converts host of rules to host in the host table,
+// "*" of wildcard hosts in the host table matches one or more labels
+func tableHost(host string) string {
+	if host == "*" {
+		return ""
+	}
+	if cache.IsMultiLabelWildcardHost(host) {
+		return "*." + strings.TrimPrefix(host, cache.MultiLabelWildcardPrefix)
+	}
+	return host
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
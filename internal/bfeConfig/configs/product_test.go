@@ -0,0 +1,252 @@
+// Copyright (c) 2021 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bfenetworks/bfe/bfe_config/bfe_route_conf/host_rule_conf"
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/option/ingress"
+)
+
+func newTestProductTable(t *testing.T, mode string) *ProductTable {
+	foo := newTestIngress("foo", nil, "a.foo.com", "*.foo.com")
+	foo.Namespace = "ns1"
+	bar := newTestIngress("bar", map[string]string{
+		annotations.WildcardHostAnnotation: annotations.WildcardHostMultiLabel,
+	}, "*.bar.com", "")
+	bar.Namespace = "ns2"
+
+	cache := newRouteRuleCache("")
+	for _, ing := range []*netv1.Ingress{foo, bar} {
+		if err := cache.UpdateByIngress(ing); err != nil {
+			t.Fatalf("UpdateByIngress() error = %v", err)
+		}
+	}
+	return newProductTable(mode, cache.GetRules())
+}
+
+func Test_productTable(t *testing.T) {
+	tests := []struct {
+		mode  string
+		host  string
+		want  []string
+		wantN int
+	}{
+		{ingress.ProductModeSingle, "a.foo.com", []string{"default"}, 1},
+		{ingress.ProductModeSingle, "*", []string{"default"}, 1},
+		{ingress.ProductModeHost, "a.foo.com", []string{"a.foo.com"}, 4},
+		{ingress.ProductModeHost, "b.foo.com", []string{"*.foo.com"}, 4},
+		{ingress.ProductModeHost, "*.foo.com", []string{"*.foo.com", "a.foo.com"}, 4},
+		{ingress.ProductModeHost, "**.bar.com", []string{"*.bar.com"}, 4},
+		{ingress.ProductModeHost, "x.y.bar.com", []string{"*.bar.com"}, 4},
+		{ingress.ProductModeHost, "baz.com", []string{"default"}, 4},
+		{ingress.ProductModeHost, "*", []string{"default", "*.bar.com", "*.foo.com", "a.foo.com"}, 4},
+		{ingress.ProductModeNamespace, "a.foo.com", []string{"ns1"}, 3},
+		{ingress.ProductModeNamespace, "*.bar.com", []string{"ns2"}, 3},
+		{ingress.ProductModeNamespace, "*.com", []string{"default", "ns1", "ns2"}, 3},
+	}
+	for _, tt := range tests {
+		products := newTestProductTable(t, tt.mode)
+		if got := products.Of(tt.host); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Of(%s) = %v, want %v", tt.mode, tt.host, got, tt.want)
+		}
+		if got := len(products.All()); got != tt.wantN {
+			t.Errorf("%s: len(All()) = %v, want %v", tt.mode, got, tt.wantN)
+		}
+	}
+}
+
+func Test_productTableNamespace(t *testing.T) {
+	// newIngress creates an ingress of the namespace with path "/" of the hosts, or the path given after "|"
+	newIngress := func(namespace, name string, hosts ...string) *netv1.Ingress {
+		ing := newTestIngress(name, nil)
+		ing.Namespace = namespace
+		for _, host := range hosts {
+			path := "/"
+			if i := strings.Index(host, "|"); i >= 0 {
+				host, path = host[:i], host[i+1:]
+			}
+			ing.Spec.Rules = append(ing.Spec.Rules, newTestIngress("", nil, host).Spec.Rules...)
+			ing.Spec.Rules[len(ing.Spec.Rules)-1].HTTP.Paths[0].Path = path
+		}
+		return ing
+	}
+
+	tests := []struct {
+		name      string
+		ingresses []*netv1.Ingress
+		host      string
+		want      []string
+		wantAll   []string
+		wantHosts host_rule_conf.HostTagToHost
+	}{
+		{
+			name: "host shared by namespaces belongs to the namespace of the rule with highest priority",
+			ingresses: []*netv1.Ingress{
+				newIngress("ns1", "a", "foo.com|/"),
+				newIngress("ns2", "b", "foo.com|/api"),
+			},
+			host:    "foo.com",
+			want:    []string{"ns2"},
+			wantAll: []string{"default", "ns2"},
+			wantHosts: host_rule_conf.HostTagToHost{
+				"default": &host_rule_conf.HostnameList{"default"},
+				"ns2":     &host_rule_conf.HostnameList{"foo.com"},
+			},
+		},
+		{
+			name: "host shared by namespaces doesn't depend on order of ingresses",
+			ingresses: []*netv1.Ingress{
+				newIngress("ns2", "b", "foo.com|/api"),
+				newIngress("ns1", "a", "foo.com|/", "bar.com"),
+			},
+			host:    "foo.com",
+			want:    []string{"ns2"},
+			wantAll: []string{"default", "ns1", "ns2"},
+			wantHosts: host_rule_conf.HostTagToHost{
+				"default": &host_rule_conf.HostnameList{"default"},
+				"ns1":     &host_rule_conf.HostnameList{"bar.com"},
+				"ns2":     &host_rule_conf.HostnameList{"foo.com"},
+			},
+		},
+		{
+			name: "hosts under wildcard host of another namespace",
+			ingresses: []*netv1.Ingress{
+				newIngress("ns1", "a", "*.foo.com"),
+				newIngress("ns2", "b", "a.foo.com"),
+			},
+			host:    "*.foo.com",
+			want:    []string{"ns1", "ns2"},
+			wantAll: []string{"default", "ns1", "ns2"},
+			wantHosts: host_rule_conf.HostTagToHost{
+				"default": &host_rule_conf.HostnameList{"default"},
+				"ns1":     &host_rule_conf.HostnameList{"*.foo.com"},
+				"ns2":     &host_rule_conf.HostnameList{"a.foo.com"},
+			},
+		},
+		{
+			name: "hosts of namespace default go to the default product",
+			ingresses: []*netv1.Ingress{
+				newIngress("default", "a", "foo.com"),
+				newIngress("ns1", "b", "bar.com"),
+			},
+			host:    "foo.com",
+			want:    []string{"default"},
+			wantAll: []string{"default", "ns1"},
+			wantHosts: host_rule_conf.HostTagToHost{
+				"default": &host_rule_conf.HostnameList{"foo.com"},
+				"ns1":     &host_rule_conf.HostnameList{"bar.com"},
+			},
+		},
+		{
+			name: "host named after the default product",
+			ingresses: []*netv1.Ingress{
+				newIngress("ns1", "a", "default"),
+			},
+			host:    "default",
+			want:    []string{"ns1"},
+			wantAll: []string{"default", "ns1"},
+			wantHosts: host_rule_conf.HostTagToHost{
+				"default": &host_rule_conf.HostnameList{},
+				"ns1":     &host_rule_conf.HostnameList{"default"},
+			},
+		},
+		{
+			name: "ingresses without host",
+			ingresses: []*netv1.Ingress{
+				newIngress("ns1", "a", ""),
+			},
+			host:    "foo.com",
+			want:    []string{"default"},
+			wantAll: []string{"default"},
+			wantHosts: host_rule_conf.HostTagToHost{
+				"default": &host_rule_conf.HostnameList{"default"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newRouteRuleCache("")
+			for _, ing := range tt.ingresses {
+				if err := cache.UpdateByIngress(ing); err != nil {
+					t.Fatalf("UpdateByIngress() error = %v", err)
+				}
+			}
+			products := newProductTable(ingress.ProductModeNamespace, cache.GetRules())
+
+			if got := products.Of(tt.host); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Of(%s) = %v, want %v", tt.host, got, tt.want)
+			}
+			if got := products.All(); !reflect.DeepEqual(got, tt.wantAll) {
+				t.Errorf("All() = %v, want %v", got, tt.wantAll)
+			}
+
+			conf := newHostTableConf("", products)
+			if err := host_rule_conf.HostTableConfCheck(*conf); err != nil {
+				t.Fatalf("HostTableConfCheck() error = %v", err)
+			}
+			if !reflect.DeepEqual(*conf.Hosts, tt.wantHosts) {
+				t.Errorf("Hosts = %v, want %v", *conf.Hosts, tt.wantHosts)
+			}
+		})
+	}
+}
+
+func Test_productTableUpdate(t *testing.T) {
+	products := NewProductTable()
+	if got := products.ConfVersion("1"); got != "1" {
+		t.Errorf("ConfVersion() = %v, want 1", got)
+	}
+
+	if !products.update(newTestProductTable(t, ingress.ProductModeNamespace)) {
+		t.Errorf("update() = false, want true")
+	}
+	version := products.ConfVersion("1")
+	if version == "1" {
+		t.Errorf("ConfVersion() is not changed with products")
+	}
+
+	if products.update(newTestProductTable(t, ingress.ProductModeNamespace)) {
+		t.Errorf("update() = true, want false")
+	}
+	if got := products.ConfVersion("1"); got != version {
+		t.Errorf("ConfVersion() = %v, want %v", got, version)
+	}
+}
+
+func Test_newHostTableConf(t *testing.T) {
+	conf := newHostTableConf("", newTestProductTable(t, ingress.ProductModeNamespace))
+	if err := host_rule_conf.HostTableConfCheck(*conf); err != nil {
+		t.Fatalf("HostTableConfCheck() error = %v", err)
+	}
+
+	wantHosts := host_rule_conf.HostTagToHost{
+		"default": &host_rule_conf.HostnameList{"default"},
+		"ns1":     &host_rule_conf.HostnameList{"*.foo.com", "a.foo.com"},
+		"ns2":     &host_rule_conf.HostnameList{"*.bar.com"},
+	}
+	if !reflect.DeepEqual(*conf.Hosts, wantHosts) {
+		t.Errorf("Hosts = %v, want %v", *conf.Hosts, wantHosts)
+	}
+	if got := *(*conf.HostTags)["ns1"]; !reflect.DeepEqual(got, host_rule_conf.HostTagList{"ns1"}) {
+		t.Errorf("HostTags[ns1] = %v, want [ns1]", got)
+	}
+}
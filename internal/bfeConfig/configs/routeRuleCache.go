@@ -15,6 +15,7 @@
 package configs
 
 import (
+	"fmt"
 	"sort"
 	"time"
 
@@ -24,6 +25,7 @@ import (
 
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs/cache"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
 )
 
 type routeRule struct {
@@ -56,14 +58,32 @@ func newRouteRule(ingress string, host string, path string, annots map[string]st
 
 func (c *RouteRuleCache) getRouteRules() (basicRuleList []*routeRule, advancedRuleList []*routeRule) {
 	httpRules := c.BaseRules
-	for _, paths := range httpRules.RuleMap {
+	advancedHosts := c.advancedHosts()
+
+	for host, paths := range httpRules.RuleMap {
+		// regex paths, multi-label wildcard hosts and router.priority can't be handled by basic rules, and basic rules are matched before
+		// advanced rules, so all rules of such hosts are converted to advanced rules to be matched in the order of priority
+		if advancedHosts[host] {
+			for _, ruleList := range paths {
+				for _, rule := range ruleList {
+					advancedRuleList = append(advancedRuleList, rule.(*routeRule))
+				}
+			}
+			// add a fake basicRule for all paths of the host, so requests of the host aren't matched by basic rules of wildcard hosts,
+			// "*" of multi-label wildcard hosts can't be matched by basic rules, and requests of such hosts fall through to advanced rules
+			if !cache.IsMultiLabelWildcardHost(host) {
+				basicRuleList = append(basicRuleList, newRouteRule("", host, "/*", nil, route_rule_conf.AdvancedMode, time.Time{}))
+			}
+			continue
+		}
+
 		for _, ruleList := range paths {
 			if len(ruleList) == 0 {
 				continue
 			}
 
 			// add host+path rule to basic rule list
-			if len(ruleList) == 1 && annotations.Priority(ruleList[0].GetAnnotations()) == annotations.PriorityBasic {
+			if len(ruleList) == 1 && annotations.Priority(ruleList[0].GetAnnotations()) == annotations.PriorityBasic && !annotations.IsCanary(ruleList[0].GetAnnotations()) {
 				basicRuleList = append(basicRuleList, ruleList[0].(*routeRule))
 				continue
 			}
@@ -88,7 +108,58 @@ func (c *RouteRuleCache) getRouteRules() (basicRuleList []*routeRule, advancedRu
 	return
 }
 
+// advancedHosts returns hosts of which all rules should be advanced rules, i.e. hosts with a regex path or a priority set by
+// annotation, and multi-label wildcard hosts
+func (c *RouteRuleCache) advancedHosts() map[string]bool {
+	hosts := make(map[string]bool)
+	for host, paths := range c.BaseRules.RuleMap {
+		for path, ruleList := range paths {
+			if len(ruleList) > 0 && (cache.IsRegexPath(path) || cache.IsMultiLabelWildcardHost(host)) {
+				hosts[host] = true
+			}
+			for _, rule := range ruleList {
+				if priority, _ := annotations.GetRoutePriority(rule.GetAnnotations()); priority != 0 {
+					hosts[host] = true
+				}
+			}
+		}
+	}
+
+	// basic rules without host match requests of all hosts not in basic rules, including hosts of multi-label wildcard hosts
+	for host := range hosts {
+		if cache.IsMultiLabelWildcardHost(host) {
+			if _, ok := c.BaseRules.RuleMap["*"]; ok {
+				hosts["*"] = true
+			}
+			break
+		}
+	}
+	return hosts
+}
+
+// routeStatuses returns the order of rules of each ingress among rules of the same host, starting from 1
+func (c *RouteRuleCache) routeStatuses() map[string][]annotations.RouteStatus {
+	orders := make(map[string]int)
+	routes := make(map[string][]annotations.RouteStatus)
+	for _, rule := range c.GetRules() {
+		orders[rule.GetHost()]++
+		routes[rule.GetIngress()] = append(routes[rule.GetIngress()], annotations.RouteStatus{
+			Host:  rule.GetHost(),
+			Path:  rule.GetPath(),
+			Order: orders[rule.GetHost()],
+		})
+	}
+	return routes
+}
+
 func (c *RouteRuleCache) UpdateByIngress(ingress *netv1.Ingress) error {
+	if _, err := annotations.GetRoutePriority(ingress.Annotations); err != nil {
+		return err
+	}
+	if _, ok := ingress.Annotations[annotations.ConditionAnnotation]; ok && !option.Opts.Ingress.RouterConditionAllowed(ingress.Namespace) {
+		return fmt.Errorf("annotation %s is illegal, it's disabled in namespace %s", annotations.ConditionAnnotation, ingress.Namespace)
+	}
+
 	return c.BaseCache.UpdateByIngressFramework(
 		ingress,
 		func(ingress *netv1.Ingress, host, path string, httpPath netv1.HTTPIngressPath) (cache.Rule, error) {
@@ -15,8 +15,13 @@
 package configs
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/bfenetworks/bfe/bfe_config/bfe_route_conf/route_rule_conf"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func Test_putBasic(t *testing.T) {
@@ -273,3 +278,289 @@ func Test_deleteRule(t *testing.T) {
 	}
 
 }
+
+func Test_regexPathRule(t *testing.T) {
+	cache := newRouteRuleCache("init")
+
+	rules := []*routeRule{
+		newRouteRule("ingress1", "example.com", "/foo*", nil, "svc1", time.Now()),
+		newRouteRule("ingress2", "example.com", "~/foo/v[0-9]+", nil, "svc2", time.Now()),
+		newRouteRule("ingress3", "example.com", "/foo/v1", nil, "svc3", time.Now()),
+		newRouteRule("ingress4", "example.com", "~/foo/.*", nil, "svc4", time.Now()),
+		newRouteRule("ingress5", "*", "/*", nil, "svc5", time.Now()),
+		newRouteRule("ingress6", "*.example.com", "/*", nil, "svc6", time.Now()),
+	}
+	for _, r := range rules {
+		if err := cache.PutRule(r); err != nil {
+			t.Fatalf("PutRule() error = %v", err)
+		}
+	}
+
+	basicList, advancedList := cache.getRouteRules()
+	// rules of other hosts are still basic rules
+	checkBasicRoutes(t, basicList, map[string]string{
+		"example.com/foo/v1": route_rule_conf.AdvancedMode,
+		"a.example.com/foo":  "svc6",
+		"foo.com/":           "svc5",
+	})
+
+	// exact path over regex path over prefix path
+	want := []string{"ingress3", "ingress2", "ingress4", "ingress1"}
+	if len(advancedList) != len(want) {
+		t.Fatalf("getRouteRules() got %d advanced rules, want %d", len(advancedList), len(want))
+	}
+	for i, r := range advancedList {
+		if r.GetIngress() != want[i] {
+			t.Errorf("getRouteRules() advanced rule %d is %s, want %s", i, r.GetIngress(), want[i])
+		}
+	}
+
+	cond, err := advancedList[1].GetCond()
+	if err != nil || cond != "req_host_in(\"example.com\")&&req_path_regmatch(`^(?:/foo/v[0-9]+)`)" {
+		t.Errorf("GetCond() of regex path got %s, error %v", cond, err)
+	}
+}
+
+func Test_routePriority(t *testing.T) {
+	cache := newRouteRuleCache("init")
+
+	priority := map[string]string{"bfe.ingress.kubernetes.io/router.priority": "-1"}
+	rules := []*routeRule{
+		newRouteRule("ingress1", "example.com", "/*", map[string]string{"bfe.ingress.kubernetes.io/router.cookie": "a: b"}, "svc1", time.Now()),
+		newRouteRule("ingress2", "example.com", "/foo*", nil, "svc2", time.Now()),
+		newRouteRule("ingress3", "example.com", "/foo/bar*", priority, "svc3", time.Now()),
+		newRouteRule("ingress4", "*.example.com", "/*", nil, "svc4", time.Now()),
+	}
+	for _, r := range rules {
+		if err := cache.PutRule(r); err != nil {
+			t.Fatalf("PutRule() error = %v", err)
+		}
+	}
+
+	basicList, advancedList := cache.getRouteRules()
+	checkBasicRoutes(t, basicList, map[string]string{
+		"example.com/foo/bar": route_rule_conf.AdvancedMode,
+		"a.example.com/":      "svc4",
+	})
+	want := []string{"ingress2", "ingress1", "ingress3"}
+	if len(advancedList) != len(want) {
+		t.Fatalf("getRouteRules() got %d advanced rules, want %d", len(advancedList), len(want))
+	}
+	for i, r := range advancedList {
+		if r.GetIngress() != want[i] {
+			t.Errorf("getRouteRules() advanced rule %d is %s, want %s", i, r.GetIngress(), want[i])
+		}
+	}
+
+	routes := cache.routeStatuses()["ingress3"]
+	if len(routes) != 1 || routes[0].Order != 3 || routes[0].Host != "example.com" || routes[0].Path != "/foo/bar*" {
+		t.Errorf("routeStatuses() got %+v", routes)
+	}
+	routes = cache.routeStatuses()["ingress4"]
+	if len(routes) != 1 || routes[0].Order != 1 {
+		t.Errorf("routeStatuses() got %+v", routes)
+	}
+}
+
+func Test_canaryRule(t *testing.T) {
+	cache := newRouteRuleCache("init")
+
+	canary := map[string]string{
+		"bfe.ingress.kubernetes.io/canary":        "true",
+		"bfe.ingress.kubernetes.io/canary.weight": "20",
+	}
+	rules := []*routeRule{
+		newRouteRule("main", "example.com", "/foo*", nil, "svc1", time.Now()),
+		newRouteRule("canary", "example.com", "/foo*", canary, "svc2", time.Now()),
+	}
+	for _, r := range rules {
+		if err := cache.PutRule(r); err != nil {
+			t.Fatalf("PutRule() error = %v", err)
+		}
+	}
+	if err := cache.PutRule(newRouteRule("canary2", "example.com", "/foo*", canary, "svc3", time.Now())); err == nil {
+		t.Errorf("PutRule() of second canary should conflict")
+	}
+
+	_, advancedList := cache.getRouteRules()
+	if len(advancedList) != 2 || advancedList[0].GetIngress() != "canary" || advancedList[1].GetIngress() != "main" {
+		t.Fatalf("getRouteRules() canary rule should be matched before main rule")
+	}
+	cond, err := advancedList[0].GetCond()
+	if err != nil || cond != `req_host_in("example.com")&&req_path_element_prefix_in("/foo", false)&&req_cip_hash_in("0-1999")` {
+		t.Errorf("GetCond() of canary rule got %s, error %v", cond, err)
+	}
+
+	// canary rule without main rule isn't a basic rule
+	cache.DeleteByIngress("main")
+	basicList, advancedList := cache.getRouteRules()
+	if len(advancedList) != 1 || basicList[0].Cluster == "svc2" {
+		t.Errorf("getRouteRules() canary rule should be an advanced rule")
+	}
+}
+
+func newTestIngress(name string, annots map[string]string, hosts ...string) *netv1.Ingress {
+	pathType := netv1.PathTypePrefix
+	ingress := &netv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        name,
+			Annotations: annots,
+		},
+	}
+	for _, host := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, netv1.IngressRule{
+			Host: host,
+			IngressRuleValue: netv1.IngressRuleValue{HTTP: &netv1.HTTPIngressRuleValue{
+				Paths: []netv1.HTTPIngressPath{{
+					Path:     "/",
+					PathType: &pathType,
+					Backend:  netv1.IngressBackend{Service: &netv1.IngressServiceBackend{Name: "svc"}},
+				}},
+			}},
+		})
+	}
+	return ingress
+}
+
+func Test_hostAliases(t *testing.T) {
+	cache := newRouteRuleCache("init")
+
+	aliases := map[string]string{"bfe.ingress.kubernetes.io/router.host-aliases": `{"foo.com": ["www.foo.com", "Foo.org:8080"]}`}
+	if err := cache.UpdateByIngress(newTestIngress("ingress1", aliases, "foo.com", "bar.com")); err != nil {
+		t.Fatalf("UpdateByIngress() error = %v", err)
+	}
+
+	hosts := make(map[string]bool)
+	for _, rule := range cache.GetRules() {
+		hosts[rule.GetHost()] = true
+	}
+	for _, host := range []string{"foo.com", "www.foo.com", "foo.org", "bar.com"} {
+		if !hosts[host] {
+			t.Errorf("UpdateByIngress() rule of host %s not found", host)
+		}
+	}
+	if len(hosts) != 4 {
+		t.Errorf("UpdateByIngress() got hosts %v", hosts)
+	}
+
+	// alias conflicts with rules of newer ingresses
+	ingress := newTestIngress("ingress2", nil, "www.foo.com")
+	ingress.CreationTimestamp = metav1.Now()
+	if err := cache.UpdateByIngress(ingress); err == nil {
+		t.Errorf("UpdateByIngress() should conflict with alias")
+	}
+
+	// host of alias should be a host of rules
+	aliases = map[string]string{"bfe.ingress.kubernetes.io/router.host-aliases": `{"baz.com": ["www.baz.com"]}`}
+	if err := cache.UpdateByIngress(newTestIngress("ingress3", aliases, "foo.net")); err == nil {
+		t.Errorf("UpdateByIngress() should fail if host of alias is not a host of rules")
+	}
+}
+
+func Test_multiLabelWildcardHost(t *testing.T) {
+	cache := newRouteRuleCache("init")
+
+	multi := map[string]string{"bfe.ingress.kubernetes.io/router.wildcard-host": "multi-label"}
+	ingresses := []*netv1.Ingress{
+		newTestIngress("ingress1", multi, "*.example.com"),
+		newTestIngress("ingress2", nil, "*.example.com"),
+		newTestIngress("ingress3", multi, "*.foo.example.com"),
+		newTestIngress("ingress4", nil, ""),
+	}
+	for _, ingress := range ingresses {
+		if err := cache.UpdateByIngress(ingress); err != nil {
+			t.Fatalf("UpdateByIngress() error = %v", err)
+		}
+	}
+
+	basicList, advancedList := cache.getRouteRules()
+	// rules without host would match requests of multi-label wildcard hosts if they were basic rules
+	checkBasicRoutes(t, basicList, map[string]string{
+		"a.example.com/":       "default/ingress2_svc_",
+		"foo.example.com/":     "default/ingress2_svc_",
+		"a.b.example.com/":     route_rule_conf.AdvancedMode,
+		"a.foo.example.com/":   route_rule_conf.AdvancedMode,
+		"a.b.foo.example.com/": route_rule_conf.AdvancedMode,
+		"foo.com/":             route_rule_conf.AdvancedMode,
+	})
+	want := []string{"default/ingress3", "default/ingress1", "default/ingress4"}
+	if len(advancedList) != len(want) {
+		t.Fatalf("getRouteRules() got %d advanced rules, want %d", len(advancedList), len(want))
+	}
+	for i, r := range advancedList {
+		if r.GetIngress() != want[i] {
+			t.Errorf("getRouteRules() advanced rule %d is %s, want %s", i, r.GetIngress(), want[i])
+		}
+	}
+
+	cond, err := advancedList[1].GetCond()
+	if err != nil || cond != "req_host_regmatch(`(?i)^.+\\.example\\.com$`)&&req_path_element_prefix_in(\"/\", false)" {
+		t.Errorf("GetCond() of multi-label wildcard host got %s, error %v", cond, err)
+	}
+}
+
+func Test_routePriorityPerHost(t *testing.T) {
+	cache := newRouteRuleCache("init")
+
+	priority := map[string]string{"bfe.ingress.kubernetes.io/router.priority": "1"}
+	rules := []*routeRule{
+		newRouteRule("ingress1", "a.example.com", "/foo*", priority, "svc1", time.Now()),
+		newRouteRule("ingress2", "a.example.com", "/*", nil, "svc2", time.Now()),
+		newRouteRule("ingress3", "b.example.com", "/foo*", nil, "svc3", time.Now()),
+		newRouteRule("ingress4", "b.example.com", "/bar", nil, "svc4", time.Now()),
+		newRouteRule("ingress5", "*.example.com", "/*", nil, "svc5", time.Now()),
+	}
+	for _, r := range rules {
+		if err := cache.PutRule(r); err != nil {
+			t.Fatalf("PutRule() error = %v", err)
+		}
+	}
+
+	basicList, advancedList := cache.getRouteRules()
+	// only rules of the host using priority are advanced rules, and its requests aren't matched by the wildcard host
+	checkBasicRoutes(t, basicList, map[string]string{
+		"a.example.com/foo": route_rule_conf.AdvancedMode,
+		"a.example.com/baz": route_rule_conf.AdvancedMode,
+		"b.example.com/foo": "svc3",
+		"b.example.com/bar": "svc4",
+		"c.example.com/foo": "svc5",
+	})
+	want := []string{"ingress1", "ingress2"}
+	if len(advancedList) != len(want) {
+		t.Fatalf("getRouteRules() got %d advanced rules, want %d", len(advancedList), len(want))
+	}
+	for i, r := range advancedList {
+		if r.GetIngress() != want[i] {
+			t.Errorf("getRouteRules() advanced rule %d is %s, want %s", i, r.GetIngress(), want[i])
+		}
+	}
+}
+
+// checkBasicRoutes checks clusters of requests matched by the basic rules, routes are host+path => cluster
+func checkBasicRoutes(t *testing.T, basicList []*routeRule, routes map[string]string) {
+	t.Helper()
+
+	tree := route_rule_conf.NewBasicRouteRuleTree()
+	for _, rule := range basicList {
+		ruleFile := &route_rule_conf.BasicRouteRuleFile{
+			ClusterName: &rule.Cluster,
+		}
+		if len(rule.GetHost()) > 0 && rule.GetHost() != "*" {
+			ruleFile.Hostname = []string{rule.GetHost()}
+		}
+		if len(rule.GetPath()) > 0 {
+			ruleFile.Path = []string{rule.GetPath()}
+		}
+		if err := tree.Insert(ruleFile); err != nil {
+			t.Fatalf("basic rule of %s%s is illegal: %v", rule.GetHost(), rule.GetPath(), err)
+		}
+	}
+
+	for route, want := range routes {
+		i := strings.Index(route, "/")
+		if got, _ := tree.Get(route[:i], route[i:]); got != want {
+			t.Errorf("basic rules route %s to %q, want %q", route, got, want)
+		}
+	}
+}
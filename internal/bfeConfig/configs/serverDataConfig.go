@@ -16,12 +16,16 @@ package configs
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 
+	"github.com/jwangsadinata/go-multimap/setmultimap"
 	netv1 "k8s.io/api/networking/v1"
 
 	"github.com/bfenetworks/bfe/bfe_config/bfe_cluster_conf/cluster_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_route_conf/host_rule_conf"
 	"github.com/bfenetworks/bfe/bfe_config/bfe_route_conf/route_rule_conf"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
 	"github.com/bfenetworks/ingress-bfe/internal/option"
 )
@@ -29,6 +33,12 @@ import (
 const (
 	DefaultProduct       = "default"
 	ConfigNameServerData = "server_data_conf"
+
+	// placeholderHost is the host of the default product while the product has no host
+	placeholderHost = "default"
+
+	// AcmeChallengePath is the path prefix of ACME HTTP-01 challenge requests
+	AcmeChallengePath = "/.well-known/acme-challenge/"
 )
 
 var (
@@ -43,33 +53,55 @@ type ServerDataConfig struct {
 	bfeClusterConfVersion string
 
 	routeRuleCache *RouteRuleCache
+	// products is updated with route rules, and shared with configs of modules
+	products *ProductTable
+
+	// ingress -> hosts whose ACME HTTP-01 challenges are served by the controller
+	ingress2AcmeHost *setmultimap.MultiMap
+
+	// ingress -> order of its route rules, which is reported in the status of the ingress
+	routeStatuses map[string][]annotations.RouteStatus
+	// ingresses whose order of route rules is changed by updating or deleting other ingresses
+	routeStatusChanged map[string]bool
 
 	hostTableConf  *host_rule_conf.HostTableConf
 	routeTableFile *route_rule_conf.RouteTableFile
 	bfeClusterConf *cluster_conf.BfeClusterConf
 }
 
-func NewServerDataConfig(version string) *ServerDataConfig {
+func NewServerDataConfig(version string, products *ProductTable) *ServerDataConfig {
 	return &ServerDataConfig{
-		routeRuleCache: newRouteRuleCache(version),
-		hostTableConf:  newHostTableConf(version),
-		routeTableFile: newRouteTableConfFile(version),
-		bfeClusterConf: newBfeClusterConf(version),
+		routeRuleCache:     newRouteRuleCache(version),
+		products:           products,
+		ingress2AcmeHost:   setmultimap.New(),
+		routeStatuses:      make(map[string][]annotations.RouteStatus),
+		routeStatusChanged: make(map[string]bool),
+		hostTableConf:      newHostTableConf(version, products),
+		routeTableFile:     newRouteTableConfFile(version, products),
+		bfeClusterConf:     newBfeClusterConf(version),
 	}
 }
 
-func newHostTableConf(version string) *host_rule_conf.HostTableConf {
+// newHostTableConf builds host table with one host tag for each product, the tag is named after the product
+func newHostTableConf(version string, products *ProductTable) *host_rule_conf.HostTableConf {
 	hostTagToHost := make(host_rule_conf.HostTagToHost)
 	productToHostTag := make(host_rule_conf.ProductToHostTag)
 
-	// all requests go to default product
-	product := DefaultProduct
-	var hostnameList host_rule_conf.HostnameList
-	hostnameList = append(hostnameList, product)
-	hostTagToHost[product] = &hostnameList
+	hosts := products.hosts()
+	for _, product := range products.All() {
+		hostnameList := append(host_rule_conf.HostnameList{}, hosts[product]...)
+		hostTagToHost[product] = &hostnameList
 
-	list := host_rule_conf.HostTagList{product}
-	productToHostTag[product] = &list
+		list := host_rule_conf.HostTagList{product}
+		productToHostTag[product] = &list
+	}
+
+	// requests of other hosts go to default product, its host tag keeps the placeholder host if it has no host,
+	// unless the placeholder is used by ingresses, since a host can't be in more than one host tag
+	product := DefaultProduct
+	if len(*hostTagToHost[product]) == 0 && !products.hasHost(placeholderHost) {
+		*hostTagToHost[product] = append(*hostTagToHost[product], placeholderHost)
+	}
 
 	return &host_rule_conf.HostTableConf{
 		Version:        &version,
@@ -80,7 +112,7 @@ func newHostTableConf(version string) *host_rule_conf.HostTableConf {
 }
 
 // newRouteTableConfFile build route table for all ingress rules
-func newRouteTableConfFile(version string) *route_rule_conf.RouteTableFile {
+func newRouteTableConfFile(version string, products *ProductTable) *route_rule_conf.RouteTableFile {
 	basicRule := make(route_rule_conf.ProductBasicRouteRuleFile)
 	productRule := make(route_rule_conf.ProductAdvancedRouteRuleFile)
 	routeTable := &route_rule_conf.RouteTableFile{
@@ -89,8 +121,10 @@ func newRouteTableConfFile(version string) *route_rule_conf.RouteTableFile {
 		ProductRule: &productRule,
 	}
 
-	(*routeTable.BasicRule)[DefaultProduct] = make(route_rule_conf.BasicRouteRuleFiles, 0)
-	(*routeTable.ProductRule)[DefaultProduct] = make(route_rule_conf.AdvancedRouteRuleFiles, 0)
+	for _, product := range products.All() {
+		(*routeTable.BasicRule)[product] = make(route_rule_conf.BasicRouteRuleFiles, 0)
+		(*routeTable.ProductRule)[product] = make(route_rule_conf.AdvancedRouteRuleFiles, 0)
+	}
 
 	return routeTable
 }
@@ -111,11 +145,13 @@ func (c *ServerDataConfig) UpdateIngress(ingress *netv1.Ingress) error {
 	}
 
 	ingressName := util.NamespacedName(ingress.Namespace, ingress.Name)
+	defer c.updateRouteStatuses(ingressName)
 
 	//delete existing ingress
 	if c.routeRuleCache.ContainsIngress(ingressName) {
 		c.routeRuleCache.DeleteByIngress(ingressName)
 	}
+	c.ingress2AcmeHost.RemoveAll(ingressName)
 
 	if err := c.updateCache(ingress); err != nil {
 		// delete rules which have been inserted
@@ -123,10 +159,19 @@ func (c *ServerDataConfig) UpdateIngress(ingress *netv1.Ingress) error {
 		return err
 	}
 
+	if acme, _ := annotations.GetAcme(ingress.Annotations); acme && option.Opts.Ingress.AcmeEnabled() {
+		for _, tls := range ingress.Spec.TLS {
+			for _, host := range tls.Hosts {
+				c.ingress2AcmeHost.Put(ingressName, host)
+			}
+		}
+	}
+
 	// TODO: avoid calling c.updateRouteTable() and c.updateBfeClusterConf() frequently
 
 	if err := c.updateRouteTable(); err != nil {
 		c.routeRuleCache.DeleteByIngress(ingressName)
+		c.ingress2AcmeHost.RemoveAll(ingressName)
 		return err
 	}
 
@@ -137,6 +182,7 @@ func (c *ServerDataConfig) UpdateIngress(ingress *netv1.Ingress) error {
 
 func (c *ServerDataConfig) DeleteIngress(namespace, name string) {
 	ingressName := util.NamespacedName(namespace, name)
+	c.ingress2AcmeHost.RemoveAll(ingressName)
 
 	if !c.routeRuleCache.ContainsIngress(ingressName) {
 		return
@@ -145,6 +191,36 @@ func (c *ServerDataConfig) DeleteIngress(namespace, name string) {
 	c.routeRuleCache.DeleteByIngress(ingressName)
 	_ = c.updateRouteTable()
 	c.updateBfeClusterConf()
+	c.updateRouteStatuses(ingressName)
+}
+
+// RouteStatus returns the order of route rules of the ingress
+func (c *ServerDataConfig) RouteStatus(namespace, name string) []annotations.RouteStatus {
+	return c.routeStatuses[util.NamespacedName(namespace, name)]
+}
+
+// RouteStatusChanged returns ingresses whose order of route rules has been changed by other ingresses since the last call
+func (c *ServerDataConfig) RouteStatusChanged() []string {
+	ingresses := make([]string, 0, len(c.routeStatusChanged))
+	for ingress := range c.routeStatusChanged {
+		ingresses = append(ingresses, ingress)
+	}
+	sort.Strings(ingresses)
+
+	c.routeStatusChanged = make(map[string]bool)
+	return ingresses
+}
+
+// updateRouteStatuses updates the order of route rules after the ingress is updated or deleted,
+// and records other ingresses whose order is changed, e.g. ingresses of the same host
+func (c *ServerDataConfig) updateRouteStatuses(ingressName string) {
+	routeStatuses := c.routeRuleCache.routeStatuses()
+	for ingress, routes := range routeStatuses {
+		if ingress != ingressName && !reflect.DeepEqual(routes, c.routeStatuses[ingress]) {
+			c.routeStatusChanged[ingress] = true
+		}
+	}
+	c.routeStatuses = routeStatuses
 }
 
 func (c *ServerDataConfig) updateCache(ingress *netv1.Ingress) error {
@@ -154,7 +230,9 @@ func (c *ServerDataConfig) updateCache(ingress *netv1.Ingress) error {
 func (c *ServerDataConfig) updateRouteTable() error {
 	basicRules, advancedRules := c.routeRuleCache.getRouteRules()
 
-	routeTableFile := newRouteTableConfFile(util.NewVersion())
+	products := newProductTable(option.Opts.Ingress.ProductMode, c.routeRuleCache.GetRules())
+
+	routeTableFile := newRouteTableConfFile(util.NewVersion(), products)
 	for _, rule := range basicRules {
 		ruleFile := route_rule_conf.BasicRouteRuleFile{
 			ClusterName: &rule.Cluster,
@@ -168,8 +246,22 @@ func (c *ServerDataConfig) updateRouteTable() error {
 			ruleFile.Path = []string{rule.GetPath()}
 		}
 
-		(*routeTableFile.BasicRule)[DefaultProduct] = append(
-			(*routeTableFile.BasicRule)[DefaultProduct], ruleFile)
+		for _, product := range products.Of(rule.GetHost()) {
+			(*routeTableFile.BasicRule)[product] = append((*routeTableFile.BasicRule)[product], ruleFile)
+		}
+	}
+
+	// challenge requests of acme hosts are served by the controller
+	for _, host := range c.acmeHosts() {
+		cluster := util.AcmeClusterName()
+		ruleFile := route_rule_conf.BasicRouteRuleFile{
+			Hostname:    []string{host},
+			Path:        []string{AcmeChallengePath + "*"},
+			ClusterName: &cluster,
+		}
+		for _, product := range products.Of(host) {
+			(*routeTableFile.BasicRule)[product] = append((*routeTableFile.BasicRule)[product], ruleFile)
+		}
 	}
 
 	for _, rule := range advancedRules {
@@ -181,7 +273,9 @@ func (c *ServerDataConfig) updateRouteTable() error {
 			Cond:        &condition,
 			ClusterName: &rule.Cluster,
 		}
-		(*routeTableFile.ProductRule)[DefaultProduct] = append((*routeTableFile.ProductRule)[DefaultProduct], ruleFile)
+		for _, product := range products.Of(rule.GetHost()) {
+			(*routeTableFile.ProductRule)[product] = append((*routeTableFile.ProductRule)[product], ruleFile)
+		}
 	}
 
 	if len(option.Opts.Ingress.DefaultBackend) > 0 && (len(basicRules) > 0 || len(advancedRules) > 0) {
@@ -191,7 +285,9 @@ func (c *ServerDataConfig) updateRouteTable() error {
 			Cond:        &condition,
 			ClusterName: &cluster,
 		}
-		(*routeTableFile.ProductRule)[DefaultProduct] = append((*routeTableFile.ProductRule)[DefaultProduct], ruleFile)
+		for _, product := range products.All() {
+			(*routeTableFile.ProductRule)[product] = append((*routeTableFile.ProductRule)[product], ruleFile)
+		}
 	}
 
 	// check routeTableFile
@@ -199,6 +295,10 @@ func (c *ServerDataConfig) updateRouteTable() error {
 		return fmt.Errorf("fail to check generated routeTableFile, err: %s", err)
 	}
 
+	// host table and configs of modules are changed with products
+	if c.products.update(products) {
+		c.hostTableConf = newHostTableConf(util.NewVersion(), c.products)
+	}
 	c.routeTableFile = routeTableFile
 
 	return nil
@@ -225,6 +325,12 @@ func (c *ServerDataConfig) updateBfeClusterConf() {
 			GslbBasic: newGslbBasicConf(),
 		}
 	}
+	if len(c.acmeHosts()) > 0 {
+		(*clusterConf.Config)[util.AcmeClusterName()] = cluster_conf.ClusterConf{
+			CheckConf: newCheckConf(),
+			GslbBasic: newGslbBasicConf(),
+		}
+	}
 	if len(option.Opts.Ingress.DefaultBackend) > 0 && (len(basicRules) > 0 || len(advancedRules) > 0) {
 		(*clusterConf.Config)[util.DefaultClusterName()] = cluster_conf.ClusterConf{
 			CheckConf: newCheckConf(),
@@ -235,6 +341,22 @@ func (c *ServerDataConfig) updateBfeClusterConf() {
 	c.bfeClusterConf = clusterConf
 }
 
+// acmeHosts returns the sorted hosts whose ACME challenges should be routed to the controller
+func (c *ServerDataConfig) acmeHosts() []string {
+	set := make(map[string]bool)
+	for _, host := range c.ingress2AcmeHost.Values() {
+		set[host.(string)] = true
+	}
+
+	// a host may be used by more than one ingress
+	hosts := make([]string, 0, len(set))
+	for host := range set {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
 func newCheckConf() *cluster_conf.BackendCheck {
 	schem := "tcp"
 	return &cluster_conf.BackendCheck{
@@ -258,13 +380,7 @@ func newGslbBasicConf() *cluster_conf.GslbBasicConf {
 
 func (c *ServerDataConfig) Reload() error {
 	reload := false
-	if *c.hostTableConf.Version != c.hostTableVersion {
-		err := util.DumpBfeConf(HostRuleData, c.hostTableConf)
-		if err != nil {
-			return fmt.Errorf("dump gslb.data error: %v", err)
-		}
-		reload = true
-	}
+	// the host table is changed with products, which are updated with the route table
 	if *c.routeTableFile.Version != c.routeTableVersion {
 		if err := c.updateRouteTable(); err != nil {
 			if err != nil {
@@ -277,6 +393,13 @@ func (c *ServerDataConfig) Reload() error {
 		}
 		reload = true
 	}
+	if *c.hostTableConf.Version != c.hostTableVersion {
+		err := util.DumpBfeConf(HostRuleData, c.hostTableConf)
+		if err != nil {
+			return fmt.Errorf("dump gslb.data error: %v", err)
+		}
+		reload = true
+	}
 
 	if *c.bfeClusterConf.Version != c.bfeClusterConfVersion {
 		c.updateBfeClusterConf()
@@ -0,0 +1,87 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configs
+
+import (
+	"reflect"
+	"testing"
+
+	netv1 "k8s.io/api/networking/v1"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+func Test_routeStatusChanged(t *testing.T) {
+	option.Opts = option.NewOptions()
+	defer func() { option.Opts = nil }()
+
+	priority := map[string]string{annotations.PriorityAnnotation: "-1"}
+	cookie := map[string]string{annotations.CookieAnnotation: "a: b"}
+	newIngress := func(name string, annots map[string]string, host, path string) *netv1.Ingress {
+		ingress := newTestIngress(name, annots, host)
+		ingress.Spec.Rules[0].HTTP.Paths[0].Path = path
+		return ingress
+	}
+	c := NewServerDataConfig("init", NewProductTable())
+
+	steps := []struct {
+		name        string
+		action      func() error
+		wantChanged []string
+		wantOrder   int // order of route rule of ingress a
+	}{
+		{"add ingress a", func() error {
+			return c.UpdateIngress(newIngress("a", nil, "foo.com", "/"))
+		}, []string{}, 1},
+		{"add ingress b of the same host with longer path", func() error {
+			return c.UpdateIngress(newIngress("b", nil, "foo.com", "/foo"))
+		}, []string{"default/a"}, 2},
+		{"add ingress c of another host", func() error {
+			return c.UpdateIngress(newIngress("c", nil, "bar.com", "/foo"))
+		}, []string{}, 2},
+		{"update ingress b without changing order", func() error {
+			return c.UpdateIngress(newIngress("b", nil, "foo.com", "/foo"))
+		}, []string{}, 2},
+		{"update ingress b with lower priority", func() error {
+			return c.UpdateIngress(newIngress("b", priority, "foo.com", "/foo"))
+		}, []string{"default/a"}, 1},
+		{"add ingress d of the same host with condition", func() error {
+			return c.UpdateIngress(newIngress("d", cookie, "foo.com", "/"))
+		}, []string{"default/a", "default/b"}, 2},
+		{"delete ingress d", func() error {
+			c.DeleteIngress("default", "d")
+			return nil
+		}, []string{"default/a", "default/b"}, 1},
+		{"update ingress a", func() error {
+			return c.UpdateIngress(newIngress("a", nil, "foo.com", "/"))
+		}, []string{}, 1},
+	}
+	for _, step := range steps {
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		if got := c.RouteStatusChanged(); !reflect.DeepEqual(got, step.wantChanged) {
+			t.Errorf("%s: RouteStatusChanged() = %v, want %v", step.name, got, step.wantChanged)
+		}
+		if got := c.RouteStatusChanged(); len(got) != 0 {
+			t.Errorf("%s: RouteStatusChanged() of the second call = %v, want empty", step.name, got)
+		}
+		routes := c.RouteStatus("default", "a")
+		if len(routes) != 1 || routes[0].Order != step.wantOrder {
+			t.Errorf("%s: RouteStatus() = %+v, want order %d", step.name, routes, step.wantOrder)
+		}
+	}
+}
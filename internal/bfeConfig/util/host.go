@@ -0,0 +1,46 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+
+	netv1 "k8s.io/api/networking/v1"
+)
+
+// IsTLSHost returns true if host of an ingress rule is listed in spec.tls of the ingress.
+// A wildcard tls host, e.g. "*.example.com", covers hosts of one more label, e.g. "foo.example.com".
+func IsTLSHost(host string, tls []netv1.IngressTLS) bool {
+	host = strings.ToLower(host)
+	if len(host) == 0 || host == "*" {
+		return false
+	}
+
+	for _, t := range tls {
+		for _, tlsHost := range t.Hosts {
+			tlsHost = strings.ToLower(tlsHost)
+			if host == tlsHost {
+				return true
+			}
+			if strings.HasPrefix(tlsHost, "*.") && !strings.HasPrefix(host, "*.") {
+				index := strings.Index(host, ".")
+				if index > 0 && host[index:] == tlsHost[1:] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -37,6 +37,12 @@ func DefaultClusterName() string {
 	return fmt.Sprintf("%s_%s_%d", ingress, option.Opts.Ingress.DefaultBackend, 0)
 }
 
+// AcmeClusterName returns a cluster for the local ACME HTTP-01 challenge solver
+func AcmeClusterName() string {
+	ingress := "__acmeCluster__"
+	return fmt.Sprintf("%s_%s_%d", ingress, "solver", option.Opts.Ingress.AcmeSolverPort)
+}
+
 func ParsePort(clusterName string) netv1.ServiceBackendPort {
 	port := netv1.ServiceBackendPort{}
 	index := strings.LastIndexByte(clusterName, '_')
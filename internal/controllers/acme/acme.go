@@ -0,0 +1,267 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package acme obtains certificates for ingresses annotated with "tls.acme",
+// and stores them in the Secrets referenced by spec.tls of the ingresses.
+package acme
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+const (
+	checkInterval = 10 * time.Second
+	retryInterval = 10 * time.Minute
+	issueTimeout  = 5 * time.Minute
+
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+var (
+	log = ctrl.Log.WithName("acme")
+)
+
+func AddAcmeController(mgr manager.Manager, cb *bfeConfig.ConfigBuilder) error {
+	namespace, name := util.SplitNamespacedName(option.Opts.Ingress.AcmeSecret)
+	store := newSecretStore(namespace, name, mgr.GetClient(), mgr.GetAPIReader())
+	solver := newHTTPSolver(store)
+	issuer, err := newIssuer(solver, store)
+	if err != nil {
+		return err
+	}
+
+	lock, err := newLeaseLock(mgr.GetConfig(), namespace, name)
+	if err != nil {
+		return err
+	}
+
+	return mgr.Add(&Manager{
+		BfeConfigBuilder: cb,
+		Client:           mgr.GetClient(),
+		lock:             lock,
+		solver:           solver,
+		issuer:           issuer,
+		firstSeen:        make(map[string]time.Time),
+		lastFailure:      make(map[string]time.Time),
+	})
+}
+
+// newLeaseLock returns the lock electing the replica which issues certificates
+func newLeaseLock(config *rest.Config, namespace, name string) (resourcelock.Interface, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	id, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	return resourcelock.New(resourcelock.LeasesResourceLock, namespace, name,
+		clientset.CoreV1(), clientset.CoordinationV1(), resourcelock.ResourceLockConfig{Identity: id})
+}
+
+// Manager serves HTTP-01 challenges on every replica, while certificates are only issued by the elected replica.
+// The election is independent of the controller manager, since ingresses are reconciled by every replica.
+type Manager struct {
+	BfeConfigBuilder *bfeConfig.ConfigBuilder
+
+	client.Client
+	lock   resourcelock.Interface
+	solver *httpSolver
+	issuer *issuer
+
+	// secret -> time when the certificate is found in the config builder
+	firstSeen map[string]time.Time
+	// secret -> time of the last failed issuance
+	lastFailure map[string]time.Time
+}
+
+// NeedLeaderElection implements the LeaderElectionRunnable interface, the solver runs on every replica
+func (m *Manager) NeedLeaderElection() bool {
+	return false
+}
+
+func (m *Manager) Start(ctx context.Context) error {
+	server := &http.Server{
+		Addr:    fmt.Sprintf("127.0.0.1:%d", option.Opts.Ingress.AcmeSolverPort),
+		Handler: m.solver,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error(err, "acme http-01 solver exit")
+		}
+	}()
+
+	// RunOrDie returns when the leadership is lost, then try to acquire it again
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            m.lock,
+			LeaseDuration:   leaseDuration,
+			RenewDeadline:   renewDeadline,
+			RetryPeriod:     retryPeriod,
+			ReleaseOnCancel: true,
+			Name:            "acme",
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: m.issue,
+				OnStoppedLeading: func() {
+					log.Info("stop issuing certificates")
+				},
+			},
+		})
+	}
+
+	log.Info("exit acme manager")
+	return server.Close()
+}
+
+// issue checks certificates periodically while this replica is the leader
+func (m *Manager) issue(ctx context.Context) {
+	log.Info("start issuing certificates")
+
+	// certificates may have been issued by the previous leader
+	m.firstSeen = make(map[string]time.Time)
+	m.lastFailure = make(map[string]time.Time)
+
+	tick := time.NewTicker(checkInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			m.sync(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Manager) sync(ctx context.Context) {
+	seen := make(map[string]time.Time)
+	for _, cert := range m.BfeConfigBuilder.AcmeCertificates() {
+		name := util.NamespacedName(cert.Namespace, cert.SecretName)
+		if t, ok := m.firstSeen[name]; ok {
+			seen[name] = t
+		} else {
+			seen[name] = time.Now()
+		}
+
+		// wait until the challenge route has been reloaded into bfe
+		if time.Since(seen[name]) < 2*option.Opts.Ingress.ReloadInterval {
+			continue
+		}
+		if t, ok := m.lastFailure[name]; ok && time.Since(t) < retryInterval {
+			continue
+		}
+
+		if err := m.ensureCertificate(ctx, cert); err != nil {
+			log.Error(err, "fail to obtain certificate", "secret", name, "hosts", cert.Hosts)
+			m.lastFailure[name] = time.Now()
+		} else {
+			delete(m.lastFailure, name)
+		}
+	}
+	m.firstSeen = seen
+}
+
+// ensureCertificate issues a certificate if the secret is missing, expiring or doesn't cover all hosts
+func (m *Manager) ensureCertificate(ctx context.Context, cert configs.AcmeCertificate) error {
+	secret := &corev1.Secret{}
+	err := m.Get(ctx, client.ObjectKey{Namespace: cert.Namespace, Name: cert.SecretName}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	exist := err == nil
+	if exist {
+		if _, ok := secret.Annotations[annotations.AcmeManagedAnnotation]; !ok {
+			// never overwrite certificates provided by users
+			return nil
+		}
+		if !needIssue(secret, cert.Hosts) {
+			return nil
+		}
+	}
+
+	log.Info("issuing certificate", "secret", util.NamespacedName(cert.Namespace, cert.SecretName), "hosts", cert.Hosts)
+	issueCtx, cancel := context.WithTimeout(ctx, issueTimeout)
+	defer cancel()
+	certPEM, keyPEM, err := m.issuer.obtain(issueCtx, cert.Hosts)
+	if err != nil {
+		return err
+	}
+
+	if !exist {
+		secret = &corev1.Secret{}
+		secret.Namespace = cert.Namespace
+		secret.Name = cert.SecretName
+		secret.Type = corev1.SecretTypeTLS
+		secret.Annotations = map[string]string{annotations.AcmeManagedAnnotation: "true"}
+	}
+	secret.Data = map[string][]byte{
+		configs.SecretCrt: certPEM,
+		configs.SecretKey: keyPEM,
+	}
+
+	// the secret controller will load the certificate into bfe
+	if exist {
+		return m.Update(ctx, secret)
+	}
+	return m.Create(ctx, secret)
+}
+
+// needIssue returns true if certificate in the secret expires soon or doesn't cover all hosts
+func needIssue(secret *corev1.Secret, hosts []string) bool {
+	block, _ := pem.Decode(secret.Data[configs.SecretCrt])
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+
+	if time.Until(cert.NotAfter) < option.Opts.Ingress.AcmeRenewBefore {
+		return true
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+)
+
+// newTestCertificate returns a self-signed PEM encoded certificate of hosts
+func newTestCertificate(t *testing.T, notAfter time.Time, hosts ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		DNSNames:     hosts,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func newTestSecret(crt []byte, managed bool) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo-tls"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{configs.SecretCrt: crt, configs.SecretKey: []byte("key")},
+	}
+	if managed {
+		secret.Annotations = map[string]string{annotations.AcmeManagedAnnotation: "true"}
+	}
+	return secret
+}
+
+func TestNeedIssue(t *testing.T) {
+	setTestOptions(t, "https://acme.example.com/dir", "")
+	renew := time.Now().Add(90 * 24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		crt   []byte
+		hosts []string
+		want  bool
+	}{
+		{"no certificate", nil, []string{"foo.com"}, true},
+		{"invalid certificate", []byte("invalid"), []string{"foo.com"}, true},
+		{"valid certificate", newTestCertificate(t, renew, "foo.com"), []string{"foo.com"}, false},
+		{"expiring certificate", newTestCertificate(t, time.Now().Add(time.Hour), "foo.com"), []string{"foo.com"}, true},
+		{"host not covered", newTestCertificate(t, renew, "foo.com"), []string{"foo.com", "bar.com"}, true},
+		{"host covered by wildcard", newTestCertificate(t, renew, "*.foo.com"), []string{"www.foo.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needIssue(newTestSecret(tt.crt, true), tt.hosts); got != tt.want {
+				t.Errorf("needIssue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureCertificate(t *testing.T) {
+	valid := newTestCertificate(t, time.Now().Add(90*24*time.Hour), "foo.com")
+	expiring := newTestCertificate(t, time.Now().Add(time.Hour), "foo.com")
+
+	tests := []struct {
+		name      string
+		secret    *corev1.Secret
+		wantIssue bool
+	}{
+		{"missing secret", nil, true},
+		{"secret provided by user", newTestSecret(expiring, false), false},
+		{"valid managed secret", newTestSecret(valid, true), false},
+		{"expiring managed secret", newTestSecret(expiring, true), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := newFakeDirectory(t)
+			setTestOptions(t, directory.URL+"/dir", directory.writeCAFile(t))
+
+			builder := fake.NewClientBuilder()
+			if tt.secret != nil {
+				builder = builder.WithObjects(tt.secret)
+			}
+			c := builder.Build()
+			solver := newHTTPSolver(nil)
+			i, err := newIssuer(solver, nil)
+			if err != nil {
+				t.Fatalf("newIssuer() error = %v", err)
+			}
+			directory.keyAuth = i.client.HTTP01ChallengeResponse
+			directory.solver = solver
+
+			m := &Manager{Client: c, solver: solver, issuer: i}
+			cert := configs.AcmeCertificate{Namespace: "default", SecretName: "foo-tls", Hosts: []string{"foo.com"}}
+			if err := m.ensureCertificate(context.Background(), cert); err != nil {
+				t.Fatalf("ensureCertificate() error = %v", err)
+			}
+
+			secret := &corev1.Secret{}
+			if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "foo-tls"}, secret); err != nil {
+				t.Fatalf("get secret error = %v", err)
+			}
+			issued := tt.secret == nil || !bytes.Equal(secret.Data[configs.SecretCrt], tt.secret.Data[configs.SecretCrt])
+			if issued != tt.wantIssue {
+				t.Errorf("issued = %v, want %v", issued, tt.wantIssue)
+			}
+			if issued {
+				if _, ok := secret.Annotations[annotations.AcmeManagedAnnotation]; !ok {
+					t.Errorf("issued secret is not annotated with %s", annotations.AcmeManagedAnnotation)
+				}
+				if needIssue(secret, cert.Hosts) {
+					t.Errorf("issued certificate is invalid")
+				}
+			}
+		})
+	}
+}
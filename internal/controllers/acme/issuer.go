@@ -0,0 +1,231 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	xacme "golang.org/x/crypto/acme"
+
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+const (
+	// challengePropagation is the time to wait for the challenge to be synchronized to other replicas
+	challengePropagation = 2 * time.Second
+	// challengeCleanupTimeout is the timeout of deleting a challenge after it's validated
+	challengeCleanupTimeout = 10 * time.Second
+)
+
+// issuer obtains certificates from an ACME server through HTTP-01 challenges
+type issuer struct {
+	client *xacme.Client
+	solver *httpSolver
+	// store keeps the account key, a new account is used for each issuer if it's nil
+	store      *secretStore
+	registered bool
+}
+
+func newIssuer(solver *httpSolver, store *secretStore) (*issuer, error) {
+	httpClient, err := newHTTPClient(option.Opts.Ingress.AcmeCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &issuer{
+		client: &xacme.Client{
+			DirectoryURL: option.Opts.Ingress.AcmeDirectoryURL,
+			HTTPClient:   httpClient,
+			UserAgent:    "bfe-ingress-controller",
+		},
+		solver: solver,
+		store:  store,
+	}, nil
+}
+
+// newHTTPClient returns a http client trusting the given CA bundle, which is used with local ACME servers like Pebble
+func newHTTPClient(caFile string) (*http.Client, error) {
+	if len(caFile) == 0 {
+		return http.DefaultClient, nil
+	}
+
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read acme ca file: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in acme ca file: %s", caFile)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: transport}, nil
+}
+
+func (i *issuer) register(ctx context.Context) error {
+	if i.registered {
+		return nil
+	}
+
+	if i.client.Key == nil {
+		key, err := i.accountKey(ctx)
+		if err != nil {
+			return fmt.Errorf("fail to get acme account key: %s", err)
+		}
+		i.client.Key = key
+	}
+
+	account := &xacme.Account{}
+	if len(option.Opts.Ingress.AcmeEmail) > 0 {
+		account.Contact = []string{"mailto:" + option.Opts.Ingress.AcmeEmail}
+	}
+	if _, err := i.client.Register(ctx, account, xacme.AcceptTOS); err != nil && err != xacme.ErrAccountAlreadyExists {
+		return fmt.Errorf("fail to register acme account: %s", err)
+	}
+
+	i.registered = true
+	return nil
+}
+
+func (i *issuer) accountKey(ctx context.Context) (crypto.Signer, error) {
+	if i.store == nil {
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	return i.store.accountKey(ctx)
+}
+
+// obtain issues a certificate for hosts, and returns the PEM encoded certificate chain and private key
+func (i *issuer) obtain(ctx context.Context, hosts []string) ([]byte, []byte, error) {
+	if err := i.register(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	order, err := i.client.AuthorizeOrder(ctx, xacme.DomainIDs(hosts...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to create acme order: %s", err)
+	}
+
+	for _, url := range order.AuthzURLs {
+		if err := i.authorize(ctx, url); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	order, err = i.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to wait acme order: %s", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := newCSR(key, hosts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail to finalize acme order: %s", err)
+	}
+
+	var certPEM []byte
+	for _, b := range der {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+	keyPEM, err := encodeECKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return certPEM, keyPEM, nil
+}
+
+// authorize fulfills the HTTP-01 challenge of an authorization
+func (i *issuer) authorize(ctx context.Context, url string) error {
+	authz, err := i.client.GetAuthorization(ctx, url)
+	if err != nil {
+		return fmt.Errorf("fail to get acme authorization: %s", err)
+	}
+	if authz.Status == xacme.StatusValid {
+		return nil
+	}
+
+	var challenge *xacme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == "http-01" {
+			challenge = c
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("no http-01 challenge offered for host[%s]", authz.Identifier.Value)
+	}
+
+	keyAuth, err := i.client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return err
+	}
+	if err := i.solver.put(ctx, challenge.Token, keyAuth); err != nil {
+		return fmt.Errorf("fail to put acme challenge for host[%s]: %s", authz.Identifier.Value, err)
+	}
+	defer func() {
+		// ctx may have expired
+		deleteCtx, cancel := context.WithTimeout(context.Background(), challengeCleanupTimeout)
+		defer cancel()
+		if err := i.solver.delete(deleteCtx, challenge.Token); err != nil {
+			log.Error(err, "fail to delete acme challenge", "host", authz.Identifier.Value)
+		}
+	}()
+
+	// requests of the challenge may reach other replicas, which read it from their caches
+	if i.solver.store != nil {
+		select {
+		case <-time.After(challengePropagation):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if _, err := i.client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("fail to accept acme challenge for host[%s]: %s", authz.Identifier.Value, err)
+	}
+	if _, err := i.client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("fail to authorize host[%s]: %s", authz.Identifier.Value, err)
+	}
+	return nil
+}
+
+func newCSR(key crypto.Signer, hosts []string) ([]byte, error) {
+	req := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: hosts[0]},
+		DNSNames: hosts,
+	}
+	return x509.CreateCertificateRequest(rand.Reader, req, key)
+}
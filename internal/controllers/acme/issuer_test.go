@@ -0,0 +1,367 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+// fakeDirectory is a minimal ACME server, it validates HTTP-01 challenges by requesting the solver directly
+type fakeDirectory struct {
+	*httptest.Server
+
+	lock   sync.Mutex
+	solver http.Handler
+	// keyAuth returns the expected key authorization of a token
+	keyAuth func(token string) (string, error)
+	// reject makes all challenges invalid
+	reject bool
+
+	hosts       []string
+	authzStatus string
+	orderStatus string
+	accounts    int
+
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+	cert   []byte
+}
+
+func newFakeDirectory(t *testing.T) *fakeDirectory {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake acme ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &fakeDirectory{caKey: caKey, caCert: caCert}
+	d.Server = httptest.NewTLSServer(http.HandlerFunc(d.serveHTTP))
+	t.Cleanup(d.Close)
+	return d
+}
+
+func (d *fakeDirectory) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	payload, err := jwsPayload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/dir":
+		writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   d.URL + "/nonce",
+			"newAccount": d.URL + "/account",
+			"newOrder":   d.URL + "/order",
+			"revokeCert": d.URL + "/revoke",
+			"keyChange":  d.URL + "/key-change",
+		})
+
+	case "/account":
+		d.accounts++
+		w.Header().Set("Location", d.URL+"/account/1")
+		writeJSON(w, http.StatusCreated, map[string]string{"status": "valid"})
+
+	case "/order":
+		var req struct {
+			Identifiers []struct{ Value string }
+		}
+		if err := json.Unmarshal(payload, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		d.hosts = nil
+		for _, id := range req.Identifiers {
+			d.hosts = append(d.hosts, id.Value)
+		}
+		d.authzStatus = "pending"
+		d.orderStatus = "pending"
+		d.writeOrder(w, http.StatusCreated)
+
+	case "/order/1":
+		d.writeOrder(w, http.StatusOK)
+
+	case "/authz/1":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"identifier": map[string]string{"type": "dns", "value": d.hosts[0]},
+			"status":     d.authzStatus,
+			"challenges": []map[string]string{
+				{"type": "dns-01", "url": d.URL + "/chall/dns", "token": "dns-token", "status": "pending"},
+				{"type": "http-01", "url": d.URL + "/chall/1", "token": "http-token", "status": "pending"},
+			},
+		})
+
+	case "/chall/1":
+		d.authzStatus, d.orderStatus = "invalid", "invalid"
+		if !d.reject && d.validate("http-token") {
+			d.authzStatus, d.orderStatus = "valid", "ready"
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"type": "http-01", "url": d.URL + "/chall/1", "token": "http-token", "status": "processing"})
+
+	case "/finalize":
+		if err := d.sign(payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		d.orderStatus = "valid"
+		d.writeOrder(w, http.StatusOK)
+
+	case "/cert":
+		w.Write(d.cert)
+		w.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: d.caCert.Raw}))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// validate requests the challenge from the solver like BFE forwards it
+func (d *fakeDirectory) validate(token string) bool {
+	want, err := d.keyAuth(token)
+	if err != nil {
+		return false
+	}
+
+	rec := httptest.NewRecorder()
+	d.solver.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, configs.AcmeChallengePath+token, nil))
+	return rec.Code == http.StatusOK && rec.Body.String() == want
+}
+
+func (d *fakeDirectory) sign(payload []byte) error {
+	var req struct{ CSR string }
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return err
+	}
+	der, err := base64.RawURLEncoding.DecodeString(req.CSR)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err = x509.CreateCertificate(rand.Reader, template, d.caCert, csr.PublicKey, d.caKey)
+	if err != nil {
+		return err
+	}
+	d.cert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return nil
+}
+
+func (d *fakeDirectory) writeOrder(w http.ResponseWriter, code int) {
+	order := map[string]interface{}{
+		"status":         d.orderStatus,
+		"authorizations": []string{d.URL + "/authz/1"},
+		"finalize":       d.URL + "/finalize",
+	}
+	if d.orderStatus == "valid" {
+		order["certificate"] = d.URL + "/cert"
+	}
+	w.Header().Set("Location", d.URL+"/order/1")
+	writeJSON(w, code, order)
+}
+
+func (d *fakeDirectory) writeCAFile(t *testing.T) string {
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: d.Certificate().Raw})
+	if err := ioutil.WriteFile(file, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// jwsPayload returns the decoded payload of the JWS in the request body
+func jwsPayload(r *http.Request) ([]byte, error) {
+	if r.Method != http.MethodPost {
+		return nil, nil
+	}
+
+	var jws struct{ Payload string }
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(jws.Payload)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func setTestOptions(t *testing.T, directoryURL, caFile string) {
+	opts := option.NewOptions()
+	opts.Ingress.AcmeDirectoryURL = directoryURL
+	opts.Ingress.AcmeCAFile = caFile
+	option.Opts = opts
+	t.Cleanup(func() { option.Opts = nil })
+}
+
+func TestIssuerObtain(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []string
+		shared  bool
+		reject  bool
+		wantErr bool
+	}{
+		{name: "single host", hosts: []string{"foo.com"}},
+		{name: "multiple hosts", hosts: []string{"foo.com", "www.foo.com"}},
+		{name: "challenge shared by secret", hosts: []string{"foo.com"}, shared: true},
+		{name: "challenge rejected", hosts: []string{"foo.com"}, reject: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := newFakeDirectory(t)
+			directory.reject = tt.reject
+			setTestOptions(t, directory.URL+"/dir", directory.writeCAFile(t))
+
+			var store *secretStore
+			if tt.shared {
+				c := fake.NewClientBuilder().Build()
+				store = newSecretStore("ingress-bfe", "acme", c, c)
+			}
+			solver := newHTTPSolver(store)
+			i, err := newIssuer(solver, store)
+			if err != nil {
+				t.Fatalf("newIssuer() error = %v", err)
+			}
+			directory.keyAuth = i.client.HTTP01ChallengeResponse
+			directory.solver = solver
+			if tt.shared {
+				// the challenge is served by another replica
+				directory.solver = newHTTPSolver(store)
+			}
+
+			certPEM, keyPEM, err := i.obtain(context.Background(), tt.hosts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("obtain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(solver.responses) != 0 {
+				t.Errorf("challenges are not deleted: %v", solver.responses)
+			}
+			if store != nil {
+				if _, ok, _ := store.getChallenge(context.Background(), "http-token"); ok {
+					t.Errorf("challenge is not deleted from secret")
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+
+			block, _ := pem.Decode(certPEM)
+			if block == nil {
+				t.Fatalf("no certificate in %s", certPEM)
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hosts := append([]string(nil), cert.DNSNames...)
+			sort.Strings(hosts)
+			if !reflect.DeepEqual(hosts, tt.hosts) {
+				t.Errorf("DNSNames = %v, want %v", hosts, tt.hosts)
+			}
+			if !strings.Contains(string(keyPEM), "EC PRIVATE KEY") {
+				t.Errorf("unexpected private key: %s", keyPEM)
+			}
+		})
+	}
+}
+
+func TestIssuerAccountKey(t *testing.T) {
+	directory := newFakeDirectory(t)
+	setTestOptions(t, directory.URL+"/dir", directory.writeCAFile(t))
+
+	c := fake.NewClientBuilder().Build()
+	store := newSecretStore("ingress-bfe", "acme", c, c)
+
+	// issuers created after restarts share the account key
+	var keys []interface{}
+	for n := 0; n < 2; n++ {
+		i, err := newIssuer(newHTTPSolver(store), store)
+		if err != nil {
+			t.Fatalf("newIssuer() error = %v", err)
+		}
+		if err := i.register(context.Background()); err != nil {
+			t.Fatalf("register() error = %v", err)
+		}
+		keys = append(keys, i.client.Key.Public())
+	}
+
+	if !keys[0].(*ecdsa.PublicKey).Equal(keys[1]) {
+		t.Errorf("account key changed after restart")
+	}
+	if directory.accounts != 2 {
+		t.Errorf("accounts = %d, want 2", directory.accounts)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+)
+
+type httpSolver struct {
+	lock sync.RWMutex
+
+	// token -> key authorization of challenges put by this replica
+	responses map[string]string
+
+	// store shares challenges with other replicas, challenges are only served by this replica if it's nil
+	store *secretStore
+}
+
+func newHTTPSolver(store *secretStore) *httpSolver {
+	return &httpSolver{
+		responses: make(map[string]string),
+		store:     store,
+	}
+}
+
+func (s *httpSolver) put(ctx context.Context, token, keyAuth string) error {
+	s.lock.Lock()
+	s.responses[token] = keyAuth
+	s.lock.Unlock()
+
+	if s.store == nil {
+		return nil
+	}
+	return s.store.putChallenge(ctx, token, keyAuth)
+}
+
+func (s *httpSolver) delete(ctx context.Context, token string) error {
+	s.lock.Lock()
+	delete(s.responses, token)
+	s.lock.Unlock()
+
+	if s.store == nil {
+		return nil
+	}
+	return s.store.deleteChallenge(ctx, token)
+}
+
+func (s *httpSolver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, configs.AcmeChallengePath)
+	if len(token) == 0 || len(token) == len(r.URL.Path) {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.lock.RLock()
+	keyAuth, ok := s.responses[token]
+	s.lock.RUnlock()
+
+	// the challenge may be put by another replica
+	if !ok && s.store != nil {
+		var err error
+		if keyAuth, ok, err = s.store.getChallenge(r.Context(), token); err != nil {
+			log.Error(err, "fail to get acme challenge", "token", token)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth))
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/configs"
+)
+
+func TestHTTPSolver(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	store := newSecretStore("ingress-bfe", "acme", c, c)
+
+	local := newHTTPSolver(nil)
+	leader := newHTTPSolver(store)
+	follower := newHTTPSolver(store)
+	ctx := context.Background()
+	if err := local.put(ctx, "local-token", "local-token.key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.put(ctx, "shared-token", "shared-token.key"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		solver   *httpSolver
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"local challenge", local, configs.AcmeChallengePath + "local-token", http.StatusOK, "local-token.key"},
+		{"unknown token", local, configs.AcmeChallengePath + "unknown", http.StatusNotFound, ""},
+		{"empty token", local, configs.AcmeChallengePath, http.StatusNotFound, ""},
+		{"other path", local, "/local-token", http.StatusNotFound, ""},
+		{"challenge put by this replica", leader, configs.AcmeChallengePath + "shared-token", http.StatusOK, "shared-token.key"},
+		{"challenge put by other replica", follower, configs.AcmeChallengePath + "shared-token", http.StatusOK, "shared-token.key"},
+		{"challenge not shared", follower, configs.AcmeChallengePath + "local-token", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.solver.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	// deleted challenges are not served by any replica
+	if err := leader.delete(ctx, "shared-token"); err != nil {
+		t.Fatal(err)
+	}
+	for _, solver := range []*httpSolver{leader, follower} {
+		rec := httptest.NewRecorder()
+		solver.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, configs.AcmeChallengePath+"shared-token", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("deleted challenge: code = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// challengeKeyPrefix is the prefix of keys of pending HTTP-01 challenges in the Secret, followed by the token
+	challengeKeyPrefix = "challenge."
+	// accountKeyKey is the key of the PEM encoded private key of the ACME account in the Secret
+	accountKeyKey = "account.key"
+)
+
+// secretStore keeps the state shared by replicas in a Secret.
+// Challenges are put by the replica issuing certificates, but requests of them may reach any replica.
+type secretStore struct {
+	key client.ObjectKey
+
+	// client reads the Secret from the cache of the manager
+	client client.Client
+	// reader reads the Secret from the API server directly, so that updates are based on the latest version
+	reader client.Reader
+}
+
+func newSecretStore(namespace, name string, client client.Client, reader client.Reader) *secretStore {
+	return &secretStore{
+		key:    types.NamespacedName{Namespace: namespace, Name: name},
+		client: client,
+		reader: reader,
+	}
+}
+
+// getChallenge returns the key authorization of the challenge token
+func (s *secretStore) getChallenge(ctx context.Context, token string) (string, bool, error) {
+	secret := &corev1.Secret{}
+	if err := s.client.Get(ctx, s.key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	keyAuth, ok := secret.Data[challengeKeyPrefix+token]
+	return string(keyAuth), ok, nil
+}
+
+func (s *secretStore) putChallenge(ctx context.Context, token, keyAuth string) error {
+	return s.update(ctx, func(data map[string][]byte) bool {
+		data[challengeKeyPrefix+token] = []byte(keyAuth)
+		return true
+	})
+}
+
+func (s *secretStore) deleteChallenge(ctx context.Context, token string) error {
+	return s.update(ctx, func(data map[string][]byte) bool {
+		if _, ok := data[challengeKeyPrefix+token]; !ok {
+			return false
+		}
+		delete(data, challengeKeyPrefix+token)
+		return true
+	})
+}
+
+// accountKey returns the private key of the ACME account, the key is generated and stored if it doesn't exist.
+// So the same account is used after restarts and by all replicas.
+func (s *secretStore) accountKey(ctx context.Context) (crypto.Signer, error) {
+	var keyPEM []byte
+	var genErr error
+	err := s.update(ctx, func(data map[string][]byte) bool {
+		if keyPEM = data[accountKeyKey]; len(keyPEM) > 0 {
+			return false
+		}
+
+		var key *ecdsa.PrivateKey
+		if key, genErr = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); genErr != nil {
+			return false
+		}
+		if keyPEM, genErr = encodeECKey(key); genErr != nil {
+			return false
+		}
+		data[accountKeyKey] = keyPEM
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if genErr != nil {
+		return nil, genErr
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no acme account key found in secret[%s]", s.key)
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+func encodeECKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// update modifies data of the Secret by fn, the Secret is created if it doesn't exist.
+// fn returns false if data is not modified.
+func (s *secretStore) update(ctx context.Context, fn func(data map[string][]byte) bool) error {
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		secret := &corev1.Secret{}
+		err := s.reader.Get(ctx, s.key, secret)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		exist := err == nil
+		if !exist {
+			secret = &corev1.Secret{}
+			secret.Namespace = s.key.Namespace
+			secret.Name = s.key.Name
+			secret.Type = corev1.SecretTypeOpaque
+		}
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		if !fn(secret.Data) {
+			return nil
+		}
+
+		if exist {
+			return s.client.Update(ctx, secret)
+		}
+		return s.client.Create(ctx, secret)
+	})
+}
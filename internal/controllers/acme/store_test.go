@@ -0,0 +1,102 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSecretStore(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	store := newSecretStore("ingress-bfe", "acme", c, c)
+	ctx := context.Background()
+
+	if _, ok, err := store.getChallenge(ctx, "foo"); err != nil || ok {
+		t.Errorf("getChallenge() of missing secret = %v, %v", ok, err)
+	}
+
+	steps := []struct {
+		name     string
+		action   func() error
+		wantKeys []string
+	}{
+		{"put challenge creates secret", func() error {
+			return store.putChallenge(ctx, "foo", "foo.key")
+		}, []string{"challenge.foo"}},
+		{"put another challenge", func() error {
+			return store.putChallenge(ctx, "bar", "bar.key")
+		}, []string{"challenge.bar", "challenge.foo"}},
+		{"delete challenge", func() error {
+			return store.deleteChallenge(ctx, "foo")
+		}, []string{"challenge.bar"}},
+		{"delete missing challenge", func() error {
+			return store.deleteChallenge(ctx, "foo")
+		}, []string{"challenge.bar"}},
+		{"generate account key", func() error {
+			_, err := store.accountKey(ctx)
+			return err
+		}, []string{"account.key", "challenge.bar"}},
+	}
+	for _, step := range steps {
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, store.key, secret); err != nil {
+			t.Fatalf("%s: get secret error = %v", step.name, err)
+		}
+		var keys []string
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, step.wantKeys) {
+			t.Errorf("%s: keys = %v, want %v", step.name, keys, step.wantKeys)
+		}
+	}
+
+	keyAuth, ok, err := store.getChallenge(ctx, "bar")
+	if err != nil || !ok || keyAuth != "bar.key" {
+		t.Errorf("getChallenge() = %s, %v, %v, want bar.key", keyAuth, ok, err)
+	}
+	if _, ok, err := store.getChallenge(ctx, "foo"); err != nil || ok {
+		t.Errorf("getChallenge() of deleted challenge = %v, %v", ok, err)
+	}
+}
+
+func TestSecretStoreAccountKey(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	ctx := context.Background()
+
+	// stores of different replicas share the account key
+	key1, err := newSecretStore("ingress-bfe", "acme", c, c).accountKey(ctx)
+	if err != nil {
+		t.Fatalf("accountKey() error = %v", err)
+	}
+	key2, err := newSecretStore("ingress-bfe", "acme", c, c).accountKey(ctx)
+	if err != nil {
+		t.Fatalf("accountKey() error = %v", err)
+	}
+	if !reflect.DeepEqual(key1, key2) {
+		t.Errorf("account key is regenerated")
+	}
+}
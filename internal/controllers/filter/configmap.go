@@ -0,0 +1,31 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+// ConfigMapFilter filters the global ConfigMap
+func ConfigMapFilter() predicate.Funcs {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		name := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		return name.String() == option.Opts.Ingress.ConfigMap
+	})
+}
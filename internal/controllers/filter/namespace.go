@@ -24,16 +24,21 @@ import (
 
 func NamespaceFilter() predicate.Funcs {
 	funcs := predicate.NewPredicateFuncs(func(obj client.Object) bool {
-		if len(option.Opts.NamespaceList) == 1 && option.Opts.NamespaceList[0] == corev1.NamespaceAll {
-			return true
-		}
-		for _, ns := range option.Opts.NamespaceList {
-			if ns == obj.GetNamespace() {
-				return true
-			}
-		}
-		return false
+		return IsWatchedNamespace(obj.GetNamespace())
 	})
 
 	return funcs
 }
+
+// IsWatchedNamespace checks whether resources in the namespace are watched by the controller
+func IsWatchedNamespace(namespace string) bool {
+	if len(option.Opts.NamespaceList) == 1 && option.Opts.NamespaceList[0] == corev1.NamespaceAll {
+		return true
+	}
+	for _, ns := range option.Opts.NamespaceList {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
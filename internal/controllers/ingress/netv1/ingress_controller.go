@@ -19,15 +19,21 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlevent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
@@ -53,20 +59,24 @@ type IngressReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	recorder record.EventRecorder
+	// routeStatusEvents requeues ingresses whose order of route rules is changed by other ingresses
+	routeStatusEvents chan ctrlevent.GenericEvent
 }
 
 func newIngressReconciler(mgr manager.Manager, cb *bfeConfig.ConfigBuilder) *IngressReconciler {
 	return &IngressReconciler{
-		BfeConfigBuilder: cb,
-		Client:           mgr.GetClient(),
-		Scheme:           mgr.GetScheme(),
-		recorder:         mgr.GetEventRecorderFor("bfe-ingress-controller"),
+		BfeConfigBuilder:  cb,
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		recorder:          mgr.GetEventRecorderFor("bfe-ingress-controller"),
+		routeStatusEvents: make(chan ctrlevent.GenericEvent, RouteStatusEventsSize),
 	}
 }
 
 func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.V(1).Info("reconciling ingress", "api version", "netv1")
+	defer RequeueRouteStatusChanged(r.BfeConfigBuilder, r.routeStatusEvents)
 
 	// read ingress
 	ingress := &netv1.Ingress{}
@@ -84,7 +94,7 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	log.V(1).Info("reconcile: ingress object", "ingress", ingress)
 
 	err = ReconcileV1Ingress(ctx, r.Client, r.BfeConfigBuilder, ingress)
-	setStatus(ctx, r.Client, err, ingress)
+	setStatus(ctx, r.Client, err, ingress, r.BfeConfigBuilder.RouteStatus(req.Namespace, req.Name))
 
 	if err != nil {
 		r.recorder.Event(ingress, corev1.EventTypeWarning, event.SyncFailed, err.Error())
@@ -99,14 +109,35 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 func (r *IngressReconciler) setupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&netv1.Ingress{}, builder.WithPredicates(filter.NamespaceFilter())).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			EnqueueIngressesForConfigMap(r, &netv1.IngressList{}),
+			builder.WithPredicates(filter.NamespaceFilter())).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			EnqueueIngressesForSecret(r, &netv1.IngressList{}),
+			builder.WithPredicates(filter.NamespaceFilter())).
+		Watches(&source.Channel{Source: r.routeStatusEvents}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
 
-func setStatus(ctx context.Context, r client.Client, err error, ingress *netv1.Ingress) {
+// RouteStatusEventsSize is the buffer size of the channel which requeues ingresses whose order of route rules is changed
+const RouteStatusEventsSize = 1024
+
+// RequeueRouteStatusChanged requeues ingresses whose order of route rules is changed by other ingresses,
+// e.g. ingresses of the same host, so that the order in their status is updated
+func RequeueRouteStatusChanged(cb *bfeConfig.ConfigBuilder, events chan<- ctrlevent.GenericEvent) {
+	for _, ingress := range cb.RouteStatusChanged() {
+		namespace, name := util.SplitNamespacedName(ingress)
+		events <- ctrlevent.GenericEvent{Object: &metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		}}
+	}
+}
+
+func setStatus(ctx context.Context, r client.Client, err error, ingress *netv1.Ingress, routes []annotations.RouteStatus) {
 	log := log.FromContext(ctx)
 
-	if annotations.CompareStatus(err, ingress.Annotations[annotations.StatusAnnotationKey]) == 0 {
-		// no need to update status if error is not changed
+	if annotations.CompareStatusRoutes(err, routes, ingress.Annotations[annotations.StatusAnnotationKey]) == 0 {
+		// no need to update status if error and order of routes are not changed
 		return
 	}
 
@@ -114,7 +145,7 @@ func setStatus(ctx context.Context, r client.Client, err error, ingress *netv1.I
 	if ingress.Annotations == nil {
 		ingress.Annotations = make(map[string]string)
 	}
-	ingress.Annotations[annotations.StatusAnnotationKey] = annotations.GenErrorMsg(err)
+	ingress.Annotations[annotations.StatusAnnotationKey] = annotations.GenStatusMsg(err, routes)
 	if err := r.Patch(ctx, ingress, patch); err != nil {
 		log.Error(err, "fail to update annotation")
 	}
@@ -138,7 +169,19 @@ func ReconcileV1Ingress(ctx context.Context, r client.Client, configBuilder *bfe
 		return err
 	}
 
-	if err = configBuilder.UpdateIngress(ingress, service, endpoints, secrets); err != nil {
+	configMaps, err := getIngressConfigMaps(ctx, r, ingress)
+	if err != nil {
+		configBuilder.DeleteIngress(ingress.Namespace, ingress.Name)
+		return err
+	}
+
+	refSecrets, err := getIngressReferencedSecrets(ctx, r, ingress)
+	if err != nil {
+		configBuilder.DeleteIngress(ingress.Namespace, ingress.Name)
+		return err
+	}
+
+	if err = configBuilder.UpdateIngress(ingress, service, endpoints, secrets, configMaps, refSecrets); err != nil {
 		configBuilder.DeleteIngress(ingress.Namespace, ingress.Name)
 		return err
 	}
@@ -163,6 +206,12 @@ func getIngressBackends(ctx context.Context, r client.Reader, ingress *netv1.Ing
 		return nil, nil, err
 	}
 
+	// services may be in other namespaces which grant the namespace of the ingress
+	namespaces, err := annotations.GetBackendNamespaces(ingress.Annotations)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	for _, rule := range ingress.Spec.Rules {
 		for _, p := range rule.IngressRuleValue.HTTP.Paths {
 			// service name exist in annotation
@@ -176,16 +225,23 @@ func getIngressBackends(ctx context.Context, r client.Reader, ingress *netv1.Ing
 			}
 
 			for _, name := range names {
-				if svc, err := getService(ctx, r, ingress.Namespace, name, p.Backend.Service.Port); err != nil {
+				namespace := namespaces.Namespace(ingress.Namespace, name)
+				if namespace != ingress.Namespace {
+					if err := checkBackendGrant(ctx, r, ingress.Namespace, namespace, name); err != nil {
+						return nil, nil, err
+					}
+				}
+
+				if svc, err := getService(ctx, r, namespace, name, p.Backend.Service.Port); err != nil {
 					return nil, nil, err
 				} else {
-					services[util.NamespacedName(ingress.Namespace, name)] = svc
+					services[util.NamespacedName(namespace, name)] = svc
 				}
 
-				if ep, err := getEndpoint(ctx, r, ingress.Namespace, name); err != nil {
+				if ep, err := getEndpoint(ctx, r, namespace, name); err != nil {
 					return nil, nil, err
 				} else {
-					endpoints[util.NamespacedName(ingress.Namespace, name)] = ep
+					endpoints[util.NamespacedName(namespace, name)] = ep
 				}
 			}
 
@@ -195,6 +251,33 @@ func getIngressBackends(ctx context.Context, r client.Reader, ingress *netv1.Ing
 	return services, endpoints, nil
 }
 
+// checkBackendGrant checks whether the service in namespace is granted to ingresses in namespace from
+func checkBackendGrant(ctx context.Context, r client.Reader, from, namespace, name string) error {
+	// changes of services in namespaces not watched can't be synced to BFE
+	if !filter.IsWatchedNamespace(namespace) {
+		return fmt.Errorf("service %s is not granted to namespace %s, namespace %s is not watched by the controller",
+			util.NamespacedName(namespace, name), from, namespace)
+	}
+
+	grant := &corev1.ConfigMap{}
+	err := r.Get(ctx, client.ObjectKey{
+		Namespace: namespace,
+		Name:      annotations.BackendGrantConfigMap,
+	}, grant)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("service %s is not granted to namespace %s, ConfigMap %s not found",
+			util.NamespacedName(namespace, name), from, util.NamespacedName(namespace, annotations.BackendGrantConfigMap))
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := annotations.CheckBackendGrant(grant.Data, from, name); err != nil {
+		return fmt.Errorf("service %s is not granted to namespace %s, %s", util.NamespacedName(namespace, name), from, err)
+	}
+	return nil
+}
+
 func getDefaultBackends(ctx context.Context, r client.Reader, name string) (*corev1.Service, *corev1.Endpoints, error) {
 	// name is in format of "namespace/name"
 	names := strings.Split(name, string(types.Separator))
@@ -255,8 +338,31 @@ func getService(ctx context.Context, r client.Reader, namespace, name string, po
 
 func getIngressSecret(ctx context.Context, r client.Reader, ingress *netv1.Ingress) ([]*corev1.Secret, error) {
 	secrets := make([]*corev1.Secret, 0)
+	acme, err := annotations.GetAcme(ingress.Annotations)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, tls := range ingress.Spec.TLS {
 		secret, err := getSecret(ctx, r, ingress.Namespace, tls.SecretName)
+		if acme && apierrors.IsNotFound(err) {
+			// secret will be created after the certificate is issued through acme
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
+
+// getIngressReferencedSecrets returns Secrets referenced by annotations of the ingress
+func getIngressReferencedSecrets(ctx context.Context, r client.Reader, ingress *netv1.Ingress) ([]*corev1.Secret, error) {
+	secrets := make([]*corev1.Secret, 0)
+	for _, name := range annotations.GetSecretReferences(ingress.Annotations) {
+		secret, err := getSecret(ctx, r, ingress.Namespace, name)
 		if err != nil {
 			return nil, err
 		}
@@ -279,6 +385,101 @@ func getSecret(ctx context.Context, r client.Reader, namespace, name string) (*c
 	}
 }
 
+// getIngressConfigMaps returns ConfigMaps referenced by annotations of the ingress
+func getIngressConfigMaps(ctx context.Context, r client.Reader, ingress *netv1.Ingress) ([]*corev1.ConfigMap, error) {
+	configMaps := make([]*corev1.ConfigMap, 0)
+	for _, name := range annotations.GetConfigMapReferences(ingress.Annotations) {
+		configMap := &corev1.ConfigMap{}
+		err := r.Get(ctx, client.ObjectKey{
+			Namespace: ingress.Namespace,
+			Name:      name,
+		}, configMap)
+		if err != nil {
+			return nil, err
+		}
+		configMaps = append(configMaps, configMap)
+	}
+
+	return configMaps, nil
+}
+
+// EnqueueIngressesForConfigMap returns an event handler which enqueues ingresses referencing the ConfigMap by annotations,
+// or ingresses referencing services granted by the ConfigMap in other namespaces,
+// list is used to list ingresses of the api version watched by the controller
+func EnqueueIngressesForConfigMap(r client.Reader, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		if obj.GetName() == annotations.BackendGrantConfigMap {
+			return enqueueIngressesForBackendGrant(r, list, obj)
+		}
+
+		return enqueueIngressesForReference(r, list, obj, annotations.GetConfigMapReferences)
+	})
+}
+
+// EnqueueIngressesForSecret returns an event handler which enqueues ingresses referencing the Secret by annotations,
+// so that ingresses are synced again when the Secret is created or deleted,
+// list is used to list ingresses of the api version watched by the controller
+func EnqueueIngressesForSecret(r client.Reader, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		return enqueueIngressesForReference(r, list, obj, annotations.GetSecretReferences)
+	})
+}
+
+// enqueueIngressesForReference returns ingresses in the namespace of obj, of which references returns the name of obj
+func enqueueIngressesForReference(r client.Reader, list client.ObjectList, obj client.Object, references func(map[string]string) []string) []reconcile.Request {
+	ingresses := list.DeepCopyObject().(client.ObjectList)
+	if err := r.List(context.Background(), ingresses, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	_ = meta.EachListItem(ingresses, func(item runtime.Object) error {
+		ingress, ok := item.(client.Object)
+		if !ok {
+			return nil
+		}
+		for _, name := range references(ingress.GetAnnotations()) {
+			if name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: ingress.GetNamespace(),
+					Name:      ingress.GetName(),
+				}})
+				break
+			}
+		}
+		return nil
+	})
+	return requests
+}
+
+// enqueueIngressesForBackendGrant returns ingresses in other namespaces referencing services in the namespace of the grant ConfigMap
+func enqueueIngressesForBackendGrant(r client.Reader, list client.ObjectList, grant client.Object) []reconcile.Request {
+	ingresses := list.DeepCopyObject().(client.ObjectList)
+	if err := r.List(context.Background(), ingresses); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	_ = meta.EachListItem(ingresses, func(item runtime.Object) error {
+		ingress, ok := item.(client.Object)
+		if !ok || ingress.GetNamespace() == grant.GetNamespace() || !filter.IsWatchedNamespace(ingress.GetNamespace()) {
+			return nil
+		}
+		namespaces, _ := annotations.GetBackendNamespaces(ingress.GetAnnotations())
+		for _, namespace := range namespaces {
+			if namespace == grant.GetNamespace() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: ingress.GetNamespace(),
+					Name:      ingress.GetName(),
+				}})
+				break
+			}
+		}
+		return nil
+	})
+	return requests
+}
+
 // set defaultBackend in ingress
 func setDefautBackend(ingress *netv1.Ingress, service *corev1.Service) {
 	if len(option.Opts.Ingress.DefaultBackend) == 0 || service == nil || len(service.Spec.Ports) == 0 {
@@ -0,0 +1,153 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netv1
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlevent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+func TestEnqueueIngressesForSecret(t *testing.T) {
+	newIngress := func(namespace, name string, annots map[string]string) client.Object {
+		return &netv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annots}}
+	}
+	c := fake.NewClientBuilder().WithObjects(
+		newIngress("default", "basic", map[string]string{annotations.AuthBasicSecretAnnotation: "users"}),
+		newIngress("default", "jwt", map[string]string{annotations.AuthJWTSecretAnnotation: "users"}),
+		newIngress("default", "other", map[string]string{annotations.AuthBasicSecretAnnotation: "admins"}),
+		newIngress("default", "none", nil),
+		newIngress("test", "basic", map[string]string{annotations.AuthBasicSecretAnnotation: "users"}),
+	).Build()
+	h := EnqueueIngressesForSecret(c, &netv1.IngressList{})
+
+	tests := []struct {
+		name   string
+		secret string
+		want   []string
+	}{
+		{
+			name:   "secret referenced by ingresses",
+			secret: "users",
+			want:   []string{"default/basic", "default/jwt"},
+		},
+		{
+			name:   "secret not referenced",
+			secret: "tls",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer queue.ShutDown()
+
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: tt.secret}}
+			h.Delete(ctrlevent.DeleteEvent{Object: secret}, queue)
+
+			var got []string
+			for queue.Len() > 0 {
+				item, _ := queue.Get()
+				got = append(got, item.(reconcile.Request).String())
+				queue.Done(item)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("enqueued ingresses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequeueRouteStatusChanged(t *testing.T) {
+	option.Opts = option.NewOptions()
+	defer func() { option.Opts = nil }()
+
+	pathType := netv1.PathTypePrefix
+	newIngress := func(name, path string) *netv1.Ingress {
+		return &netv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: netv1.IngressSpec{Rules: []netv1.IngressRule{{
+				Host: "foo.com",
+				IngressRuleValue: netv1.IngressRuleValue{HTTP: &netv1.HTTPIngressRuleValue{
+					Paths: []netv1.HTTPIngressPath{{
+						Path:     path,
+						PathType: &pathType,
+						Backend: netv1.IngressBackend{Service: &netv1.IngressServiceBackend{
+							Name: "svc",
+							Port: netv1.ServiceBackendPort{Number: 80},
+						}},
+					}},
+				}},
+			}}},
+		}
+	}
+	endpoints := map[string]*corev1.Endpoints{
+		"default/svc": {Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+			Ports:     []corev1.EndpointPort{{Port: 8080}},
+		}}},
+	}
+	cb := bfeConfig.NewConfigBuilder()
+	events := make(chan ctrlevent.GenericEvent, RouteStatusEventsSize)
+
+	steps := []struct {
+		name   string
+		action func() error
+		want   []string
+	}{
+		{"add ingress a", func() error {
+			return cb.UpdateIngress(newIngress("a", "/"), nil, endpoints, nil, nil, nil)
+		}, nil},
+		{"add ingress b of the same host, which is matched before ingress a", func() error {
+			return cb.UpdateIngress(newIngress("b", "/foo"), nil, endpoints, nil, nil, nil)
+		}, []string{"default/a"}},
+		{"update ingress a", func() error {
+			return cb.UpdateIngress(newIngress("a", "/"), nil, endpoints, nil, nil, nil)
+		}, nil},
+		{"delete ingress b", func() error {
+			cb.DeleteIngress("default", "b")
+			return nil
+		}, []string{"default/a"}},
+	}
+	for _, step := range steps {
+		if err := step.action(); err != nil {
+			t.Fatalf("%s: error = %v", step.name, err)
+		}
+		RequeueRouteStatusChanged(cb, events)
+
+		var got []string
+		for len(events) > 0 {
+			e := <-events
+			got = append(got, e.Object.GetNamespace()+"/"+e.Object.GetName())
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: requeued ingresses = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
@@ -24,9 +24,12 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlevent "sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig"
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/annotations"
@@ -51,20 +54,24 @@ type IngressReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	recorder record.EventRecorder
+	// routeStatusEvents requeues ingresses whose order of route rules is changed by other ingresses
+	routeStatusEvents chan ctrlevent.GenericEvent
 }
 
 func newIngressReconciler(mgr manager.Manager, cb *bfeConfig.ConfigBuilder) *IngressReconciler {
 	return &IngressReconciler{
-		BfeConfigBuilder: cb,
-		Client:           mgr.GetClient(),
-		Scheme:           mgr.GetScheme(),
-		recorder:         mgr.GetEventRecorderFor("bfe-ingress-controller"),
+		BfeConfigBuilder:  cb,
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		recorder:          mgr.GetEventRecorderFor("bfe-ingress-controller"),
+		routeStatusEvents: make(chan ctrlevent.GenericEvent, controllerV1.RouteStatusEventsSize),
 	}
 }
 
 func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.V(1).Info("reconciling ingress", "api version", "netv1beta1")
+	defer controllerV1.RequeueRouteStatusChanged(r.BfeConfigBuilder, r.routeStatusEvents)
 
 	// read ingress
 	ingressV1beta1 := &netv1beta1.Ingress{}
@@ -84,7 +91,7 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	convert(ingressV1beta1, ingressV1)
 
 	err = controllerV1.ReconcileV1Ingress(ctx, r.Client, r.BfeConfigBuilder, ingressV1)
-	setStatus(ctx, r.Client, err, ingressV1beta1)
+	setStatus(ctx, r.Client, err, ingressV1beta1, r.BfeConfigBuilder.RouteStatus(req.Namespace, req.Name))
 
 	if err != nil {
 		r.recorder.Event(ingressV1beta1, corev1.EventTypeWarning, event.SyncFailed, err.Error())
@@ -99,19 +106,26 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 func (r *IngressReconciler) setupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&netv1beta1.Ingress{}, builder.WithPredicates(filter.NamespaceFilter())).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			controllerV1.EnqueueIngressesForConfigMap(r, &netv1beta1.IngressList{}),
+			builder.WithPredicates(filter.NamespaceFilter())).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			controllerV1.EnqueueIngressesForSecret(r, &netv1beta1.IngressList{}),
+			builder.WithPredicates(filter.NamespaceFilter())).
+		Watches(&source.Channel{Source: r.routeStatusEvents}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
 
-func setStatus(ctx context.Context, r client.Client, err error, ingress *netv1beta1.Ingress) {
+func setStatus(ctx context.Context, r client.Client, err error, ingress *netv1beta1.Ingress, routes []annotations.RouteStatus) {
 	log := log.FromContext(ctx)
 
-	if annotations.CompareStatus(err, ingress.Annotations[annotations.StatusAnnotationKey]) == 0 {
-		// no need to update status if error is not changed
+	if annotations.CompareStatusRoutes(err, routes, ingress.Annotations[annotations.StatusAnnotationKey]) == 0 {
+		// no need to update status if error and order of routes are not changed
 		return
 	}
 
 	patch := client.MergeFrom(ingress.DeepCopy())
-	ingress.Annotations[annotations.StatusAnnotationKey] = annotations.GenErrorMsg(err)
+	ingress.Annotations[annotations.StatusAnnotationKey] = annotations.GenStatusMsg(err, routes)
 	if err := r.Patch(ctx, ingress, patch); err != nil {
 		log.Error(err, "fail to update annotation")
 	}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -64,11 +65,17 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		Namespace: req.Namespace,
 		Name:      req.Name,
 	}, secret)
-	if err != nil {
+	if apierrors.IsNotFound(err) {
+		r.BfeConfigBuilder.DeleteSecret(req.Namespace, req.Name)
 		return ctrl.Result{}, nil
 	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-	r.BfeConfigBuilder.UpdateSecret(secret)
+	if err := r.BfeConfigBuilder.UpdateSecret(secret); err != nil {
+		log.Error(err, "fail to update secret", "secret", req.NamespacedName)
+	}
 
 	return ctrl.Result{}, nil
 }
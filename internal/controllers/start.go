@@ -17,10 +17,15 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/discovery"
@@ -29,6 +34,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig"
+	"github.com/bfenetworks/ingress-bfe/internal/bfeConfig/util"
+	"github.com/bfenetworks/ingress-bfe/internal/controllers/acme"
 	"github.com/bfenetworks/ingress-bfe/internal/controllers/ingress"
 	"github.com/bfenetworks/ingress-bfe/internal/controllers/ingress/extv1beta1"
 	"github.com/bfenetworks/ingress-bfe/internal/controllers/ingress/netv1"
@@ -73,6 +80,19 @@ func Start(scheme *runtime.Scheme) error {
 		return err
 	}
 
+	// set bfe.conf which can't be reloaded
+	if option.Opts.Ingress.ProxyProtocol {
+		if err := enableProxyProtocol(); err != nil {
+			return err
+		}
+	}
+
+	if len(option.Opts.Ingress.AuthURL) > 0 {
+		if err := enableAuthRequest(); err != nil {
+			return err
+		}
+	}
+
 	// start bfe process
 	if err := startBFE(ctx); err != nil {
 		return err
@@ -119,9 +139,126 @@ func addController(cb *bfeConfig.ConfigBuilder, mgr manager.Manager) error {
 		return fmt.Errorf("unable to create controller secret: %s", err)
 	}
 
+	if len(option.Opts.Ingress.ConfigMap) > 0 {
+		if err := ingress.AddConfigMapController(mgr, cb); err != nil {
+			return fmt.Errorf("unable to create controller configmap: %s", err)
+		}
+	}
+
+	if option.Opts.Ingress.AcmeEnabled() {
+		if err := acme.AddAcmeController(mgr, cb); err != nil {
+			return fmt.Errorf("unable to create acme controller: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// enableProxyProtocol sets Layer4LoadBalancer of bfe.conf to PROXY.
+// Connections without PROXY protocol header are still accepted by BFE.
+func enableProxyProtocol() error {
+	const bfeConf = "bfe.conf"
+	data, err := ioutil.ReadFile(option.Opts.Ingress.ConfigPath + bfeConf)
+	if err != nil {
+		return fmt.Errorf("fail to read %s: %s", bfeConf, err)
+	}
+
+	conf := string(data)
+	line := "Layer4LoadBalancer = PROXY"
+	re := regexp.MustCompile(`(?m)^[ \t]*Layer4LoadBalancer[ \t]*=.*$`)
+	if re.MatchString(conf) {
+		conf = re.ReplaceAllLiteralString(conf, line)
+	} else if strings.Contains(conf, "[Server]\n") {
+		conf = strings.Replace(conf, "[Server]\n", "[Server]\n"+line+"\n", 1)
+	} else {
+		return fmt.Errorf("fail to enable proxy protocol: section [Server] not found in %s", bfeConf)
+	}
+
+	if err := util.DumpFile(bfeConf, []byte(conf)); err != nil {
+		return fmt.Errorf("fail to write %s: %s", bfeConf, err)
+	}
+	log.Info("proxy protocol is enabled")
+	return nil
+}
+
+// enableAuthRequest enables mod_auth_request in bfe.conf, and sets the external authorization service of it.
+// The service is loaded when bfe starts, so only one service is supported.
+func enableAuthRequest() error {
+	const bfeConf = "bfe.conf"
+	const modConf = "mod_auth_request/mod_auth_request.conf"
+
+	data, err := ioutil.ReadFile(option.Opts.Ingress.ConfigPath + bfeConf)
+	if err != nil {
+		return fmt.Errorf("fail to read %s: %s", bfeConf, err)
+	}
+	conf, err := enableModule(string(data), "mod_auth_request")
+	if err != nil {
+		return fmt.Errorf("fail to enable mod_auth_request in %s: %s", bfeConf, err)
+	}
+	if err := util.DumpFile(bfeConf, []byte(conf)); err != nil {
+		return fmt.Errorf("fail to write %s: %s", bfeConf, err)
+	}
+
+	data, err = ioutil.ReadFile(option.Opts.Ingress.ConfigPath + modConf)
+	if err != nil {
+		return fmt.Errorf("fail to read %s: %s", modConf, err)
+	}
+	conf, err = setAuthService(string(data), option.Opts.Ingress.AuthURL, option.Opts.Ingress.AuthTimeout)
+	if err != nil {
+		return fmt.Errorf("fail to set auth service in %s: %s", modConf, err)
+	}
+	if err := util.DumpFile(modConf, []byte(conf)); err != nil {
+		return fmt.Errorf("fail to write %s: %s", modConf, err)
+	}
+
+	log.Info("external authorization is enabled", "auth-url", option.Opts.Ingress.AuthURL)
 	return nil
 }
 
+// enableModule adds the module to Modules of bfe.conf, a commented line of the module is uncommented if exists.
+func enableModule(conf, module string) (string, error) {
+	line := "Modules = " + module
+	enabled := regexp.MustCompile(`(?m)^[ \t]*Modules[ \t]*=[ \t]*` + module + `[ \t]*$`)
+	commented := regexp.MustCompile(`(?m)^[ \t]*#[ \t]*Modules[ \t]*=[ \t]*` + module + `[ \t]*$`)
+	modules := regexp.MustCompile(`(?m)^[ \t]*Modules[ \t]*=.*$`)
+
+	if enabled.MatchString(conf) {
+		return conf, nil
+	}
+	if loc := commented.FindStringIndex(conf); loc != nil {
+		return conf[:loc[0]] + line + conf[loc[1]:], nil
+	}
+	locs := modules.FindAllStringIndex(conf, -1)
+	if len(locs) == 0 {
+		return "", fmt.Errorf("Modules not found")
+	}
+	last := locs[len(locs)-1]
+	return conf[:last[1]] + "\n" + line + conf[last[1]:], nil
+}
+
+// setAuthService sets AuthAddress and AuthTimeout of mod_auth_request.conf.
+func setAuthService(conf, authURL string, timeout time.Duration) (string, error) {
+	values := []struct {
+		key   string
+		value string
+	}{
+		{"AuthAddress", authURL},
+		{"AuthTimeout", strconv.FormatInt(timeout.Milliseconds(), 10)},
+	}
+	for _, v := range values {
+		re := regexp.MustCompile(`(?m)^[ \t]*` + v.key + `[ \t]*=.*$`)
+		line := v.key + " = " + v.value
+		if re.MatchString(conf) {
+			conf = re.ReplaceAllLiteralString(conf, line)
+		} else if strings.Contains(conf, "[Basic]\n") {
+			conf = strings.Replace(conf, "[Basic]\n", "[Basic]\n"+line+"\n", 1)
+		} else {
+			return "", fmt.Errorf("section [Basic] not found")
+		}
+	}
+	return conf, nil
+}
+
 func startBFE(ctx context.Context) error {
 	cmd := exec.Command(option.Opts.Ingress.BfeBinary, "-c", "../conf", "-l", "../log", "-s")
 	cmd.Dir = filepath.Dir(option.Opts.Ingress.BfeBinary)
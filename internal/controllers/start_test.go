@@ -0,0 +1,195 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bfenetworks/bfe/bfe_modules/mod_auth_request"
+
+	"github.com/bfenetworks/ingress-bfe/internal/option"
+)
+
+func Test_enableModule(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "commented",
+			conf:    "Modules = mod_block\n#Modules = mod_auth_request\n# Modules = mod_cors\n",
+			want:    "Modules = mod_block\nModules = mod_auth_request\n# Modules = mod_cors\n",
+			wantErr: false,
+		},
+		{
+			name:    "not found",
+			conf:    "Modules = mod_block\nModules = mod_prison\n\nMonitorInterval = 20\n",
+			want:    "Modules = mod_block\nModules = mod_prison\nModules = mod_auth_request\n\nMonitorInterval = 20\n",
+			wantErr: false,
+		},
+		{
+			name:    "enabled",
+			conf:    "Modules = mod_auth_request\nModules = mod_block\n",
+			want:    "Modules = mod_auth_request\nModules = mod_block\n",
+			wantErr: false,
+		},
+		{
+			name:    "no module",
+			conf:    "[Server]\nHttpPort = 8080\n",
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enableModule(tt.conf, "mod_auth_request")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("enableModule() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("enableModule() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_setAuthService(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "replace",
+			conf:    "[Basic]\nDataPath = mod_auth_request/auth_request_rule.data\nAuthAddress = http://0.0.0.0\nAuthTimeout = 100 # ms\n",
+			want:    "[Basic]\nDataPath = mod_auth_request/auth_request_rule.data\nAuthAddress = http://127.0.0.1:8000/auth\nAuthTimeout = 500\n",
+			wantErr: false,
+		},
+		{
+			name:    "add",
+			conf:    "[Basic]\nDataPath = mod_auth_request/auth_request_rule.data\n",
+			want:    "[Basic]\nAuthTimeout = 500\nAuthAddress = http://127.0.0.1:8000/auth\nDataPath = mod_auth_request/auth_request_rule.data\n",
+			wantErr: false,
+		},
+		{
+			name:    "no section",
+			conf:    "[Log]\nOpenDebug = false\n",
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setAuthService(tt.conf, "http://127.0.0.1:8000/auth", 500*time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setAuthService() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("setAuthService() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_enableAuthRequest(t *testing.T) {
+	const bfeConf = "bfe.conf"
+	const modConf = "mod_auth_request/mod_auth_request.conf"
+	// default mod_auth_request.conf of bfe
+	defaultModConf := "[Basic]\nDataPath = mod_auth_request/auth_request_rule.data\nAuthAddress = http://127.0.0.1\nAuthTimeout = 100\n\n[Log]\nOpenDebug = false\n"
+
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantErr     bool
+		wantBfeConf string
+	}{
+		{
+			name: "enable",
+			files: map[string]string{
+				bfeConf: "[Server]\nHttpPort = 8080\n\nModules = mod_block\n#Modules = mod_auth_request\n",
+				modConf: defaultModConf,
+			},
+			wantErr:     false,
+			wantBfeConf: "[Server]\nHttpPort = 8080\n\nModules = mod_block\nModules = mod_auth_request\n",
+		},
+		{
+			name: "no mod_auth_request.conf",
+			files: map[string]string{
+				bfeConf: "[Server]\nHttpPort = 8080\n\nModules = mod_block\n",
+			},
+			wantErr: true,
+		},
+		{
+			name: "no module in bfe.conf",
+			files: map[string]string{
+				bfeConf: "[Server]\nHttpPort = 8080\n",
+				modConf: defaultModConf,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := option.NewOptions()
+			opts.Ingress.ConfigPath = t.TempDir() + "/"
+			opts.Ingress.AuthURL = "http://auth.default.svc:8080/check"
+			opts.Ingress.AuthTimeout = 500 * time.Millisecond
+			option.Opts = opts
+			t.Cleanup(func() { option.Opts = nil })
+			for name, content := range tt.files {
+				name = opts.Ingress.ConfigPath + name
+				if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := enableAuthRequest()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("enableAuthRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, err := ioutil.ReadFile(opts.Ingress.ConfigPath + bfeConf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(data); got != tt.wantBfeConf {
+				t.Errorf("%s = %q, want %q", bfeConf, got, tt.wantBfeConf)
+			}
+			// the auth service is loaded by mod_auth_request
+			conf, err := mod_auth_request.ConfLoad(opts.Ingress.ConfigPath+modConf, opts.Ingress.ConfigPath)
+			if err != nil {
+				t.Fatalf("mod_auth_request.ConfLoad() error = %v", err)
+			}
+			if conf.Basic.AuthAddress != opts.Ingress.AuthURL || conf.Basic.AuthTimeout != 500 {
+				t.Errorf("auth service = %s, %dms, want %s, 500ms", conf.Basic.AuthAddress, conf.Basic.AuthTimeout, opts.Ingress.AuthURL)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package ingress
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -43,6 +44,23 @@ const (
 
 	// default backend
 	defaultBackend = ""
+
+	// acme certificate issuance
+	acmeRenewBefore = 30 * 24 * time.Hour
+	acmeSolverPort  = 9082
+	acmeSecret      = "ingress-bfe/bfe-ingress-acme"
+
+	// timeout of requests to the external authorization service
+	authTimeout = 100 * time.Millisecond
+)
+
+const (
+	// ProductModeSingle puts all rules into the default product of BFE
+	ProductModeSingle = "single"
+	// ProductModeHost generates a BFE product for each host of rules
+	ProductModeHost = "host"
+	// ProductModeNamespace generates a BFE product for each namespace of ingresses
+	ProductModeNamespace = "namespace"
 )
 
 type Options struct {
@@ -56,6 +74,34 @@ type Options struct {
 	FilePerm       os.FileMode
 	ReloadInterval time.Duration
 	DefaultBackend string
+	// ConfigMap holds the global default of annotations, format namespace/name
+	ConfigMap string
+
+	SSLRedirect     bool
+	SSLRedirectHSTS bool
+
+	// ProxyProtocol enables PROXY protocol of BFE, for BFE behind a layer4 load balancer
+	ProxyProtocol bool
+
+	// AuthURL is the external authorization service used by ingresses with annotation auth-url
+	AuthURL     string
+	AuthTimeout time.Duration
+
+	AcmeDirectoryURL string
+	AcmeEmail        string
+	AcmeCAFile       string
+	AcmeRenewBefore  time.Duration
+	AcmeSolverPort   int
+	// AcmeSecret stores the ACME account and pending HTTP-01 challenges shared by replicas, format namespace/name.
+	// It's also the name of the Lease electing the replica which issues certificates.
+	AcmeSecret string
+
+	// RouterConditionDenyNamespaces are namespaces in which annotation router.condition is rejected, delimited by ','.
+	// "*" means all namespaces.
+	RouterConditionDenyNamespaces string
+
+	// ProductMode decides how rules are grouped into BFE products, one of ProductModeSingle, ProductModeHost and ProductModeNamespace
+	ProductMode string
 }
 
 func NewOptions() *Options {
@@ -69,6 +115,14 @@ func NewOptions() *Options {
 		FilePerm:       filePerm,
 		ReloadInterval: reloadInterval,
 		DefaultBackend: defaultBackend,
+
+		AcmeRenewBefore: acmeRenewBefore,
+		AcmeSolverPort:  acmeSolverPort,
+		AcmeSecret:      acmeSecret,
+
+		AuthTimeout: authTimeout,
+
+		ProductMode: ProductModeSingle,
 	}
 }
 
@@ -83,6 +137,30 @@ func (opts *Options) Check() error {
 			return fmt.Errorf("invalid command line argument default-backend: %s", opts.DefaultBackend)
 		}
 	}
+	if len(opts.ConfigMap) > 0 {
+		names := strings.Split(opts.ConfigMap, string(types.Separator))
+		if len(names) != 2 {
+			return fmt.Errorf("invalid command line argument configmap: %s", opts.ConfigMap)
+		}
+	}
+	if opts.AcmeEnabled() && (opts.AcmeSolverPort <= 0 || opts.AcmeSolverPort > 65535) {
+		return fmt.Errorf("invalid command line argument acme-solver-port: %d", opts.AcmeSolverPort)
+	}
+	if opts.AcmeEnabled() && len(strings.Split(opts.AcmeSecret, string(types.Separator))) != 2 {
+		return fmt.Errorf("invalid command line argument acme-secret: %s", opts.AcmeSecret)
+	}
+	if len(opts.AuthURL) > 0 {
+		u, err := url.Parse(opts.AuthURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return fmt.Errorf("invalid command line argument auth-url: %s", opts.AuthURL)
+		}
+		if opts.AuthTimeout < time.Millisecond {
+			return fmt.Errorf("invalid command line argument auth-timeout: %s", opts.AuthTimeout)
+		}
+	}
+	if opts.ProductMode != ProductModeSingle && opts.ProductMode != ProductModeHost && opts.ProductMode != ProductModeNamespace {
+		return fmt.Errorf("invalid command line argument product-mode: %s", opts.ProductMode)
+	}
 	if len(opts.BfeBinary) > 0 {
 		opts.ConfigPath = filepath.Dir(filepath.Dir(opts.BfeBinary)) + "/conf"
 	}
@@ -94,3 +172,19 @@ func (opts *Options) Check() error {
 	opts.ReloadUrl = fmt.Sprintf(reloadUrlPrefix, opts.ReloadAddr)
 	return nil
 }
+
+// RouterConditionAllowed returns true if annotation router.condition can be used by ingresses in the namespace
+func (opts *Options) RouterConditionAllowed(namespace string) bool {
+	for _, ns := range strings.Split(opts.RouterConditionDenyNamespaces, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "*" || ns == namespace {
+			return false
+		}
+	}
+	return true
+}
+
+// AcmeEnabled returns true if certificates can be obtained through ACME
+func (opts *Options) AcmeEnabled() bool {
+	return len(opts.AcmeDirectoryURL) > 0
+}
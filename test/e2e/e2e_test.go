@@ -37,7 +37,10 @@ import (
 	"github.com/bfenetworks/ingress-bfe/test/e2e/pkg/http"
 	"github.com/bfenetworks/ingress-bfe/test/e2e/pkg/kubernetes"
 	"github.com/bfenetworks/ingress-bfe/test/e2e/pkg/kubernetes/templates"
+	"github.com/bfenetworks/ingress-bfe/test/e2e/steps/annotations/acme"
 	"github.com/bfenetworks/ingress-bfe/test/e2e/steps/annotations/balance/loadbalance"
+	"github.com/bfenetworks/ingress-bfe/test/e2e/steps/annotations/block"
+	"github.com/bfenetworks/ingress-bfe/test/e2e/steps/annotations/ratelimit"
 	"github.com/bfenetworks/ingress-bfe/test/e2e/steps/annotations/redirect"
 	"github.com/bfenetworks/ingress-bfe/test/e2e/steps/annotations/rewrite"
 	"github.com/bfenetworks/ingress-bfe/test/e2e/steps/annotations/route/cookie"
@@ -143,6 +146,9 @@ var (
 		"features/annotations/balance/load_balance.feature": {loadbalance.InitializeScenario, nil},
 		"features/annotations/redirect/redirect.feature":    {redirect.InitializeScenario, nil},
 		"features/annotations/rewrite/rewrite.feature":      {rewrite.InitializeScenario, nil},
+		"features/annotations/ratelimit/ratelimit.feature":  {ratelimit.InitializeScenario, nil},
+		"features/annotations/block/block.feature":          {block.InitializeScenario, nil},
+		"features/annotations/acme/acme.feature":            {acme.InitializeScenario, nil},
 	}
 )
 
@@ -0,0 +1,72 @@
+@annotations @tls.acme @release-1.22
+Feature: ACME
+  An Ingress may obtain certificates of its TLS hosts through ACME.
+
+  If an Ingress has annotation `bfe.ingress.kubernetes.io/tls.acme`, the controller should
+  obtain a certificate from the ACME server through the HTTP-01 challenge, and store it in
+  the Secret referenced by spec.tls. Secrets provided by users should never be overwritten.
+
+  Background:
+    Given a new random namespace
+
+  Scenario: An Ingress with annotation `bfe.ingress.kubernetes.io/tls.acme` obtains a certificate
+    Given an Ingress resource
+    """
+    apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: acme
+      annotations:
+        bfe.ingress.kubernetes.io/tls.acme: "true"
+    spec:
+      tls:
+        - hosts:
+            - acme.foo.com
+          secretName: acme-tls
+      rules:
+        - host: "acme.foo.com"
+          http:
+            paths:
+              - path: /bar
+                pathType: Prefix
+                backend:
+                  service:
+                    name: foo-bar
+                    port:
+                      number: 3000
+    """
+    And The Ingress status shows the IP address or FQDN where it is exposed
+    Then the Secret "acme-tls" must contain a certificate for "acme.foo.com" issued by "Pebble"
+    When I send a "GET" request to "https://acme.foo.com/bar"
+    Then the response status-code must be 200
+    And the secure connection must verify the "acme.foo.com" hostname
+
+  Scenario: An Ingress with annotation `bfe.ingress.kubernetes.io/tls.acme` doesn't overwrite Secrets provided by users
+    Given a self-signed TLS secret named "user-tls" for the "user.foo.com" hostname
+    And an Ingress resource
+    """
+    apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: acme-user-secret
+      annotations:
+        bfe.ingress.kubernetes.io/tls.acme: "true"
+    spec:
+      tls:
+        - hosts:
+            - user.foo.com
+          secretName: user-tls
+      rules:
+        - host: "user.foo.com"
+          http:
+            paths:
+              - path: /bar
+                pathType: Prefix
+                backend:
+                  service:
+                    name: foo-bar
+                    port:
+                      number: 3000
+    """
+    And The Ingress status shows the IP address or FQDN where it is exposed
+    Then the Secret "user-tls" must not be overwritten
@@ -0,0 +1,82 @@
+@annotations @block @release-1.22
+Feature: IP access control
+  An Ingress may define IP whitelist and blacklist in its annotations.
+
+  If the client IP of a request is in the blacklist, or not in the whitelist,
+  BFE should close the connection of the request.
+
+  Scenario: An Ingress with annotation `bfe.ingress.kubernetes.io/block.whitelist` allows requests from the whitelist
+    Given an Ingress resource with IP access control annotations
+    """
+    apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: block-whitelist
+      annotations:
+        bfe.ingress.kubernetes.io/block.whitelist: "0.0.0.0/0, ::/0"
+    spec:
+      rules:
+        - host: "foo.com"
+          http:
+            paths:
+              - path: /bar
+                pathType: Prefix
+                backend:
+                  service:
+                    name: foo-bar
+                    port:
+                      number: 3000
+    """
+    And The Ingress status shows the IP address or FQDN where it is exposed
+    When I send a "GET" request to "http://foo.com/bar"
+    Then the response status-code must be 200
+
+  Scenario: An Ingress with annotation `bfe.ingress.kubernetes.io/block.blacklist` denies requests from the blacklist
+    Given an Ingress resource with IP access control annotations
+    """
+    apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: block-blacklist
+      annotations:
+        bfe.ingress.kubernetes.io/block.blacklist: "0.0.0.0/0, ::/0"
+    spec:
+      rules:
+        - host: "foo.com"
+          http:
+            paths:
+              - path: /bar
+                pathType: Prefix
+                backend:
+                  service:
+                    name: foo-bar
+                    port:
+                      number: 3000
+    """
+    And The Ingress status shows the IP address or FQDN where it is exposed
+    When I send a "GET" request to "http://foo.com/bar"
+    Then the request must be rejected
+
+  Scenario: An Ingress with illegal annotation `bfe.ingress.kubernetes.io/block.whitelist`
+    Given an Ingress resource with IP access control annotations
+    """
+    apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: block-illegal-whitelist
+      annotations:
+        bfe.ingress.kubernetes.io/block.whitelist: "10.0.0.0/33"
+    spec:
+      rules:
+        - host: "foo.com"
+          http:
+            paths:
+              - path: /bar
+                pathType: Prefix
+                backend:
+                  service:
+                    name: foo-bar
+                    port:
+                      number: 3000
+    """
+    Then The Ingress status should not be success
@@ -0,0 +1,91 @@
+@annotations @ratelimit @release-1.22
+Feature: Rate limit
+  An Ingress may limit the rate of requests in its annotations.
+
+  If requests of the same key exceed the limit defined in the Ingress,
+  BFE should close the connections of the exceeded requests until the next second.
+
+  Scenario: An Ingress with annotation `bfe.ingress.kubernetes.io/ratelimit.rps` limits requests by client IP
+    Given an Ingress resource with rate limit annotations
+    """
+    apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: ratelimit-client-ip
+      annotations:
+        bfe.ingress.kubernetes.io/ratelimit.rps: "1"
+    spec:
+      rules:
+        - host: "foo.com"
+          http:
+            paths:
+              - path: /bar
+                pathType: Prefix
+                backend:
+                  service:
+                    name: foo-bar
+                    port:
+                      number: 3000
+    """
+    And The Ingress status shows the IP address or FQDN where it is exposed
+    When I send 5 "GET" requests to "http://foo.com/bar"
+    Then at least 1 request must succeed
+    And at least 1 request must be rejected
+    When I wait for the rate limit window to pass
+    And I send a "GET" request to "http://foo.com/bar"
+    Then the response status-code must be 200
+
+  Scenario: An Ingress with annotation `bfe.ingress.kubernetes.io/ratelimit.key` limits requests by header
+    Given an Ingress resource with rate limit annotations
+    """
+    apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: ratelimit-header
+      annotations:
+        bfe.ingress.kubernetes.io/ratelimit.rps: "2"
+        bfe.ingress.kubernetes.io/ratelimit.burst: "1"
+        bfe.ingress.kubernetes.io/ratelimit.key: "header:X-Api-Key"
+    spec:
+      rules:
+        - host: "foo.com"
+          http:
+            paths:
+              - path: /bar
+                pathType: Prefix
+                backend:
+                  service:
+                    name: foo-bar
+                    port:
+                      number: 3000
+    """
+    And The Ingress status shows the IP address or FQDN where it is exposed
+    When I send 10 "GET" requests to "http://foo.com/bar" with header "X-Api-Key" of value "tenant-a"
+    Then at least 1 request must be rejected
+    When I send 3 "GET" requests to "http://foo.com/bar" with header "X-Api-Key" of value "tenant-b"
+    Then all requests must succeed
+
+  Scenario: An Ingress with illegal annotation `bfe.ingress.kubernetes.io/ratelimit.key`
+    Given an Ingress resource with rate limit annotations
+    """
+    apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: ratelimit-illegal-key
+      annotations:
+        bfe.ingress.kubernetes.io/ratelimit.rps: "1"
+        bfe.ingress.kubernetes.io/ratelimit.key: "query:uid"
+    spec:
+      rules:
+        - host: "foo.com"
+          http:
+            paths:
+              - path: /bar
+                pathType: Prefix
+                backend:
+                  service:
+                    name: foo-bar
+                    port:
+                      number: 3000
+    """
+    Then The Ingress status should not be success
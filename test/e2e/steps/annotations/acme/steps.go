@@ -0,0 +1,204 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cucumber/godog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/bfenetworks/ingress-bfe/test/e2e/pkg/kubernetes"
+	tstate "github.com/bfenetworks/ingress-bfe/test/e2e/pkg/state"
+)
+
+const (
+	acmeManagedAnnotation = "bfe.ingress.kubernetes.io/tls.acme-managed"
+
+	// secretWaitInterval time to wait between checks of the secret
+	secretWaitInterval = 5 * time.Second
+	// secretWaitTimeout maximum wait time for the certificate to be issued
+	secretWaitTimeout = 3 * time.Minute
+	// reloadWaitTime time to wait for the certificate to be reloaded into bfe
+	reloadWaitTime = 10 * time.Second
+)
+
+var state *tstate.Scenario
+
+// InitializeScenario configures the Feature to test
+func InitializeScenario(ctx *godog.ScenarioContext) {
+	ctx.Step(`^a new random namespace$`, aNewRandomNamespace)
+	ctx.Step(`^a self-signed TLS secret named "([^"]*)" for the "([^"]*)" hostname$`, aSelfsignedTLSSecretNamedForTheHostname)
+	ctx.Step(`^an Ingress resource$`, anIngressResource)
+	ctx.Step(`^The Ingress status shows the IP address or FQDN where it is exposed$`, theIngressStatusShowsTheIPAddressOrFQDNWhereItIsExposed)
+	ctx.Step(`^the Secret "([^"]*)" must contain a certificate for "([^"]*)" issued by "([^"]*)"$`, theSecretMustContainACertificateForIssuedBy)
+	ctx.Step(`^the Secret "([^"]*)" must not be overwritten$`, theSecretMustNotBeOverwritten)
+	ctx.Step(`^I send a "([^"]*)" request to "([^"]*)"$`, iSendARequestTo)
+	ctx.Step(`^the response status-code must be (\d+)$`, theResponseStatusCodeMustBe)
+	ctx.Step(`^the secure connection must verify the "([^"]*)" hostname$`, theSecureConnectionMustVerifyTheHostname)
+
+	ctx.Before(func(ctx context.Context, _ *godog.Scenario) (context.Context, error) {
+		state = tstate.New()
+		return ctx, nil
+	})
+
+	ctx.After(func(ctx context.Context, _ *godog.Scenario, _ error) (context.Context, error) {
+		// delete namespace and all the content
+		_ = kubernetes.DeleteNamespace(kubernetes.KubeClient, state.Namespace)
+		return ctx, nil
+	})
+}
+
+func aNewRandomNamespace() error {
+	ns, err := kubernetes.NewNamespace(kubernetes.KubeClient)
+	if err != nil {
+		return err
+	}
+
+	state.Namespace = ns
+	return nil
+}
+
+func aSelfsignedTLSSecretNamedForTheHostname(secretName string, host string) error {
+	err := kubernetes.NewSelfSignedSecret(kubernetes.KubeClient, state.Namespace, secretName, []string{host})
+	if err != nil {
+		return err
+	}
+
+	state.SecretName = secretName
+	return nil
+}
+
+func anIngressResource(spec *godog.DocString) error {
+	ingress, err := kubernetes.IngressFromManifest(state.Namespace, spec.Content)
+	if err != nil {
+		return err
+	}
+
+	err = kubernetes.DeploymentsFromIngress(kubernetes.KubeClient, ingress)
+	if err != nil {
+		return err
+	}
+
+	err = kubernetes.NewIngress(kubernetes.KubeClient, state.Namespace, ingress)
+	if err != nil {
+		return err
+	}
+
+	state.IngressName = ingress.GetName()
+	return nil
+}
+
+func theIngressStatusShowsTheIPAddressOrFQDNWhereItIsExposed() error {
+	ingress, err := kubernetes.WaitForIngressAddress(kubernetes.KubeClient, state.Namespace, state.IngressName)
+	if err != nil {
+		return err
+	}
+
+	state.IPOrFQDN = ingress
+
+	time.Sleep(3 * time.Second)
+
+	return err
+}
+
+// getSecretCertificate returns the secret and the certificate in it
+func getSecretCertificate(name string) (*corev1.Secret, *x509.Certificate, error) {
+	secret, err := kubernetes.KubeClient.CoreV1().Secrets(state.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return secret, nil, fmt.Errorf("no certificate found in secret %s", name)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	return secret, cert, err
+}
+
+func theSecretMustContainACertificateForIssuedBy(name, host, issuer string) error {
+	var cert *x509.Certificate
+	err := wait.PollImmediate(secretWaitInterval, secretWaitTimeout, func() (bool, error) {
+		var err error
+		_, cert, err = getSecretCertificate(name)
+		return err == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("certificate is not issued in secret %s: %v", name, err)
+	}
+
+	if err := cert.VerifyHostname(host); err != nil {
+		return err
+	}
+	if !strings.Contains(cert.Issuer.CommonName, issuer) {
+		return fmt.Errorf("expected the certificate to be issued by %s but was %s", issuer, cert.Issuer.CommonName)
+	}
+
+	// wait until the certificate is reloaded into bfe
+	time.Sleep(reloadWaitTime)
+	return nil
+}
+
+func theSecretMustNotBeOverwritten(name string) error {
+	_, origin, err := getSecretCertificate(name)
+	if err != nil {
+		return err
+	}
+
+	// give the controller the chance to issue a certificate
+	time.Sleep(secretWaitTimeout / 3)
+
+	secret, cert, err := getSecretCertificate(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := secret.Annotations[acmeManagedAnnotation]; ok {
+		return fmt.Errorf("secret %s should not be managed by acme", name)
+	}
+	if !cert.Equal(origin) {
+		return fmt.Errorf("secret %s is overwritten", name)
+	}
+	return nil
+}
+
+func iSendARequestTo(method string, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return state.CaptureRoundTrip(method, u.Scheme, u.Host, u.Path, nil, nil, false)
+}
+
+func theResponseStatusCodeMustBe(statusCode int) error {
+	return state.AssertStatusCode(statusCode)
+}
+
+func theSecureConnectionMustVerifyTheHostname(hostname string) error {
+	err := state.AssertTLSHostname(hostname)
+	if err != nil {
+		return err
+	}
+
+	return state.AssertResponseCertificate(hostname)
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2022 The BFE Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cucumber/godog"
+
+	"github.com/bfenetworks/ingress-bfe/test/e2e/pkg/kubernetes"
+	tstate "github.com/bfenetworks/ingress-bfe/test/e2e/pkg/state"
+)
+
+var state *tstate.Scenario
+
+// succeeded and rejected are the number of requests sent by the last step of sending requests
+var (
+	succeeded int
+	rejected  int
+)
+
+// InitializeScenario configures the Feature to test
+func InitializeScenario(ctx *godog.ScenarioContext) {
+	ctx.Step(`^an Ingress resource with rate limit annotations$`, anIngressResourceWithRateLimitAnnotations)
+	ctx.Step(`^The Ingress status shows the IP address or FQDN where it is exposed$`, theIngressStatusShowsTheIPAddressOrFQDNWhereItIsExposed)
+	ctx.Step(`^I send (\d+) "([^"]*)" requests to "([^"]*)"$`, iSendRequestsTo)
+	ctx.Step(`^I send (\d+) "([^"]*)" requests to "([^"]*)" with header "([^"]*)" of value "([^"]*)"$`, iSendRequestsToWithHeader)
+	ctx.Step(`^at least (\d+) requests? must succeed$`, atLeastRequestsMustSucceed)
+	ctx.Step(`^at least (\d+) requests? must be rejected$`, atLeastRequestsMustBeRejected)
+	ctx.Step(`^all requests must succeed$`, allRequestsMustSucceed)
+	ctx.Step(`^I wait for the rate limit window to pass$`, iWaitForTheRateLimitWindowToPass)
+	ctx.Step(`^I send a "([^"]*)" request to "([^"]*)"$`, iSendARequestTo)
+	ctx.Step(`^the response status-code must be (\d+)$`, theResponseStatusCodeMustBe)
+	ctx.Step(`^The Ingress status should not be success$`, theIngressStatusShouldNotBeSuccess)
+
+	ctx.Before(func(ctx context.Context, _ *godog.Scenario) (context.Context, error) {
+		state = tstate.New()
+		succeeded, rejected = 0, 0
+		return ctx, nil
+	})
+
+	ctx.After(func(ctx context.Context, _ *godog.Scenario, _ error) (context.Context, error) {
+		// delete namespace and all the content
+		_ = kubernetes.DeleteNamespace(kubernetes.KubeClient, state.Namespace)
+		return ctx, nil
+	})
+}
+
+func anIngressResourceWithRateLimitAnnotations(spec *godog.DocString) error {
+	ns, err := kubernetes.NewNamespace(kubernetes.KubeClient)
+	if err != nil {
+		return err
+	}
+
+	state.Namespace = ns
+
+	ingress, err := kubernetes.IngressFromManifest(state.Namespace, spec.Content)
+	if err != nil {
+		return err
+	}
+
+	err = kubernetes.DeploymentsFromIngress(kubernetes.KubeClient, ingress)
+	if err != nil {
+		return err
+	}
+
+	err = kubernetes.NewIngress(kubernetes.KubeClient, state.Namespace, ingress)
+	if err != nil {
+		return err
+	}
+
+	state.IngressName = ingress.GetName()
+
+	return nil
+}
+
+func theIngressStatusShowsTheIPAddressOrFQDNWhereItIsExposed() error {
+	ingress, err := kubernetes.WaitForIngressAddress(kubernetes.KubeClient, state.Namespace, state.IngressName)
+	if err != nil {
+		return err
+	}
+
+	state.IPOrFQDN = ingress
+
+	time.Sleep(3 * time.Second)
+
+	return err
+}
+
+func iSendRequestsTo(count int, method, rawURL string) error {
+	return sendRequests(count, method, rawURL, nil)
+}
+
+func iSendRequestsToWithHeader(count int, method, rawURL, key, value string) error {
+	header := http.Header{}
+	header.Set(key, value)
+	return sendRequests(count, method, rawURL, header)
+}
+
+// sendRequests sends requests one by one, a request is rejected if the connection is closed without response
+func sendRequests(count int, method, rawURL string, header http.Header) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	succeeded, rejected = 0, 0
+	for i := 0; i < count; i++ {
+		if err := state.CaptureRoundTrip(method, u.Scheme, u.Host, u.Path, nil, header.Clone(), false); err != nil {
+			rejected++
+			continue
+		}
+		if err := state.AssertStatusCode(http.StatusOK); err != nil {
+			return err
+		}
+		succeeded++
+	}
+
+	return nil
+}
+
+func atLeastRequestsMustSucceed(count int) error {
+	if succeeded < count {
+		return fmt.Errorf("expected at least %d requests to succeed but %d succeeded", count, succeeded)
+	}
+	return nil
+}
+
+func atLeastRequestsMustBeRejected(count int) error {
+	if rejected < count {
+		return fmt.Errorf("expected at least %d requests to be rejected but %d were rejected", count, rejected)
+	}
+	return nil
+}
+
+func allRequestsMustSucceed() error {
+	if rejected != 0 {
+		return fmt.Errorf("expected all requests to succeed but %d were rejected", rejected)
+	}
+	return nil
+}
+
+func iWaitForTheRateLimitWindowToPass() error {
+	time.Sleep(2 * time.Second)
+	return nil
+}
+
+func iSendARequestTo(method string, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return state.CaptureRoundTrip(method, u.Scheme, u.Host, u.Path, nil, nil, false)
+}
+
+func theResponseStatusCodeMustBe(statusCode int) error {
+	return state.AssertStatusCode(statusCode)
+}
+
+func theIngressStatusShouldNotBeSuccess() error {
+	_, err := kubernetes.WaitForIngressAddress(kubernetes.KubeClient, state.Namespace, state.IngressName)
+	if err == nil {
+		return fmt.Errorf("create ingress should return error")
+	}
+
+	return nil
+}
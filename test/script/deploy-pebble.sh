@@ -0,0 +1,45 @@
+#!/usr/bin/env bash
+# Copyright 2022 The BFE Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+# http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+set -e
+
+cd "$(dirname "$0")"
+
+PEBBLE_VERSION=v2.3.1
+
+# resolve all hosts to the controller service, so that pebble validates challenges through bfe
+SERVICE_IP=$(./kubectl -n ingress-bfe get service bfe-controller-service -o jsonpath='{.spec.clusterIP}')
+sed "s#BFE_SERVICE_IP#$SERVICE_IP#g" pebble.yaml | ./kubectl apply -f -
+
+# CA bundle of the pebble directory
+if [[ ! -f pebble.minica.pem ]]; then
+    curl -Lo pebble.minica.pem https://raw.githubusercontent.com/letsencrypt/pebble/$PEBBLE_VERSION/test/certs/pebble.minica.pem
+fi
+./kubectl -n ingress-bfe create configmap pebble-ca --from-file=pebble.minica.pem --dry-run=client -o yaml | ./kubectl apply -f -
+
+# permissions required by acme
+./kubectl apply -f ../../examples/rbac.yaml
+
+# enable acme in the controller
+./kubectl -n ingress-bfe patch deployment bfe-ingress-controller --type=json -p='[
+  {"op": "add", "path": "/spec/template/spec/volumes", "value": [{"name": "pebble-ca", "configMap": {"name": "pebble-ca"}}]},
+  {"op": "add", "path": "/spec/template/spec/containers/0/volumeMounts", "value": [{"name": "pebble-ca", "mountPath": "/pebble"}]},
+  {"op": "add", "path": "/spec/template/spec/containers/0/args", "value": [
+    "--acme-directory-url=https://pebble:14000/dir",
+    "--acme-ca-file=/pebble/pebble.minica.pem"
+  ]}
+]'
+
+./kubectl -n ingress-bfe rollout status deployment pebble --timeout=5m
+./kubectl -n ingress-bfe rollout status deployment bfe-ingress-controller --timeout=5m